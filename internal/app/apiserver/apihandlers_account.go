@@ -0,0 +1,257 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/mailer"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+const (
+	emailVerificationTokenTTL = 24 * time.Hour
+	passwordResetTokenTTL     = time.Hour
+)
+
+func (server *server) sendEmailVerification(user *model.User) error {
+	methodName := "sendEmailVerification"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	token, err := server.tokens.CreateOneTimeToken(tokenutils.PurposeEmailVerification, &tokenutils.OneTimeTokenDetails{
+		UserID: user.ID,
+		Email:  user.Email,
+	}, emailVerificationTokenTTL)
+	if err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", server.appBaseURL, url.QueryEscape(token))
+
+	if err := server.mailer.Send(mailer.NewEmailVerificationMessage(user.Email, link)); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	return nil
+}
+
+func (server *server) handleEmailVerificationRequest() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "EmailVerificationRequest"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		if user.EmailVerified {
+			server.respond(writer, req, http.StatusOK, map[string]string{})
+			return
+		}
+
+		if err := server.sendEmailVerification(user); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+func (server *server) handleEmailVerificationConfirm() http.HandlerFunc {
+	type request struct {
+		Token string `json:"token"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "EmailVerificationConfirm"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		tokenDetails, err := server.tokens.ConsumeOneTimeToken(tokenutils.PurposeEmailVerification, requestStruct.Token)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrTokenExpiredOrDeleted, tokenutils.ErrTokenDamaged:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				// Mostly TokenUtils.ErrInternal, probably something with Redis
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		user, err := server.store.Users().Find(tokenDetails.UserID)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case store.ErrNotFound:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		// Email was changed after the token had been sent
+		if user.Email != tokenDetails.Email {
+			errWrapped := errors.Wrap(tokenutils.ErrTokenExpiredOrDeleted, errWrapMessage)
+			server.error(writer, req, http.StatusForbidden, errWrapped)
+			return
+		}
+
+		if err := server.store.Users().UpdateEmailVerified(true, user.ID); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+// handlePasswordResetRequest responds the same way whether the email is registered or not,
+// so it can't be used to find out users' emails
+func (server *server) handlePasswordResetRequest() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "PasswordResetRequest"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user, err := server.store.Users().FindBy("email", requestStruct.Email)
+		if err != nil {
+			if errors.Cause(err) == store.ErrNotFound {
+				server.respond(writer, req, http.StatusOK, map[string]string{})
+				return
+			}
+
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		token, err := server.tokens.CreateOneTimeToken(tokenutils.PurposePasswordReset, &tokenutils.OneTimeTokenDetails{
+			UserID: user.ID,
+			Email:  user.Email,
+		}, passwordResetTokenTTL)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		link := fmt.Sprintf("%s/reset-password?token=%s", server.appBaseURL, url.QueryEscape(token))
+
+		if err := server.mailer.Send(mailer.NewPasswordResetMessage(user.Email, link)); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+func (server *server) handlePasswordResetConfirm() http.HandlerFunc {
+	type request struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "PasswordResetConfirm"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		// Password is validated before the token is consumed, so the user can fix it and try again
+		if err := validation.Validate(&requestStruct.NewPassword, model.ValidationRulesPassword...); err != nil {
+			errWrapped := errors.Wrap(errors.Wrap(model.ErrValidationFailed, err.Error()), errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		tokenDetails, err := server.tokens.ConsumeOneTimeToken(tokenutils.PurposePasswordReset, requestStruct.Token)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrTokenExpiredOrDeleted, tokenutils.ErrTokenDamaged:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				// Mostly TokenUtils.ErrInternal, probably something with Redis
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		if err := server.store.Users().UpdatePassword(requestStruct.NewPassword, tokenDetails.UserID); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(errWrapped) {
+			case model.ErrValidationFailed:
+				server.error(writer, req, http.StatusBadRequest, errWrapped)
+			case store.ErrNotFound:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		// Whoever knew the old password must be logged out
+		if err := server.tokens.DeleteAllAuths(tokenDetails.UserID); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
@@ -0,0 +1,515 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+// requestReleaseDateLayout is the format of model.Game.ReleaseDate
+const requestReleaseDateLayout = "02.01.2006"
+
+func parseIDVar(req *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+}
+
+// storeError answers with status matching the cause of the store error
+func (server *server) storeError(writer http.ResponseWriter, req *http.Request, err error) {
+	switch errors.Cause(err) {
+	case store.ErrNotFound:
+		server.error(writer, req, http.StatusNotFound, err)
+	case store.ErrConflict, errBuiltInMarket, errPublisherHasGames:
+		server.error(writer, req, http.StatusConflict, err)
+	case model.ErrValidationFailed:
+		server.error(writer, req, http.StatusBadRequest, err)
+	default:
+		server.log(err)
+		server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+	}
+}
+
+// Catalogue and markets are listed and found by ID the same way, find functions return models as they are
+
+func (server *server) handleAdminList(methodName string, findAll func() (interface{}, error)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		items, err := findAll()
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, items)
+	}
+}
+
+func (server *server) handleAdminGetByID(methodName string, find func(id uint64) (interface{}, error)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		item, err := find(id)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d", id))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, item)
+	}
+}
+
+// Publishers, tags and markets consist of name only, so they share handlers
+
+func (server *server) handleAdminNameCreate(methodName string, create func(name string) (uint64, error)) http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil || strings.TrimSpace(requestStruct.Name) == "" {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		id, err := create(strings.TrimSpace(requestStruct.Name))
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusCreated, map[string]uint64{"id": id})
+	}
+}
+
+func (server *server) handleAdminNameUpdate(methodName string, update func(id uint64, name string) error) http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil || strings.TrimSpace(requestStruct.Name) == "" {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		if err := update(id, strings.TrimSpace(requestStruct.Name)); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d", id))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+func (server *server) handleAdminDelete(methodName string, delete func(id uint64) error) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		if err := delete(id); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d", id))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+func (server *server) handleAdminPublishers() http.HandlerFunc {
+	return server.handleAdminList("AdminPublishers", func() (interface{}, error) {
+		return server.store.Publishers().FindAll()
+	})
+}
+
+func (server *server) handleAdminPublishersGetByID() http.HandlerFunc {
+	return server.handleAdminGetByID("AdminPublishersGetByID", func(id uint64) (interface{}, error) {
+		return server.store.Publishers().Find(id)
+	})
+}
+
+func (server *server) handleAdminPublishersCreate() http.HandlerFunc {
+	return server.handleAdminNameCreate("AdminPublishersCreate", func(name string) (uint64, error) {
+		publisher := &model.Publisher{Name: name}
+		err := server.store.Publishers().Create(publisher)
+		return publisher.ID, err
+	})
+}
+
+func (server *server) handleAdminPublishersUpdate() http.HandlerFunc {
+	return server.handleAdminNameUpdate("AdminPublishersUpdate", func(id uint64, name string) error {
+		return server.store.Publishers().Update(&model.Publisher{ID: id, Name: name})
+	})
+}
+
+// handleAdminPublishersDelete refuses to delete the publisher with games, as games would be deleted with it
+func (server *server) handleAdminPublishersDelete() http.HandlerFunc {
+	return server.handleAdminDelete("AdminPublishersDelete", func(id uint64) error {
+		_, err := server.store.Games().FindBy("publisher_id", id)
+		if err == nil {
+			return errPublisherHasGames
+		}
+		if errors.Cause(err) != store.ErrNotFound {
+			return err
+		}
+
+		return server.store.Publishers().Delete(id)
+	})
+}
+
+func (server *server) handleAdminTags() http.HandlerFunc {
+	return server.handleAdminList("AdminTags", func() (interface{}, error) {
+		return server.store.Tags().FindAll()
+	})
+}
+
+func (server *server) handleAdminTagsGetByID() http.HandlerFunc {
+	return server.handleAdminGetByID("AdminTagsGetByID", func(id uint64) (interface{}, error) {
+		return server.store.Tags().Find(id)
+	})
+}
+
+func (server *server) handleAdminTagsCreate() http.HandlerFunc {
+	return server.handleAdminNameCreate("AdminTagsCreate", func(name string) (uint64, error) {
+		tag := &model.Tag{Name: name}
+		err := server.store.Tags().Create(tag)
+		return tag.ID, err
+	})
+}
+
+func (server *server) handleAdminTagsUpdate() http.HandlerFunc {
+	return server.handleAdminNameUpdate("AdminTagsUpdate", func(id uint64, name string) error {
+		return server.store.Tags().Update(&model.Tag{ID: id, Name: name})
+	})
+}
+
+func (server *server) handleAdminTagsDelete() http.HandlerFunc {
+	return server.handleAdminDelete("AdminTagsDelete", func(id uint64) error {
+		if err := server.store.Tags().Delete(id); err != nil {
+			return err
+		}
+
+		server.refreshNeighbours()
+
+		return nil
+	})
+}
+
+func (server *server) handleAdminMarkets() http.HandlerFunc {
+	return server.handleAdminList("AdminMarkets", func() (interface{}, error) {
+		return server.store.Markets().FindAll()
+	})
+}
+
+func (server *server) handleAdminMarketsGetByID() http.HandlerFunc {
+	return server.handleAdminGetByID("AdminMarketsGetByID", func(id uint64) (interface{}, error) {
+		return server.store.Markets().Find(id)
+	})
+}
+
+func (server *server) handleAdminMarketsCreate() http.HandlerFunc {
+	return server.handleAdminNameCreate("AdminMarketsCreate", func(name string) (uint64, error) {
+		market := &model.Market{Name: name}
+		err := server.store.Markets().Create(market)
+		return market.ID, err
+	})
+}
+
+// findNotBuiltInMarket returns errBuiltInMarket for markets the prices are collected from
+func (server *server) findNotBuiltInMarket(id uint64) (*model.Market, error) {
+	market, err := server.store.Markets().Find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if market.IsBuiltIn() {
+		return nil, errBuiltInMarket
+	}
+
+	return market, nil
+}
+
+func (server *server) handleAdminMarketsUpdate() http.HandlerFunc {
+	return server.handleAdminNameUpdate("AdminMarketsUpdate", func(id uint64, name string) error {
+		if _, err := server.findNotBuiltInMarket(id); err != nil {
+			return err
+		}
+
+		return server.store.Markets().Update(&model.Market{ID: id, Name: name})
+	})
+}
+
+func (server *server) handleAdminMarketsDelete() http.HandlerFunc {
+	return server.handleAdminDelete("AdminMarketsDelete", func(id uint64) error {
+		if _, err := server.findNotBuiltInMarket(id); err != nil {
+			return err
+		}
+
+		return server.store.Markets().Delete(id)
+	})
+}
+
+func (server *server) handleAdminGames() http.HandlerFunc {
+	return server.handleAdminList("AdminGames", func() (interface{}, error) {
+		return server.store.Games().FindAll()
+	})
+}
+
+func (server *server) handleAdminGamesGetByID() http.HandlerFunc {
+	return server.handleAdminGetByID("AdminGamesGetByID", func(id uint64) (interface{}, error) {
+		return server.store.Games().Find(id)
+	})
+}
+
+type adminGameRequest struct {
+	HeaderImageURL string `json:"header_image"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	ReleaseDate    string `json:"release_date"`
+	PublisherID    uint64 `json:"publisher_id"`
+}
+
+// decodeAdminGameRequest returns the game without ID, ErrNotFound cause means the publisher doesn't exist
+func (server *server) decodeAdminGameRequest(req *http.Request) (*model.Game, error) {
+	requestStruct := &adminGameRequest{}
+	if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+		return nil, errors.Wrap(errWrongRequestFormat, err.Error())
+	}
+
+	if strings.TrimSpace(requestStruct.Name) == "" {
+		return nil, errors.Wrap(errWrongRequestFormat, "Name is empty")
+	}
+
+	if _, err := time.Parse(requestReleaseDateLayout, requestStruct.ReleaseDate); err != nil {
+		return nil, errors.Wrap(errWrongRequestFormat, err.Error())
+	}
+
+	publisher, err := server.store.Publishers().Find(requestStruct.PublisherID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Game{
+		HeaderImageURL: requestStruct.HeaderImageURL,
+		Name:           strings.TrimSpace(requestStruct.Name),
+		Description:    requestStruct.Description,
+		ReleaseDate:    requestStruct.ReleaseDate,
+		Publisher:      publisher,
+	}, nil
+}
+
+func (server *server) handleAdminGamesCreate() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminGamesCreate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		game, err := server.decodeAdminGameRequest(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case errWrongRequestFormat, store.ErrNotFound:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		if err := server.store.Games().Create(game); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.refreshNeighbours()
+
+		server.respond(writer, req, http.StatusCreated, game)
+	}
+}
+
+func (server *server) handleAdminGamesUpdate() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminGamesUpdate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		game, err := server.decodeAdminGameRequest(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case errWrongRequestFormat, store.ErrNotFound:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		game.ID = id
+
+		if err := server.store.Games().Update(game); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d", id))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.refreshNeighbours()
+
+		server.respond(writer, req, http.StatusOK, game)
+	}
+}
+
+func (server *server) handleAdminGamesDelete() http.HandlerFunc {
+	return server.handleAdminDelete("AdminGamesDelete", func(id uint64) error {
+		if err := server.store.Games().Delete(id); err != nil {
+			return err
+		}
+
+		server.refreshNeighbours()
+
+		return nil
+	})
+}
+
+func (server *server) handleAdminBlacklist() http.HandlerFunc {
+	type responseItem struct {
+		ID            uint64 `json:"id"`
+		MarketGameURL string `json:"uri_string"`
+		Market        string `json:"market"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminBlacklist"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		marketBlacklistItems, err := server.store.MarketBlacklist().FindAll()
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		responseData := []responseItem{}
+
+		for _, marketBlacklistItem := range marketBlacklistItems {
+			responseData = append(responseData, responseItem{
+				ID:            marketBlacklistItem.ID,
+				MarketGameURL: marketBlacklistItem.MarketGameURL,
+				Market:        marketBlacklistItem.Market.Name,
+			})
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
+
+func (server *server) handleAdminBlacklistCreate() http.HandlerFunc {
+	type request struct {
+		MarketGameURL string `json:"uri_string"`
+		MarketID      uint64 `json:"market_id"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminBlacklistCreate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil || requestStruct.MarketGameURL == "" {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		market, err := server.store.Markets().Find(requestStruct.MarketID)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("MarketID = %d", requestStruct.MarketID))
+			server.log(errWrapped)
+
+			if errors.Cause(err) == store.ErrNotFound {
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			} else {
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+			return
+		}
+
+		marketBlacklistItem := &model.MarketBlacklistItem{
+			MarketGameURL: requestStruct.MarketGameURL,
+			Market:        market,
+		}
+
+		if err := server.store.MarketBlacklist().Create(marketBlacklistItem); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusCreated, map[string]uint64{"id": marketBlacklistItem.ID})
+	}
+}
+
+func (server *server) handleAdminBlacklistDelete() http.HandlerFunc {
+	return server.handleAdminDelete("AdminBlacklistDelete", server.store.MarketBlacklist().Delete)
+}
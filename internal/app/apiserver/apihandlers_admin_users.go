@@ -0,0 +1,167 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+type adminUserResponse struct {
+	ID            uint64 `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	Banned        bool   `json:"banned"`
+}
+
+func newAdminUserResponse(user *model.User) adminUserResponse {
+	return adminUserResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
+		Banned:        user.Banned,
+	}
+}
+
+// handleAdminUsersFind finds user by username from query, e.g. /admin/users?username=name
+func (server *server) handleAdminUsersFind() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminUsersFind"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		username := req.URL.Query().Get("username")
+		if username == "" {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user, err := server.store.Users().FindBy("username", username)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Username = %s", username))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newAdminUserResponse(user))
+	}
+}
+
+func (server *server) handleAdminUsersGetByID() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminUsersGetByID"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user, err := server.store.Users().Find(id)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d", id))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newAdminUserResponse(user))
+	}
+}
+
+// handleAdminUsersRole changes role of another user, admins can't change their own role
+func (server *server) handleAdminUsersRole() http.HandlerFunc {
+	type request struct {
+		Role string `json:"role"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminUsersRole"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		currentUser := req.Context().Value(ctxKeyUser).(*model.User)
+		if currentUser.ID == id {
+			errWrapped := errors.Wrap(errChangeOwnAccount, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		if err := server.store.Users().UpdateRole(requestStruct.Role, id); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d; Role = %s", id, requestStruct.Role))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+// handleAdminUsersBan bans or unbans another user, banned user is logged out everywhere
+func (server *server) handleAdminUsersBan(banned bool) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "AdminUsersBan"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		currentUser := req.Context().Value(ctxKeyUser).(*model.User)
+		if currentUser.ID == id {
+			errWrapped := errors.Wrap(errChangeOwnAccount, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		if err := server.store.Users().UpdateBanned(banned, id); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %d; Banned = %t", id, banned))
+			server.storeError(writer, req, errWrapped)
+			return
+		}
+
+		if banned {
+			if err := server.tokens.DeleteAllAuths(id); err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
@@ -0,0 +1,165 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type responseBestPrice struct {
+	Market           string `json:"market"`
+	InitialFormatted string `json:"initial_formatted"`
+	FinalFormatted   string `json:"final_formatted"`
+	DiscountPercent  int    `json:"discount_percent"`
+	MarketGameURL    string `json:"uri_string"`
+}
+
+func newResponseBestPrice(gameMarketPrice *model.GameMarketPrice) *responseBestPrice {
+	if gameMarketPrice == nil {
+		return nil
+	}
+
+	return &responseBestPrice{
+		Market:           gameMarketPrice.Market.Name,
+		InitialFormatted: gameMarketPrice.InitialValueFormatted,
+		FinalFormatted:   gameMarketPrice.FinalValueFormatted,
+		DiscountPercent:  gameMarketPrice.DiscountPercent,
+		MarketGameURL:    gameMarketPrice.MarketGameURL,
+	}
+}
+
+// bestPrices returns the cheapest offer in enabled markets by game ID, games that aren't sold there are missing.
+// Empty enabled markets mean every market, the same way as in model.UserPreferences.
+func (server *server) bestPrices(games []*model.Game, enabledMarkets []string) (map[uint64]*model.GameMarketPrice, error) {
+	gameMarketPrices, err := server.store.GameMarketPrices().FindAllBestByGames(games, enabledMarkets)
+	if err != nil {
+		return nil, err
+	}
+
+	bestPrices := map[uint64]*model.GameMarketPrice{}
+	for _, gameMarketPrice := range gameMarketPrices {
+		bestPrices[gameMarketPrice.Game.ID] = gameMarketPrice
+	}
+
+	return bestPrices, nil
+}
+
+func (server *server) handleDeals() http.HandlerFunc {
+	type responseItem struct {
+		GameID           uint64  `json:"game_id"`
+		HeaderImageURL   string  `json:"header_image"`
+		Name             string  `json:"name"`
+		Market           string  `json:"market"`
+		InitialFormatted string  `json:"initial_formatted"`
+		FinalFormatted   string  `json:"final_formatted"`
+		DiscountPercent  int     `json:"discount_percent"`
+		MarketGameURL    string  `json:"uri_string"`
+		SaleStartsAt     *string `json:"sale_starts_at"`
+		SaleEndsAt       *string `json:"sale_ends_at"`
+		responsePricesLocale
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Deals"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		sort := store.DealsSort(req.URL.Query().Get("sort"))
+		switch sort {
+		case "":
+			sort = store.DealsSortDiscount
+		case store.DealsSortEndingSoon, store.DealsSortDiscount, store.DealsSortPrice:
+		default:
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Sort = %s", sort))
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		limit := 100
+		if limitRaw := req.URL.Query().Get("limit"); limitRaw != "" {
+			limitParsed, err := strconv.Atoi(limitRaw)
+			if err != nil || limitParsed < 1 || limitParsed > 500 {
+				errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+				errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Limit = %s", limitRaw))
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+				return
+			}
+			limit = limitParsed
+		}
+
+		excludeOwned, err := parseExcludeOwned(req)
+		if err != nil {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			errWrapped = errors.Wrap(errWrapped, err.Error())
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		filter := store.DealsFilter{
+			EnabledMarkets: userPreferences.EnabledMarkets,
+			HiddenTags:     userPreferences.HiddenTags,
+		}
+		if excludeOwned {
+			filter.ExcludeOwnedBy = user
+		}
+
+		gameMarketPrices, err := server.store.GameMarketPrices().FindAllDeals(sort, limit, filter)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		pricesLocale := newResponsePricesLocale(userPreferences)
+
+		responseData := []responseItem{}
+
+		for _, gameMarketPrice := range gameMarketPrices {
+			responseItemStruct := responseItem{
+				GameID:           gameMarketPrice.Game.ID,
+				HeaderImageURL:   gameMarketPrice.Game.HeaderImageURL,
+				Name:             gameMarketPrice.Game.Name,
+				Market:           gameMarketPrice.Market.Name,
+				InitialFormatted: gameMarketPrice.InitialValueFormatted,
+				FinalFormatted:   gameMarketPrice.FinalValueFormatted,
+				DiscountPercent:  gameMarketPrice.DiscountPercent,
+				MarketGameURL:    gameMarketPrice.MarketGameURL,
+
+				responsePricesLocale: pricesLocale,
+			}
+
+			if gameMarketPrice.SaleStartsAt != nil {
+				saleStartsAt := gameMarketPrice.SaleStartsAt.Format(responseDateTimeLayout)
+				responseItemStruct.SaleStartsAt = &saleStartsAt
+			}
+
+			if gameMarketPrice.SaleEndsAt != nil {
+				saleEndsAt := gameMarketPrice.SaleEndsAt.Format(responseDateTimeLayout)
+				responseItemStruct.SaleEndsAt = &saleEndsAt
+			}
+
+			responseData = append(responseData, responseItemStruct)
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
@@ -3,17 +3,25 @@ package apiserver
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/forecast"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
+const (
+	responseDateTimeLayout = time.RFC3339
+	offerDelistedMessage   = "No longer sold"
+)
+
 // TODO: handleTags (list of all tag names)
 
 // TODO: add offset param
@@ -22,14 +30,17 @@ func (server *server) handleGames() http.HandlerFunc {
 		Query string   `json:"query,omitempty"`
 		Tags  []string `json:"tags,omitempty"`
 		Limit int      `json:"limit,omitempty"`
+		// ExcludeOwned hides games the user already has
+		ExcludeOwned bool `json:"exclude_owned,omitempty"`
 	}
 	type responseItem struct {
-		ID             uint64   `json:"id"`
-		HeaderImageURL string   `json:"header_image"`
-		Name           string   `json:"name"`
-		Publisher      string   `json:"publisher"`
-		ReleaseDate    string   `json:"release_date"`
-		Tags           []string `json:"tags"`
+		ID              uint64   `json:"id"`
+		HeaderImageURL  string   `json:"header_image"`
+		Name            string   `json:"name"`
+		Publisher       string   `json:"publisher"`
+		ReleaseDate     string   `json:"release_date"`
+		Tags            []string `json:"tags"`
+		IsHistoricalLow bool     `json:"is_historical_low"`
 	}
 
 	return func(writer http.ResponseWriter, req *http.Request) {
@@ -80,12 +91,50 @@ func (server *server) handleGames() http.HandlerFunc {
 			return
 		}
 
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		ownedGameIDs := map[uint64]bool{}
+		if requestStruct.ExcludeOwned {
+			ownedGameIDs, err = server.ownedGameIDs(user)
+			if err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+		}
+
+		priceStatsAll, err := server.store.PriceStats().FindAllByGames(games, time.Now())
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		priceStatsByGame := make(map[uint64][]*model.PriceStats)
+		for _, priceStats := range priceStatsAll {
+			gameID := priceStats.GameMarketPrice.Game.ID
+			priceStatsByGame[gameID] = append(priceStatsByGame[gameID], priceStats)
+		}
+
 		responseData := []responseItem{}
 
 		for _, game := range games {
 			if len(responseData) >= requestStruct.Limit {
 				break
 			}
+			if ownedGameIDs[game.ID] {
+				continue
+			}
 			tags, err := server.store.Tags().FindAllByGame(game)
 			if err != nil {
 				errWrapped := errors.Wrap(err, errWrapMessage)
@@ -99,13 +148,29 @@ func (server *server) handleGames() http.HandlerFunc {
 				tagNames = append(tagNames, tag.Name)
 			}
 
+			if userPreferences.IsHidden(tagNames) {
+				continue
+			}
+
+			isHistoricalLow := false
+			for _, priceStats := range priceStatsByGame[game.ID] {
+				if !userPreferences.IsMarketEnabled(priceStats.GameMarketPrice.Market.Name) {
+					continue
+				}
+				if priceStats.IsHistoricalLow() {
+					isHistoricalLow = true
+					break
+				}
+			}
+
 			responseItemStruct := responseItem{
-				ID:             game.ID,
-				HeaderImageURL: game.HeaderImageURL,
-				Name:           game.Name,
-				Publisher:      game.Publisher.Name,
-				ReleaseDate:    game.ReleaseDate,
-				Tags:           tagNames,
+				ID:              game.ID,
+				HeaderImageURL:  game.HeaderImageURL,
+				Name:            game.Name,
+				Publisher:       game.Publisher.Name,
+				ReleaseDate:     game.ReleaseDate,
+				Tags:            tagNames,
+				IsHistoricalLow: isHistoricalLow,
 			}
 
 			responseData = append(responseData, responseItemStruct)
@@ -116,11 +181,32 @@ func (server *server) handleGames() http.HandlerFunc {
 }
 
 func (server *server) handleGamesGetByID() http.HandlerFunc {
+	type responsePricesItemStats struct {
+		AllTimeLow   int     `json:"all_time_low"`
+		AllTimeLowAt string  `json:"all_time_low_at"`
+		Low30Days    int     `json:"low_30_days"`
+		Low90Days    int     `json:"low_90_days"`
+		Low365Days   int     `json:"low_365_days"`
+		AveragePrice int     `json:"average_price"`
+		SalesCount   int     `json:"sales_count"`
+		SalesPerYear float64 `json:"sales_per_year"`
+	}
 	type responsePricesItem struct {
-		InitialFormatted string `json:"initial_formatted"`
-		FinalFormatted   string `json:"final_formatted"`
-		DiscountPercent  int    `json:"discount_percent"`
-		MarketGameURL    string `json:"uri_string"`
+		InitialFormatted string                   `json:"initial_formatted"`
+		FinalFormatted   string                   `json:"final_formatted"`
+		DiscountPercent  int                      `json:"discount_percent"`
+		MarketGameURL    string                   `json:"uri_string"`
+		IsAvailable      bool                     `json:"is_available"`
+		LastSeenAt       string                   `json:"last_seen_at"`
+		SaleEndsAt       *string                  `json:"sale_ends_at"`
+		IsHistoricalLow  bool                     `json:"is_historical_low"`
+		Stats            *responsePricesItemStats `json:"stats"`
+	}
+	type responseSaleForecast struct {
+		HorizonDays     int     `json:"horizon_days"`
+		Probability     float64 `json:"probability"`
+		DiscountPercent int     `json:"discount_percent"`
+		SalesObserved   int     `json:"sales_observed"`
 	}
 	type response struct {
 		ID             uint64                        `json:"id"`
@@ -132,6 +218,10 @@ func (server *server) handleGamesGetByID() http.HandlerFunc {
 		IsFavourite    bool                          `json:"is_favourite"`
 		Tags           []string                      `json:"tags"`
 		Prices         map[string]responsePricesItem `json:"prices"`
+		SaleForecast   *responseSaleForecast         `json:"sale_forecast"`
+		// IsHidden is set when the game has tags the user hid, the game is still shown if asked for directly
+		IsHidden bool `json:"is_hidden"`
+		responsePricesLocale
 	}
 
 	return func(writer http.ResponseWriter, req *http.Request) {
@@ -176,6 +266,15 @@ func (server *server) handleGamesGetByID() http.HandlerFunc {
 		}
 
 		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
 		isFavourite := false
 
 		if _, err := server.store.UserGameFavourites().FindByUserGame(user, game); err == nil {
@@ -197,6 +296,9 @@ func (server *server) handleGamesGetByID() http.HandlerFunc {
 			IsFavourite:    isFavourite,
 			Tags:           tagNames,
 			Prices:         make(map[string]responsePricesItem),
+			IsHidden:       userPreferences.IsHidden(tagNames),
+
+			responsePricesLocale: newResponsePricesLocale(userPreferences),
 		}
 
 		gameMarketPrices, err := server.store.GameMarketPrices().FindAllByGame(game)
@@ -207,12 +309,59 @@ func (server *server) handleGamesGetByID() http.HandlerFunc {
 			return
 		}
 
+		now := time.Now()
+
+		priceStatsAll, err := server.store.PriceStats().FindAllByGame(game, now)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		priceStatsByOffer := make(map[uint64]*model.PriceStats)
+		for _, priceStats := range priceStatsAll {
+			priceStatsByOffer[priceStats.GameMarketPrice.ID] = priceStats
+		}
+
 		for _, gameMarketPrice := range gameMarketPrices {
+			if !userPreferences.IsMarketEnabled(gameMarketPrice.Market.Name) {
+				continue
+			}
+
 			responsePricesItemStruct := responsePricesItem{
 				InitialFormatted: gameMarketPrice.InitialValueFormatted,
 				FinalFormatted:   gameMarketPrice.FinalValueFormatted,
 				DiscountPercent:  gameMarketPrice.DiscountPercent,
 				MarketGameURL:    gameMarketPrice.MarketGameURL,
+				IsAvailable:      !gameMarketPrice.Delisted,
+				LastSeenAt:       gameMarketPrice.LastSeenAt.Format(responseDateTimeLayout),
+			}
+
+			if gameMarketPrice.SaleEndsAt != nil {
+				saleEndsAt := gameMarketPrice.SaleEndsAt.Format(responseDateTimeLayout)
+				responsePricesItemStruct.SaleEndsAt = &saleEndsAt
+			}
+
+			if priceStats, ok := priceStatsByOffer[gameMarketPrice.ID]; ok {
+				responsePricesItemStruct.IsHistoricalLow = priceStats.IsHistoricalLow()
+				responsePricesItemStruct.Stats = &responsePricesItemStats{
+					AllTimeLow:   priceStats.AllTimeLow,
+					AllTimeLowAt: priceStats.AllTimeLowAt.Format(responseDateTimeLayout),
+					Low30Days:    priceStats.Low30Days,
+					Low90Days:    priceStats.Low90Days,
+					Low365Days:   priceStats.Low365Days,
+					AveragePrice: priceStats.AveragePrice,
+					SalesCount:   priceStats.SalesCount,
+					SalesPerYear: priceStats.SalesPerYear(now),
+				}
+			}
+
+			if gameMarketPrice.Delisted {
+				responsePricesItemStruct.SaleEndsAt = nil
+				responsePricesItemStruct.InitialFormatted = ""
+				responsePricesItemStruct.FinalFormatted = offerDelistedMessage
+				responsePricesItemStruct.DiscountPercent = 0
 			}
 
 			// TODO: just use market name lowercased
@@ -228,6 +377,31 @@ func (server *server) handleGamesGetByID() http.HandlerFunc {
 			}
 		}
 
+		gameRecords, err := server.store.PriceStats().FindAllRecordsByGame(game)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		publisherRecords, err := server.store.PriceStats().FindAllRecordsByPublisher(game.Publisher)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if saleForecast := forecast.Estimate(game, gameRecords, publisherRecords, now); saleForecast != nil {
+			responseStruct.SaleForecast = &responseSaleForecast{
+				HorizonDays:     forecast.HorizonDays,
+				Probability:     math.Round(saleForecast.Probability*100) / 100,
+				DiscountPercent: saleForecast.DiscountPercent,
+				SalesObserved:   saleForecast.SalesObserved,
+			}
+		}
+
 		server.respond(writer, req, http.StatusOK, responseStruct)
 	}
 }
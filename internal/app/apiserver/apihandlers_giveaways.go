@@ -0,0 +1,78 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// handleGiveaways answers giveaways without dates (GOG doesn't publish them) with null dates, they are active
+func (server *server) handleGiveaways() http.HandlerFunc {
+	type responseItem struct {
+		ID            uint64  `json:"id"`
+		Title         string  `json:"title"`
+		Market        string  `json:"market"`
+		MarketGameURL string  `json:"market_game_url"`
+		StartsAt      *string `json:"starts_at"`
+		EndsAt        *string `json:"ends_at"`
+		GameID        *uint64 `json:"game_id"`
+	}
+	type response struct {
+		Active   []responseItem `json:"active"`
+		Upcoming []responseItem `json:"upcoming"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Giveaways"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		now := time.Now()
+
+		giveaways, err := server.store.Giveaways().FindAllNotEnded(now)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseData := response{
+			Active:   []responseItem{},
+			Upcoming: []responseItem{},
+		}
+
+		for _, giveaway := range giveaways {
+			responseItemStruct := responseItem{
+				ID:            giveaway.ID,
+				Title:         giveaway.Title,
+				Market:        giveaway.Market.Name,
+				MarketGameURL: giveaway.MarketGameURL,
+			}
+
+			if giveaway.StartsAt != nil {
+				startsAt := giveaway.StartsAt.Format(responseDateTimeLayout)
+				responseItemStruct.StartsAt = &startsAt
+			}
+
+			if giveaway.EndsAt != nil {
+				endsAt := giveaway.EndsAt.Format(responseDateTimeLayout)
+				responseItemStruct.EndsAt = &endsAt
+			}
+
+			if giveaway.Game != nil {
+				gameID := giveaway.Game.ID
+				responseItemStruct.GameID = &gameID
+			}
+
+			if giveaway.StartsAt != nil && giveaway.StartsAt.After(now) {
+				responseData.Upcoming = append(responseData.Upcoming, responseItemStruct)
+			} else {
+				responseData.Active = append(responseData.Active, responseItemStruct)
+			}
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
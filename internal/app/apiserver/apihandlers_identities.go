@@ -0,0 +1,573 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/identity"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+const (
+	// Time the user has to log in at the provider
+	externalStateTTL = 10 * time.Minute
+	// Time the user has to choose username after unknown identity logged in
+	externalRegistrationTTL = 30 * time.Minute
+)
+
+// Provider redirects the user to the frontend, which posts the parameters it got to the callback handlers
+func (server *server) externalRedirectURI(providerName string, linking bool) string {
+	if linking {
+		return fmt.Sprintf("%s/auth/%s/link/callback", server.appBaseURL, url.PathEscape(providerName))
+	}
+
+	return fmt.Sprintf("%s/auth/%s/callback", server.appBaseURL, url.PathEscape(providerName))
+}
+
+// externalProvider answers 404 itself if the provider from the path isn't configured
+func (server *server) externalProvider(writer http.ResponseWriter, req *http.Request) (identity.Provider, bool) {
+	provider, err := server.identities.Provider(mux.Vars(req)["provider"])
+	if err != nil {
+		server.error(writer, req, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	return provider, true
+}
+
+// externalAuthURL saves state of the flow and returns where the user must be sent
+func (server *server) externalAuthURL(provider identity.Provider, purpose tokenutils.OneTimeTokenPurpose, userID uint64) (string, error) {
+	nonce, err := identity.NewNonce()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := server.tokens.CreateOneTimeToken(purpose, &tokenutils.OneTimeTokenDetails{
+		UserID:   userID,
+		Provider: provider.Name(),
+		Nonce:    nonce,
+	}, externalStateTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthURL(state, nonce, server.externalRedirectURI(provider.Name(), purpose == tokenutils.PurposeExternalLink))
+}
+
+// exchangeExternal checks state of the flow and asks the provider who the user is
+func (server *server) exchangeExternal(provider identity.Provider, purpose tokenutils.OneTimeTokenPurpose, state string, params map[string]string) (*identity.Identity, *tokenutils.OneTimeTokenDetails, error) {
+	stateDetails, err := server.tokens.ConsumeOneTimeToken(purpose, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if stateDetails.Provider != provider.Name() {
+		return nil, nil, tokenutils.ErrTokenExpiredOrDeleted
+	}
+
+	paramsValues := url.Values{}
+	for key, value := range params {
+		paramsValues.Set(key, value)
+	}
+
+	externalIdentity, err := provider.Exchange(paramsValues, state, stateDetails.Nonce, server.externalRedirectURI(provider.Name(), purpose == tokenutils.PurposeExternalLink))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return externalIdentity, stateDetails, nil
+}
+
+// externalError answers errors of externalAuthURL and exchangeExternal
+func (server *server) externalError(writer http.ResponseWriter, req *http.Request, err error) {
+	switch errors.Cause(err) {
+	case tokenutils.ErrTokenExpiredOrDeleted, tokenutils.ErrTokenDamaged, identity.ErrAssertionInvalid:
+		server.error(writer, req, http.StatusForbidden, err)
+	case identity.ErrProviderResponse:
+		server.log(err)
+		server.error(writer, req, http.StatusBadGateway, err)
+	default:
+		server.log(err)
+		server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+	}
+}
+
+// completeLogin is the end of every way to log in: banned users are stopped
+// and users with second factor get the challenge instead of tokens
+func (server *server) completeLogin(writer http.ResponseWriter, req *http.Request, user *model.User, deviceName string) {
+	methodName := "completeLogin"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	if user.Banned {
+		errWrapped := errors.Wrap(errUserBanned, errWrapMessage)
+		server.error(writer, req, http.StatusForbidden, errWrapped)
+		return
+	}
+
+	twoFactorEnabled, err := server.twoFactorEnabled(user)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		server.log(errWrapped)
+		server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+		return
+	}
+
+	// Tokens are issued by handleLoginTwoFactor after the code is checked
+	if twoFactorEnabled {
+		server.startLoginChallenge(writer, req, user)
+		return
+	}
+
+	tokenDetails, err := server.startSession(user, req, deviceName)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		server.log(errWrapped)
+		server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+		return
+	}
+
+	tokens := map[string]string{
+		"access_token":  tokenDetails.AccessToken,
+		"refresh_token": tokenDetails.RefreshToken,
+	}
+
+	server.respond(writer, req, http.StatusOK, tokens)
+}
+
+func (server *server) handleExternalAuth() http.HandlerFunc {
+	type response struct {
+		AuthURL string `json:"auth_url"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "ExternalAuth"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		provider, ok := server.externalProvider(writer, req)
+		if !ok {
+			return
+		}
+
+		authURL, err := server.externalAuthURL(provider, tokenutils.PurposeExternalLogin, 0)
+		if err != nil {
+			server.externalError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, response{
+			AuthURL: authURL,
+		})
+	}
+}
+
+// handleExternalCallback logs in the user the identity is linked to.
+// Unknown identity gets registration token to choose username with handleExternalRegistration.
+func (server *server) handleExternalCallback() http.HandlerFunc {
+	type request struct {
+		State      string            `json:"state"`
+		Params     map[string]string `json:"params"`
+		DeviceName string            `json:"device_name,omitempty"`
+	}
+
+	type responseRegistration struct {
+		RegistrationRequired bool   `json:"registration_required"`
+		RegistrationToken    string `json:"registration_token"`
+		Email                string `json:"email"`
+		Username             string `json:"username"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "ExternalCallback"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		provider, ok := server.externalProvider(writer, req)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		externalIdentity, _, err := server.exchangeExternal(provider, tokenutils.PurposeExternalLogin, requestStruct.State, requestStruct.Params)
+		if err != nil {
+			server.externalError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		userIdentity, err := server.store.UserIdentities().FindByProviderSubject(externalIdentity.Provider, externalIdentity.Subject)
+		if err != nil && errors.Cause(err) != store.ErrNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if err == nil {
+			user, err := server.store.Users().Find(userIdentity.User.ID)
+			if err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+
+			server.completeLogin(writer, req, user, requestStruct.DeviceName)
+			return
+		}
+
+		// Accounts are never linked by email automatically, the user must log in and link them
+		registrationToken, err := server.tokens.CreateOneTimeToken(tokenutils.PurposeExternalRegistration, &tokenutils.OneTimeTokenDetails{
+			Email:    externalIdentity.Email,
+			Provider: externalIdentity.Provider,
+			Subject:  externalIdentity.Subject,
+			Name:     externalIdentity.Name,
+		}, externalRegistrationTTL)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, responseRegistration{
+			RegistrationRequired: true,
+			RegistrationToken:    registrationToken,
+			Email:                externalIdentity.Email,
+			Username:             externalIdentity.Name,
+		})
+	}
+}
+
+// handleExternalRegistration creates user without password for the identity of registration token
+func (server *server) handleExternalRegistration() http.HandlerFunc {
+	type request struct {
+		RegistrationToken string `json:"registration_token"`
+		Username          string `json:"username"`
+		Email             string `json:"email"`
+		DeviceName        string `json:"device_name,omitempty"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "ExternalRegistration"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		// Token is kept until the user is created, so taken username can be changed
+		registrationDetails, err := server.tokens.PeekOneTimeToken(tokenutils.PurposeExternalRegistration, requestStruct.RegistrationToken)
+		if err != nil {
+			server.externalError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		user := &model.User{
+			Username: requestStruct.Username,
+			Email:    requestStruct.Email,
+		}
+
+		if err := user.ValidateWithoutPassword(); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		responseError := map[string]string{}
+
+		if _, err := server.store.Users().FindBy("username", user.Username); err == nil {
+			responseError["username"] = errUserExistsUsernameMessage
+		} else if errors.Cause(err) != store.ErrNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if _, err := server.store.Users().FindBy("email", user.Email); err == nil {
+			responseError["email"] = errUserExistsEmailMessage
+		} else if errors.Cause(err) != store.ErrNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if len(responseError) > 0 {
+			server.respond(writer, req, http.StatusOK, map[string]map[string]string{"error": responseError})
+			return
+		}
+
+		if _, err := server.tokens.ConsumeOneTimeToken(tokenutils.PurposeExternalRegistration, requestStruct.RegistrationToken); err != nil {
+			server.externalError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		if err := server.store.Users().CreateWithoutPassword(user); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userIdentity := &model.UserIdentity{
+			Provider:  registrationDetails.Provider,
+			Subject:   registrationDetails.Subject,
+			Email:     registrationDetails.Email,
+			CreatedAt: time.Now(),
+			User:      user,
+		}
+
+		if err := server.store.UserIdentities().Create(userIdentity); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		// Email verified by the provider isn't verified again
+		if registrationDetails.Email != "" && registrationDetails.Email == user.Email {
+			if err := server.store.Users().UpdateEmailVerified(true, user.ID); err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+			}
+			user.EmailVerified = true
+		} else if err := server.sendEmailVerification(user); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+		}
+
+		server.completeLogin(writer, req, user, requestStruct.DeviceName)
+	}
+}
+
+type responseIdentity struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+func newResponseIdentity(userIdentity *model.UserIdentity) responseIdentity {
+	return responseIdentity{
+		Provider:  userIdentity.Provider,
+		Subject:   userIdentity.Subject,
+		Email:     userIdentity.Email,
+		CreatedAt: userIdentity.CreatedAt.Format(responseDateTimeLayout),
+	}
+}
+
+func (server *server) handleIdentities() http.HandlerFunc {
+	type response struct {
+		Identities  []responseIdentity `json:"identities"`
+		Providers   []string           `json:"providers"`
+		HasPassword bool               `json:"has_password"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Identities"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userIdentities, err := server.store.UserIdentities().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseStruct := response{
+			Identities:  []responseIdentity{},
+			Providers:   server.identities.Names(),
+			HasPassword: user.HasPassword(),
+		}
+
+		for _, userIdentity := range userIdentities {
+			responseStruct.Identities = append(responseStruct.Identities, newResponseIdentity(userIdentity))
+		}
+
+		server.respond(writer, req, http.StatusOK, responseStruct)
+	}
+}
+
+func (server *server) handleIdentitiesLink() http.HandlerFunc {
+	type response struct {
+		AuthURL string `json:"auth_url"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "IdentitiesLink"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		provider, ok := server.externalProvider(writer, req)
+		if !ok {
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		authURL, err := server.externalAuthURL(provider, tokenutils.PurposeExternalLink, user.ID)
+		if err != nil {
+			server.externalError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, response{
+			AuthURL: authURL,
+		})
+	}
+}
+
+func (server *server) handleIdentitiesLinkCallback() http.HandlerFunc {
+	type request struct {
+		State  string            `json:"state"`
+		Params map[string]string `json:"params"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "IdentitiesLinkCallback"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		provider, ok := server.externalProvider(writer, req)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		externalIdentity, stateDetails, err := server.exchangeExternal(provider, tokenutils.PurposeExternalLink, requestStruct.State, requestStruct.Params)
+		if err != nil {
+			server.externalError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		// Linking was started by another user
+		if stateDetails.UserID != user.ID {
+			errWrapped := errors.Wrap(tokenutils.ErrTokenExpiredOrDeleted, errWrapMessage)
+			server.error(writer, req, http.StatusForbidden, errWrapped)
+			return
+		}
+
+		userIdentity, err := server.store.UserIdentities().FindByProviderSubject(externalIdentity.Provider, externalIdentity.Subject)
+		if err == nil {
+			if userIdentity.User.ID == user.ID {
+				server.respond(writer, req, http.StatusOK, newResponseIdentity(userIdentity))
+				return
+			}
+
+			errWrapped := errors.Wrap(errIdentityLinkedToOther, errWrapMessage)
+			server.error(writer, req, http.StatusConflict, errWrapped)
+			return
+		}
+
+		if errors.Cause(err) != store.ErrNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userIdentities, err := server.store.UserIdentities().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		for _, linkedIdentity := range userIdentities {
+			if linkedIdentity.Provider == externalIdentity.Provider {
+				errWrapped := errors.Wrap(errIdentityProviderLinked, errWrapMessage)
+				server.error(writer, req, http.StatusConflict, errWrapped)
+				return
+			}
+		}
+
+		userIdentity = &model.UserIdentity{
+			Provider:  externalIdentity.Provider,
+			Subject:   externalIdentity.Subject,
+			Email:     externalIdentity.Email,
+			CreatedAt: time.Now(),
+			User:      user,
+		}
+
+		if err := server.store.UserIdentities().Create(userIdentity); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusCreated, newResponseIdentity(userIdentity))
+	}
+}
+
+// handleIdentitiesUnlink keeps at least one way to log in
+func (server *server) handleIdentitiesUnlink() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "IdentitiesUnlink"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		providerName := mux.Vars(req)["provider"]
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		if !user.HasPassword() {
+			userIdentities, err := server.store.UserIdentities().FindAllByUser(user)
+			if err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+
+			if len(userIdentities) <= 1 {
+				errWrapped := errors.Wrap(errLastLoginMethod, errWrapMessage)
+				server.error(writer, req, http.StatusConflict, errWrapped)
+				return
+			}
+		}
+
+		if err := server.store.UserIdentities().DeleteByUserProvider(user, providerName); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case store.ErrNotFound:
+				server.error(writer, req, http.StatusNotFound, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
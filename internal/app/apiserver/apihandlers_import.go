@@ -0,0 +1,62 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/identity"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/steamimport"
+)
+
+// handleImportSteam adds wishlist of the linked Steam account to favourites
+// and marks games of its library as owned
+func (server *server) handleImportSteam() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "ImportSteam"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userIdentities, err := server.store.UserIdentities().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		steamID := ""
+		for _, userIdentity := range userIdentities {
+			if userIdentity.Provider == identity.SteamProviderName {
+				steamID = userIdentity.Subject
+			}
+		}
+
+		if steamID == "" {
+			errWrapped := errors.Wrap(errSteamNotLinked, errWrapMessage)
+			server.error(writer, req, http.StatusConflict, errWrapped)
+			return
+		}
+
+		result, err := server.steamImporter.Import(user, steamID)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case steamimport.ErrSteamResponse, steamimport.ErrInternal:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadGateway, errSteamUnavailable)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, result)
+	}
+}
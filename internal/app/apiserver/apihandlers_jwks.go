@@ -0,0 +1,23 @@
+package apiserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+// handleJWKS publishes public keys, so other services can verify our tokens
+func (server *server) handleJWKS() http.HandlerFunc {
+	type response struct {
+		Keys []tokenutils.JSONWebKey `json:"keys"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Cache-Control", "public, max-age=300")
+		server.respond(writer, req, http.StatusOK, response{
+			Keys: server.tokens.PublicJSONWebKeys(time.Now()),
+		})
+	}
+}
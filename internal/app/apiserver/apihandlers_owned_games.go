@@ -0,0 +1,241 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type responseOwnedGame struct {
+	ID             uint64  `json:"id"`
+	GameID         uint64  `json:"game_id"`
+	HeaderImageURL string  `json:"header_image"`
+	Name           string  `json:"name"`
+	Source         string  `json:"source"`
+	Market         *string `json:"market"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+func newResponseOwnedGame(userOwnedGame *model.UserOwnedGame) responseOwnedGame {
+	responseOwnedGameStruct := responseOwnedGame{
+		ID:             userOwnedGame.ID,
+		GameID:         userOwnedGame.Game.ID,
+		HeaderImageURL: userOwnedGame.Game.HeaderImageURL,
+		Name:           userOwnedGame.Game.Name,
+		Source:         userOwnedGame.Source,
+		CreatedAt:      userOwnedGame.CreatedAt.Format(responseDateTimeLayout),
+	}
+
+	if userOwnedGame.Market != nil {
+		responseOwnedGameStruct.Market = &userOwnedGame.Market.Name
+	}
+
+	return responseOwnedGameStruct
+}
+
+// ownedGameIDs is used to hide games the user already has
+func (server *server) ownedGameIDs(user *model.User) (map[uint64]bool, error) {
+	userOwnedGames, err := server.store.UserOwnedGames().FindAllByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	ownedGameIDs := map[uint64]bool{}
+	for _, userOwnedGame := range userOwnedGames {
+		ownedGameIDs[userOwnedGame.Game.ID] = true
+	}
+
+	return ownedGameIDs, nil
+}
+
+// parseExcludeOwned reads "exclude_owned" query parameter, it is false if missing
+func parseExcludeOwned(req *http.Request) (bool, error) {
+	excludeOwnedRaw := req.URL.Query().Get("exclude_owned")
+	if excludeOwnedRaw == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(excludeOwnedRaw)
+}
+
+// findUserOwnedGame answers 404 itself, if the record doesn't exist or belongs to another user
+func (server *server) findUserOwnedGame(writer http.ResponseWriter, req *http.Request, errWrapMessage string) (*model.UserOwnedGame, bool) {
+	id, err := parseIDVar(req)
+	if err != nil {
+		errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+		server.error(writer, req, http.StatusBadRequest, errWrapped)
+		return nil, false
+	}
+
+	user := req.Context().Value(ctxKeyUser).(*model.User)
+
+	userOwnedGame, err := server.store.UserOwnedGames().Find(id)
+	if err == nil && userOwnedGame.User.ID != user.ID {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+		return nil, false
+	}
+
+	return userOwnedGame, true
+}
+
+// findOwnedGameMarket returns nil for zero ID, which means the market is unknown
+func (server *server) findOwnedGameMarket(marketID uint64) (*model.Market, error) {
+	if marketID == 0 {
+		return nil, nil
+	}
+
+	return server.store.Markets().Find(marketID)
+}
+
+func (server *server) handleOwnedGames() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "OwnedGames"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userOwnedGames, err := server.store.UserOwnedGames().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseData := []responseOwnedGame{}
+		for _, userOwnedGame := range userOwnedGames {
+			responseData = append(responseData, newResponseOwnedGame(userOwnedGame))
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
+
+// handleOwnedGamesCreate marks the game as owned manually, games already owned are returned unchanged
+func (server *server) handleOwnedGamesCreate() http.HandlerFunc {
+	type request struct {
+		GameID   uint64 `json:"game_id"`
+		MarketID uint64 `json:"market_id,omitempty"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "OwnedGamesCreate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		game, err := server.store.Games().Find(requestStruct.GameID)
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		market, err := server.findOwnedGameMarket(requestStruct.MarketID)
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		userOwnedGame := &model.UserOwnedGame{
+			Source:    model.OwnedGameSourceManual,
+			CreatedAt: time.Now(),
+			User:      user,
+			Game:      game,
+			Market:    market,
+		}
+
+		if err := server.store.UserOwnedGames().Create(userOwnedGame); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		// Record may have existed before, so it is read back
+		userOwnedGame, err = server.store.UserOwnedGames().Find(userOwnedGame.ID)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusCreated, newResponseOwnedGame(userOwnedGame))
+	}
+}
+
+// handleOwnedGamesUpdate changes the market the game is owned in
+func (server *server) handleOwnedGamesUpdate() http.HandlerFunc {
+	type request struct {
+		MarketID uint64 `json:"market_id"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "OwnedGamesUpdate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		userOwnedGame, ok := server.findUserOwnedGame(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		market, err := server.findOwnedGameMarket(requestStruct.MarketID)
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		userOwnedGame.Market = market
+		if err := server.store.UserOwnedGames().Update(userOwnedGame); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponseOwnedGame(userOwnedGame))
+	}
+}
+
+func (server *server) handleOwnedGamesDelete() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "OwnedGamesDelete"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		userOwnedGame, ok := server.findUserOwnedGame(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		if err := server.store.UserOwnedGames().Delete(userOwnedGame.ID); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
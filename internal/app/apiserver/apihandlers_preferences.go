@@ -0,0 +1,177 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type responsePreferences struct {
+	Region         string   `json:"region"`
+	Currency       string   `json:"currency"`
+	Language       string   `json:"language"`
+	EnabledMarkets []string `json:"enabled_markets"`
+	HiddenTags     []string `json:"hidden_tags"`
+}
+
+func newResponsePreferences(userPreferences *model.UserPreferences) responsePreferences {
+	responsePreferencesStruct := responsePreferences{
+		Region:         userPreferences.Region,
+		Currency:       userPreferences.Currency,
+		Language:       userPreferences.Language,
+		EnabledMarkets: []string{},
+		HiddenTags:     []string{},
+	}
+
+	responsePreferencesStruct.EnabledMarkets = append(responsePreferencesStruct.EnabledMarkets, userPreferences.EnabledMarkets...)
+	responsePreferencesStruct.HiddenTags = append(responsePreferencesStruct.HiddenTags, userPreferences.HiddenTags...)
+
+	return responsePreferencesStruct
+}
+
+// responsePricesLocale tells the region and currency prices were collected in. Prices aren't converted,
+// so the mismatch flags warn the user, whose preferences differ, not to take them at face value.
+type responsePricesLocale struct {
+	PricesRegion     string `json:"prices_region"`
+	PricesCurrency   string `json:"prices_currency"`
+	RegionMismatch   bool   `json:"region_mismatch"`
+	CurrencyMismatch bool   `json:"currency_mismatch"`
+}
+
+func newResponsePricesLocale(userPreferences *model.UserPreferences) responsePricesLocale {
+	return responsePricesLocale{
+		PricesRegion:     model.DefaultRegion,
+		PricesCurrency:   model.DefaultCurrency,
+		RegionMismatch:   userPreferences.Region != model.DefaultRegion,
+		CurrencyMismatch: userPreferences.Currency != model.DefaultCurrency,
+	}
+}
+
+// userPreferences returns defaults if the user hasn't saved preferences yet
+func (server *server) userPreferences(user *model.User) (*model.UserPreferences, error) {
+	userPreferences, err := server.store.UserPreferences().FindByUser(user)
+	if err != nil {
+		if errors.Cause(err) == store.ErrNotFound {
+			return model.NewUserPreferences(user), nil
+		}
+
+		return nil, err
+	}
+
+	return userPreferences, nil
+}
+
+func (server *server) handlePreferences() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Preferences"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponsePreferences(userPreferences))
+	}
+}
+
+// handlePreferencesUpdate changes only the fields present in request,
+// markets and tags are referred to by their names
+func (server *server) handlePreferencesUpdate() http.HandlerFunc {
+	type request struct {
+		Region         *string   `json:"region"`
+		Currency       *string   `json:"currency"`
+		Language       *string   `json:"language"`
+		EnabledMarkets *[]string `json:"enabled_markets"`
+		HiddenTags     *[]string `json:"hidden_tags"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "PreferencesUpdate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if requestStruct.Region != nil {
+			userPreferences.Region = strings.ToUpper(*requestStruct.Region)
+		}
+		if requestStruct.Currency != nil {
+			userPreferences.Currency = strings.ToUpper(*requestStruct.Currency)
+		}
+		if requestStruct.Language != nil {
+			userPreferences.Language = strings.ToLower(*requestStruct.Language)
+		}
+
+		if requestStruct.EnabledMarkets != nil {
+			for _, marketName := range *requestStruct.EnabledMarkets {
+				if _, err := server.store.Markets().FindBy("name", marketName); err != nil {
+					errWrapped := errors.Wrap(err, errWrapMessage)
+					errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("MarketName = %s", marketName))
+					server.log(errWrapped)
+
+					if errors.Cause(err) == store.ErrNotFound {
+						server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+					} else {
+						server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+					}
+					return
+				}
+			}
+			userPreferences.EnabledMarkets = *requestStruct.EnabledMarkets
+		}
+
+		if requestStruct.HiddenTags != nil {
+			for _, tagName := range *requestStruct.HiddenTags {
+				if _, err := server.store.Tags().FindBy("name", tagName); err != nil {
+					errWrapped := errors.Wrap(err, errWrapMessage)
+					errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("TagName = %s", tagName))
+					server.log(errWrapped)
+
+					if errors.Cause(err) == store.ErrNotFound {
+						server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+					} else {
+						server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+					}
+					return
+				}
+			}
+			userPreferences.HiddenTags = *requestStruct.HiddenTags
+		}
+
+		userPreferences.UpdatedAt = time.Now()
+		if err := server.store.UserPreferences().Save(userPreferences); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponsePreferences(userPreferences))
+	}
+}
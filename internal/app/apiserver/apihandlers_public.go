@@ -0,0 +1,94 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+// wishlistSlugLength is number of random bytes in the slug of public list
+const wishlistSlugLength = 16
+
+func newWishlistSlug() (string, error) {
+	slug := make([]byte, wishlistSlugLength)
+	if _, err := rand.Read(slug); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(slug), nil
+}
+
+// handlePublicLists shows the public list to anyone knowing its slug,
+// hidden and revoked lists aren't distinguished from missing ones
+func (server *server) handlePublicLists() http.HandlerFunc {
+	type responseItem struct {
+		GameID         uint64             `json:"game_id"`
+		HeaderImageURL string             `json:"header_image"`
+		Name           string             `json:"name"`
+		Priority       int                `json:"priority"`
+		Note           string             `json:"note"`
+		BestPrice      *responseBestPrice `json:"best_price"`
+	}
+	type response struct {
+		Name  string         `json:"name"`
+		Owner string         `json:"owner"`
+		Items []responseItem `json:"items"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "PublicLists"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, err := server.store.Wishlists().FindByPublicSlug(mux.Vars(req)["slug"])
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		wishlistItems, err := server.store.WishlistItems().FindAllByWishlist(wishlist)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		games := []*model.Game{}
+		for _, wishlistItem := range wishlistItems {
+			games = append(games, wishlistItem.Game)
+		}
+
+		bestPrices, err := server.bestPrices(games, nil)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseStruct := response{
+			Name:  wishlist.Name,
+			Owner: wishlist.User.Username,
+			Items: []responseItem{},
+		}
+
+		for _, wishlistItem := range wishlistItems {
+			responseStruct.Items = append(responseStruct.Items, responseItem{
+				GameID:         wishlistItem.Game.ID,
+				HeaderImageURL: wishlistItem.Game.HeaderImageURL,
+				Name:           wishlistItem.Game.Name,
+				Priority:       wishlistItem.Priority,
+				Note:           wishlistItem.Note,
+				BestPrice:      newResponseBestPrice(bestPrices[wishlistItem.Game.ID]),
+			})
+		}
+
+		server.respond(writer, req, http.StatusOK, responseStruct)
+	}
+}
@@ -0,0 +1,288 @@
+package apiserver
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/recommend"
+)
+
+const recommendationReasonFormat = "Because you liked %s"
+
+// gameTagNames returns tag names of every game in the catalogue by game ID
+func (server *server) gameTagNames() (map[uint64][]string, error) {
+	gameTags, err := server.store.GameTags().FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return recommend.TagsByGame(gameTags), nil
+}
+
+// handleRecommendations suggests games similar to favourite and owned ones,
+// these games themselves and games with hidden tags aren't suggested
+func (server *server) handleRecommendations() http.HandlerFunc {
+	type responseItem struct {
+		GameID         uint64             `json:"game_id"`
+		HeaderImageURL string             `json:"header_image"`
+		Name           string             `json:"name"`
+		Publisher      string             `json:"publisher"`
+		Score          float64            `json:"score"`
+		Reason         string             `json:"reason"`
+		BecauseOfID    uint64             `json:"because_of_id"`
+		BestPrice      *responseBestPrice `json:"best_price"`
+		responsePricesLocale
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Recommendations"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		limit := 20
+		if limitRaw := req.URL.Query().Get("limit"); limitRaw != "" {
+			limitParsed, err := strconv.Atoi(limitRaw)
+			if err != nil || limitParsed < 1 || limitParsed > 100 {
+				errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+				errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Limit = %s", limitRaw))
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+				return
+			}
+			limit = limitParsed
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		liked, err := server.store.Games().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userOwnedGames, err := server.store.UserOwnedGames().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		for _, userOwnedGame := range userOwnedGames {
+			liked = append(liked, userOwnedGame.Game)
+		}
+
+		catalogue, err := server.store.Games().FindAll()
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		tagsByGame, err := server.gameTagNames()
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		bestPrices, err := server.bestPrices(catalogue, userPreferences.EnabledMarkets)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		discounts := map[uint64]int{}
+		for gameID, gameMarketPrice := range bestPrices {
+			discounts[gameID] = gameMarketPrice.DiscountPercent
+		}
+
+		exclude := map[uint64]bool{}
+		for _, game := range catalogue {
+			if userPreferences.IsHidden(tagsByGame[game.ID]) {
+				exclude[game.ID] = true
+			}
+		}
+
+		recommendations := recommend.Recommend(catalogue, liked, tagsByGame, discounts, exclude, limit)
+
+		pricesLocale := newResponsePricesLocale(userPreferences)
+
+		responseData := []responseItem{}
+		for _, recommendation := range recommendations {
+			responseItemStruct := responseItem{
+				GameID:         recommendation.Game.ID,
+				HeaderImageURL: recommendation.Game.HeaderImageURL,
+				Name:           recommendation.Game.Name,
+				Publisher:      recommendation.Game.Publisher.Name,
+				Score:          math.Round(recommendation.Score*1000) / 1000,
+				Reason:         fmt.Sprintf(recommendationReasonFormat, recommendation.BecauseOf.Name),
+				BecauseOfID:    recommendation.BecauseOf.ID,
+
+				responsePricesLocale: pricesLocale,
+			}
+
+			responseItemStruct.BestPrice = newResponseBestPrice(bestPrices[recommendation.Game.ID])
+
+			responseData = append(responseData, responseItemStruct)
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
+
+// handleGamesSimilar returns precomputed neighbours of the game, they are refreshed after catalogue syncs and moderator changes
+func (server *server) handleGamesSimilar() http.HandlerFunc {
+	type responseItem struct {
+		GameID         uint64             `json:"game_id"`
+		HeaderImageURL string             `json:"header_image"`
+		Name           string             `json:"name"`
+		Publisher      string             `json:"publisher"`
+		ReleaseDate    string             `json:"release_date"`
+		Score          float64            `json:"score"`
+		BestPrice      *responseBestPrice `json:"best_price"`
+		responsePricesLocale
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "GamesSimilar"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		id, err := parseIDVar(req)
+		if err != nil {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		limit := 10
+		if limitRaw := req.URL.Query().Get("limit"); limitRaw != "" {
+			limitParsed, err := strconv.Atoi(limitRaw)
+			if err != nil || limitParsed < 1 || limitParsed > recommend.NeighboursCount {
+				errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+				errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Limit = %s", limitRaw))
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+				return
+			}
+			limit = limitParsed
+		}
+
+		game, err := server.store.Games().Find(id)
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		// All neighbours are loaded, so hiding some of them doesn't leave the response short
+		gameSimilaritiesAll, err := server.store.GameSimilarities().FindAllByGame(game, recommend.NeighboursCount)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		tagsByGame, err := server.gameTagNames()
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		gameSimilarities := []*model.GameSimilarity{}
+		similarGames := []*model.Game{}
+		for _, gameSimilarity := range gameSimilaritiesAll {
+			if len(gameSimilarities) == limit {
+				break
+			}
+
+			if userPreferences.IsHidden(tagsByGame[gameSimilarity.SimilarGame.ID]) {
+				continue
+			}
+
+			gameSimilarities = append(gameSimilarities, gameSimilarity)
+			similarGames = append(similarGames, gameSimilarity.SimilarGame)
+		}
+
+		bestPrices, err := server.bestPrices(similarGames, userPreferences.EnabledMarkets)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		pricesLocale := newResponsePricesLocale(userPreferences)
+
+		responseData := []responseItem{}
+		for _, gameSimilarity := range gameSimilarities {
+			similarGame := gameSimilarity.SimilarGame
+			responseItemStruct := responseItem{
+				GameID:         similarGame.ID,
+				HeaderImageURL: similarGame.HeaderImageURL,
+				Name:           similarGame.Name,
+				Publisher:      similarGame.Publisher.Name,
+				ReleaseDate:    similarGame.ReleaseDate,
+				Score:          math.Round(gameSimilarity.Score*1000) / 1000,
+
+				responsePricesLocale: pricesLocale,
+			}
+
+			responseItemStruct.BestPrice = newResponseBestPrice(bestPrices[similarGame.ID])
+
+			responseData = append(responseData, responseItemStruct)
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
+
+// refreshNeighbours asks to recompute similar games after the catalogue was changed by moderators.
+// Refresh runs in the background, requests coming while it runs are merged into one more refresh.
+func (server *server) refreshNeighbours() {
+	select {
+	case server.neighboursRefresh <- struct{}{}:
+	default:
+	}
+}
+
+func (server *server) runNeighboursRefresh() {
+	methodName := "runNeighboursRefresh"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	for range server.neighboursRefresh {
+		if err := recommend.RefreshNeighbours(server.store); err != nil {
+			server.log(errors.Wrap(err, errWrapMessage))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+// handleRefreshTokenReuse answers refresh request with expired or deleted refresh token.
+// If the token has been rotated already, its family gets revoked and the reuse is recorded.
+func (server *server) handleRefreshTokenReuse(writer http.ResponseWriter, req *http.Request, refreshToken string, errExpired error) {
+	methodName := "RefreshTokenReuse"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	tokenDetails, err := server.tokens.RevokeReusedRefreshToken(refreshToken)
+	if err != nil {
+		switch errors.Cause(err) {
+		case tokenutils.ErrTokenExpiredOrDeleted, tokenutils.ErrTokenDamaged:
+			server.error(writer, req, http.StatusForbidden, errExpired)
+		default:
+			// Mostly TokenUtils.ErrInternal, probably something with Redis
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+		}
+
+		return
+	}
+
+	securityEvent := &model.SecurityEvent{
+		Type:      model.SecurityEventRefreshTokenReuse,
+		SessionID: tokenDetails.SessionID,
+		IP:        server.clientIP(req),
+		UserAgent: req.UserAgent(),
+		CreatedAt: time.Now(),
+		User:      &model.User{ID: tokenDetails.UserId},
+	}
+
+	// Family has already been revoked, so failing to record the event only gets logged
+	if err := server.store.SecurityEvents().Create(securityEvent); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		server.log(errWrapped)
+	}
+
+	server.error(writer, req, http.StatusForbidden, errRefreshTokenReused)
+}
+
+func (server *server) handleSecurityEvents() http.HandlerFunc {
+	type responseItem struct {
+		ID        uint64 `json:"id"`
+		Type      string `json:"type"`
+		SessionID string `json:"session_id"`
+		IP        string `json:"ip"`
+		UserAgent string `json:"user_agent"`
+		CreatedAt string `json:"created_at"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "SecurityEvents"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		limit := 50
+		if limitRaw := req.URL.Query().Get("limit"); limitRaw != "" {
+			limitParsed, err := strconv.Atoi(limitRaw)
+			if err != nil || limitParsed < 1 || limitParsed > 500 {
+				errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+				errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Limit = %s", limitRaw))
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+				return
+			}
+			limit = limitParsed
+		}
+
+		securityEvents, err := server.store.SecurityEvents().FindAllByUser(user, limit)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseData := []responseItem{}
+
+		for _, securityEvent := range securityEvents {
+			responseData = append(responseData, responseItem{
+				ID:        securityEvent.ID,
+				Type:      securityEvent.Type,
+				SessionID: securityEvent.SessionID,
+				IP:        securityEvent.IP,
+				UserAgent: securityEvent.UserAgent,
+				CreatedAt: securityEvent.CreatedAt.Format(responseDateTimeLayout),
+			})
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
@@ -0,0 +1,153 @@
+package apiserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+const unknownDeviceName = "Unknown device"
+
+// clientIP prefers the address set by reverse proxy, but only if the request came from trusted one,
+// otherwise any client could pretend to be someone else. Addresses of trusted proxies in the chain are skipped.
+func (server *server) clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if !server.isTrustedProxy(host) {
+		return host
+	}
+
+	forwardedFor := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		forwardedIP := strings.TrimSpace(forwardedFor[i])
+		if forwardedIP == "" {
+			continue
+		}
+
+		if !server.isTrustedProxy(forwardedIP) {
+			return forwardedIP
+		}
+
+		host = forwardedIP
+	}
+
+	return host
+}
+
+func (server *server) isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, trustedProxy := range server.trustedProxies {
+		if trustedProxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// startSession creates new session for the request's device and the first pair of tokens for it
+func (server *server) startSession(user *model.User, req *http.Request, deviceName string) (*tokenutils.TokenPairDetails, error) {
+	methodName := "startSession"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	if deviceName == "" {
+		deviceName = unknownDeviceName
+	}
+
+	session, err := server.tokens.CreateSession(user.ID, deviceName, server.clientIP(req), req.UserAgent())
+	if err != nil {
+		return nil, errors.Wrap(err, errWrapMessage)
+	}
+
+	tokenDetails, err := server.tokens.CreateTokens(user.ID, session.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, errWrapMessage)
+	}
+
+	return tokenDetails, nil
+}
+
+func (server *server) handleSessions() http.HandlerFunc {
+	type responseItem struct {
+		ID         string `json:"id"`
+		DeviceName string `json:"device_name"`
+		IP         string `json:"ip"`
+		UserAgent  string `json:"user_agent"`
+		CreatedAt  string `json:"created_at"`
+		LastUsedAt string `json:"last_used_at"`
+		IsCurrent  bool   `json:"is_current"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Sessions"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+		currentSessionID, _ := req.Context().Value(ctxKeySessionID).(string)
+
+		sessions, err := server.tokens.ListSessions(user.ID)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseData := []responseItem{}
+
+		for _, session := range sessions {
+			responseData = append(responseData, responseItem{
+				ID:         session.ID,
+				DeviceName: session.DeviceName,
+				IP:         session.IP,
+				UserAgent:  session.UserAgent,
+				CreatedAt:  session.CreatedAt.Format(responseDateTimeLayout),
+				LastUsedAt: session.LastUsedAt.Format(responseDateTimeLayout),
+				IsCurrent:  session.ID == currentSessionID,
+			})
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
+
+func (server *server) handleSessionsDelete() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "SessionsDelete"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+		sessionID := mux.Vars(req)["id"]
+
+		if err := server.tokens.DeleteSession(user.ID, sessionID); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrSessionNotFound:
+				server.error(writer, req, http.StatusNotFound, errWrapped)
+			default:
+				// Mostly TokenUtils.ErrInternal, probably something with Redis
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
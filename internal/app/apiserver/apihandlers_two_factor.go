@@ -0,0 +1,576 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/totp"
+)
+
+// Time the user has to enter the code after the password was accepted
+const loginChallengeTTL = 5 * time.Minute
+
+// twoFactorEnabled reports whether login of the user needs the second step
+func (server *server) twoFactorEnabled(user *model.User) (bool, error) {
+	userTOTP, err := server.store.UserTOTP().FindByUser(user)
+	if err != nil {
+		if errors.Cause(err) == store.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return userTOTP.Enabled, nil
+}
+
+// checkSecondFactor accepts either TOTP code or unused recovery code, both can be used only once
+func (server *server) checkSecondFactor(user *model.User, code string, recoveryCode string) (bool, error) {
+	now := time.Now()
+
+	if recoveryCode != "" {
+		if err := server.store.RecoveryCodes().Use(user, totp.HashRecoveryCode(recoveryCode), now); err != nil {
+			if errors.Cause(err) == store.ErrNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	userTOTP, err := server.store.UserTOTP().FindByUser(user)
+	if err != nil {
+		if errors.Cause(err) == store.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	step, ok, err := totp.Validate(userTOTP.Secret, code, now)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// Code of already used step has been seen, it may be intercepted
+	if err := server.store.UserTOTP().UseStep(user, step); err != nil {
+		if errors.Cause(err) == store.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// generateRecoveryCodes returns the codes to be shown once and their hashes to be saved
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codeHashes := make([]string, len(codes))
+	for i, code := range codes {
+		codeHashes[i] = totp.HashRecoveryCode(code)
+	}
+
+	return codes, codeHashes, nil
+}
+
+// newRecoveryCodes replaces recovery codes of the user, the codes are returned to be shown once
+func (server *server) newRecoveryCodes(user *model.User) ([]string, error) {
+	codes, codeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.store.RecoveryCodes().ReplaceAll(user, codeHashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// startLoginChallenge is the answer to correct password when second factor is needed
+func (server *server) startLoginChallenge(writer http.ResponseWriter, req *http.Request, user *model.User) {
+	methodName := "startLoginChallenge"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	challengeToken, err := server.tokens.CreateOneTimeToken(tokenutils.PurposeLoginChallenge, &tokenutils.OneTimeTokenDetails{
+		UserID: user.ID,
+		Email:  user.Email,
+	}, loginChallengeTTL)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		server.log(errWrapped)
+		server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+		return
+	}
+
+	server.respond(writer, req, http.StatusOK, map[string]interface{}{
+		"two_factor_required": true,
+		"challenge_token":     challengeToken,
+	})
+}
+
+// handleLoginTwoFactor is the second step of login, it exchanges challenge token and code for the token pair
+func (server *server) handleLoginTwoFactor() http.HandlerFunc {
+	type request struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code,omitempty"`
+		RecoveryCode   string `json:"recovery_code,omitempty"`
+		DeviceName     string `json:"device_name,omitempty"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "LoginTwoFactor"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		// Challenge is kept until the right code comes, so a typo doesn't require entering the password again
+		challengeDetails, err := server.tokens.PeekOneTimeToken(tokenutils.PurposeLoginChallenge, requestStruct.ChallengeToken)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrTokenExpiredOrDeleted, tokenutils.ErrTokenDamaged:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				// Mostly TokenUtils.ErrInternal, probably something with Redis
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		user, err := server.store.Users().Find(challengeDetails.UserID)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case store.ErrNotFound:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		if user.Banned {
+			errWrapped := errors.Wrap(errUserBanned, errWrapMessage)
+			server.error(writer, req, http.StatusForbidden, errWrapped)
+			return
+		}
+
+		// Wrong codes count towards the same lockout as wrong passwords
+		lockoutKey := "login:" + strings.ToLower(user.Username)
+
+		lockedFor, err := server.limiter.LockedFor(lockoutKey)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if lockedFor > 0 {
+			server.tooManyRequests(writer, req, lockedFor)
+			return
+		}
+
+		ok, err := server.checkSecondFactor(user, requestStruct.Code, requestStruct.RecoveryCode)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if !ok {
+			server.loginFailed(writer, req, lockoutKey, errWrongTwoFactorCodeMessage)
+			return
+		}
+
+		// Parallel request with the same challenge may have finished login already
+		if _, err := server.tokens.ConsumeOneTimeToken(tokenutils.PurposeLoginChallenge, requestStruct.ChallengeToken); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrTokenExpiredOrDeleted, tokenutils.ErrTokenDamaged:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		if err := server.limiter.Reset(lockoutKey); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+		}
+
+		tokenDetails, err := server.startSession(user, req, requestStruct.DeviceName)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		tokens := map[string]string{
+			"access_token":  tokenDetails.AccessToken,
+			"refresh_token": tokenDetails.RefreshToken,
+		}
+
+		server.respond(writer, req, http.StatusOK, tokens)
+	}
+}
+
+func (server *server) handleTwoFactor() http.HandlerFunc {
+	type response struct {
+		Enabled           bool `json:"enabled"`
+		RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "TwoFactor"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		enabled, err := server.twoFactorEnabled(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseStruct := response{
+			Enabled: enabled,
+		}
+
+		if enabled {
+			responseStruct.RecoveryCodesLeft, err = server.store.RecoveryCodes().CountUnused(user)
+			if err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+		}
+
+		server.respond(writer, req, http.StatusOK, responseStruct)
+	}
+}
+
+// handleTwoFactorSetup starts enrolment: new secret is saved disabled until the first code is confirmed
+func (server *server) handleTwoFactorSetup() http.HandlerFunc {
+	type request struct {
+		Password string `json:"password"`
+	}
+
+	type response struct {
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "TwoFactorSetup"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		// Users registered with external identity have no password to confirm with
+		if user.HasPassword() && !user.ComparePassword(requestStruct.Password) {
+			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongPasswordMessage})
+			return
+		}
+
+		enabled, err := server.twoFactorEnabled(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if enabled {
+			errWrapped := errors.Wrap(errTwoFactorEnabled, errWrapMessage)
+			server.error(writer, req, http.StatusConflict, errWrapped)
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userTOTP := &model.UserTOTP{
+			Secret:    secret,
+			CreatedAt: time.Now(),
+			User:      user,
+		}
+
+		if err := server.store.UserTOTP().Save(userTOTP); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, response{
+			Secret:          secret,
+			ProvisioningURI: totp.ProvisioningURI(secret, server.totpIssuer, user.Username),
+		})
+	}
+}
+
+// handleTwoFactorEnable finishes enrolment with the first code and returns recovery codes
+func (server *server) handleTwoFactorEnable() http.HandlerFunc {
+	type request struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "TwoFactorEnable"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		userTOTP, err := server.store.UserTOTP().FindByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case store.ErrNotFound:
+				server.error(writer, req, http.StatusBadRequest, errors.Wrap(errTwoFactorNotSetUp, errWrapMessage))
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		if userTOTP.Enabled {
+			errWrapped := errors.Wrap(errTwoFactorEnabled, errWrapMessage)
+			server.error(writer, req, http.StatusConflict, errWrapped)
+			return
+		}
+
+		ok, err := server.checkSecondFactor(user, requestStruct.Code, "")
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if !ok {
+			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongTwoFactorCodeMessage})
+			return
+		}
+
+		recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if err := server.store.UserTOTP().Enable(user, recoveryCodeHashes); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case store.ErrNotFound:
+				// Enabled by a concurrent request with another code
+				server.error(writer, req, http.StatusConflict, errors.Wrap(errTwoFactorEnabled, errWrapMessage))
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, response{
+			RecoveryCodes: recoveryCodes,
+		})
+	}
+}
+
+// handleTwoFactorRecoveryCodes replaces recovery codes, e.g. when they run out or were lost
+func (server *server) handleTwoFactorRecoveryCodes() http.HandlerFunc {
+	type request struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "TwoFactorRecoveryCodes"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		enabled, err := server.twoFactorEnabled(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if !enabled {
+			errWrapped := errors.Wrap(errTwoFactorNotSetUp, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		ok, err := server.checkSecondFactor(user, requestStruct.Code, "")
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if !ok {
+			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongTwoFactorCodeMessage})
+			return
+		}
+
+		recoveryCodes, err := server.newRecoveryCodes(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, response{
+			RecoveryCodes: recoveryCodes,
+		})
+	}
+}
+
+// handleTwoFactorDisable needs the second factor and the password, if the user has one
+func (server *server) handleTwoFactorDisable() http.HandlerFunc {
+	type request struct {
+		Password     string `json:"password"`
+		Code         string `json:"code,omitempty"`
+		RecoveryCode string `json:"recovery_code,omitempty"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "TwoFactorDisable"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		// Users registered with external identity have no password to confirm with
+		if user.HasPassword() && !user.ComparePassword(requestStruct.Password) {
+			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongPasswordMessage})
+			return
+		}
+
+		enabled, err := server.twoFactorEnabled(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if !enabled {
+			errWrapped := errors.Wrap(errTwoFactorNotSetUp, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		ok, err := server.checkSecondFactor(user, requestStruct.Code, requestStruct.RecoveryCode)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if !ok {
+			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongTwoFactorCodeMessage})
+			return
+		}
+
+		if err := server.store.UserTOTP().Delete(user); err != nil && errors.Cause(err) != store.ErrNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
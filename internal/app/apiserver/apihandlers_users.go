@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -88,14 +90,21 @@ func (server *server) handleRegistration() http.HandlerFunc {
 			return
 		}
 
+		// Account is already created, so the user can ask for another email if this one fails
+		if err := server.sendEmailVerification(user); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+		}
+
 		server.respond(writer, req, http.StatusOK, map[string]string{})
 	}
 }
 
 func (server *server) handleLogin() http.HandlerFunc {
 	type request struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name,omitempty"`
 	}
 
 	return func(writer http.ResponseWriter, req *http.Request) {
@@ -110,13 +119,29 @@ func (server *server) handleLogin() http.HandlerFunc {
 			return
 		}
 
+		// Locking by requested username, so unknown usernames are locked the same way
+		lockoutKey := "login:" + strings.ToLower(requestStruct.Username)
+
+		lockedFor, err := server.limiter.LockedFor(lockoutKey)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if lockedFor > 0 {
+			server.tooManyRequests(writer, req, lockedFor)
+			return
+		}
+
 		user, err := server.store.Users().FindBy("username", requestStruct.Username)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 
 			switch errors.Cause(err) {
 			case store.ErrNotFound:
-				server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongUsernameOrPasswordMessage})
+				server.loginFailed(writer, req, lockoutKey, errWrongUsernameOrPasswordMessage)
 			default:
 				server.log(errWrapped)
 				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
@@ -126,24 +151,24 @@ func (server *server) handleLogin() http.HandlerFunc {
 		}
 
 		if !user.ComparePassword(requestStruct.Password) {
-			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongUsernameOrPasswordMessage})
+			server.loginFailed(writer, req, lockoutKey, errWrongUsernameOrPasswordMessage)
 			return
 		}
 
-		tokenDetails, err := tokenutils.CreateTokens(user.ID)
-		if err != nil {
+		if err := server.limiter.Reset(lockoutKey); err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
-			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
-			return
 		}
 
-		tokens := map[string]string{
-			"access_token":  tokenDetails.AccessToken,
-			"refresh_token": tokenDetails.RefreshToken,
+		// Upgrading outdated hash while the password is known, login doesn't fail because of it
+		if user.PasswordNeedsRehash() {
+			if err := server.store.Users().UpdatePassword(requestStruct.Password, user.ID); err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+			}
 		}
 
-		server.respond(writer, req, http.StatusOK, tokens)
+		server.completeLogin(writer, req, user, requestStruct.DeviceName)
 	}
 }
 
@@ -170,7 +195,7 @@ func (server *server) handleLogout() http.HandlerFunc {
 			return
 		}
 
-		tokenDetails, err := tokenutils.ExtractTokenMetadata(tokenString)
+		tokenDetails, err := server.tokens.ExtractTokenMetadata(tokenString)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 
@@ -187,7 +212,20 @@ func (server *server) handleLogout() http.HandlerFunc {
 			return
 		}
 
-		if err := tokenutils.DeleteAuth(tokenDetails.Uuid); err != nil {
+		// Tokens issued before sessions don't have one
+		if tokenDetails.SessionID == "" {
+			if err := server.tokens.DeleteAuth(tokenDetails.Uuid); err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+
+			server.respond(writer, req, http.StatusOK, map[string]string{})
+			return
+		}
+
+		if err := server.tokens.DeleteSession(tokenDetails.UserId, tokenDetails.SessionID); err != nil && errors.Cause(err) != tokenutils.ErrSessionNotFound {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
 			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
@@ -235,7 +273,7 @@ func (server *server) handleRefreshToken() http.HandlerFunc {
 		}
 
 		// Access token must be valid, but expired
-		accessTokenDetails, err := tokenutils.ExtractTokenMetadata(tokenString)
+		accessTokenDetails, err := server.tokens.ExtractTokenMetadata(tokenString)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 
@@ -252,7 +290,7 @@ func (server *server) handleRefreshToken() http.HandlerFunc {
 				return
 			}
 		} else {
-			if err := tokenutils.DeleteAuth(accessTokenDetails.Uuid); err != nil {
+			if err := server.tokens.DeleteAuth(accessTokenDetails.Uuid); err != nil {
 				errWrapped := errors.Wrap(err, errWrapMessage)
 				server.log(errWrapped)
 				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
@@ -260,7 +298,7 @@ func (server *server) handleRefreshToken() http.HandlerFunc {
 			}
 		}
 
-		refreshTokenDetails, err := tokenutils.ExtractTokenMetadata(requestStruct.RefreshToken)
+		refreshTokenDetails, err := server.tokens.ExtractTokenMetadata(requestStruct.RefreshToken)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 
@@ -268,7 +306,7 @@ func (server *server) handleRefreshToken() http.HandlerFunc {
 			case tokenutils.ErrTokenDamaged:
 				server.error(writer, req, http.StatusBadRequest, errWrapped)
 			case tokenutils.ErrTokenExpiredOrDeleted:
-				server.error(writer, req, http.StatusForbidden, errWrapped)
+				server.handleRefreshTokenReuse(writer, req, requestStruct.RefreshToken, errWrapped)
 			default:
 				// Mostly TokenUtils.ErrInternal, probably something with Redis
 				server.log(errWrapped)
@@ -278,19 +316,32 @@ func (server *server) handleRefreshToken() http.HandlerFunc {
 			return
 		}
 
-		if err := tokenutils.DeleteAuth(refreshTokenDetails.Uuid); err != nil {
+		if err := server.tokens.DeleteAuth(refreshTokenDetails.Uuid); err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
 			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
 			return
 		}
 
-		// Creating new pair of tokens
-		tokenDetails, err := tokenutils.CreateTokens(refreshTokenDetails.UserId)
+		// Tokens issued before sessions can't be revoked by closing sessions, so they aren't renewed
+		if refreshTokenDetails.SessionID == "" {
+			errWrapped := errors.Wrap(errSessionRequired, errWrapMessage)
+			server.error(writer, req, http.StatusUnauthorized, errWrapped)
+			return
+		}
+
+		tokenDetails, err := server.tokens.CreateTokens(refreshTokenDetails.UserId, refreshTokenDetails.SessionID)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
-			server.log(errWrapped)
-			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrSessionNotFound:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
 			return
 		}
 
@@ -354,15 +405,15 @@ func (server *server) handleUsersChangeEmail() http.HandlerFunc {
 			return
 		}
 
-		if err := tokenutils.DeleteAllAuths(user.ID); err != nil {
+		if err := server.tokens.DeleteAllAuths(user.ID); err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
 			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
 			return
 		}
 
-		// Creating new pair of tokens
-		tokenDetails, err := tokenutils.CreateTokens(user.ID)
+		// All sessions were deleted, so the current device gets a new one
+		tokenDetails, err := server.startSession(user, req, "")
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
@@ -397,7 +448,8 @@ func (server *server) handleUsersChangePassword() http.HandlerFunc {
 
 		user := req.Context().Value(ctxKeyUser).(*model.User)
 
-		if !user.ComparePassword(requestStruct.CurrentPassword) {
+		// Users registered with external identity set their first password without current one
+		if user.HasPassword() && !user.ComparePassword(requestStruct.CurrentPassword) {
 			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongPasswordMessage})
 			return
 		}
@@ -416,15 +468,15 @@ func (server *server) handleUsersChangePassword() http.HandlerFunc {
 			return
 		}
 
-		if err := tokenutils.DeleteAllAuths(user.ID); err != nil {
+		if err := server.tokens.DeleteAllAuths(user.ID); err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
 			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
 			return
 		}
 
-		// Creating new pair of tokens
-		tokenDetails, err := tokenutils.CreateTokens(user.ID)
+		// All sessions were deleted, so the current device gets a new one
+		tokenDetails, err := server.startSession(user, req, "")
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			server.log(errWrapped)
@@ -441,3 +493,194 @@ func (server *server) handleUsersChangePassword() http.HandlerFunc {
 		return
 	}
 }
+
+// handleUsersDelete deletes the account. Favourites are deleted by the database cascade.
+func (server *server) handleUsersDelete() http.HandlerFunc {
+	type request struct {
+		Password string `json:"password"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "UserDelete"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		// Users registered with external identity have no password to confirm deletion with
+		if user.HasPassword() && !user.ComparePassword(requestStruct.Password) {
+			server.respond(writer, req, http.StatusOK, map[string]string{"error": errWrongPasswordMessage})
+			return
+		}
+
+		if err := server.store.Users().Delete(user.ID); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		if err := server.tokens.DeleteAllAuths(user.ID); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+// handleUsersExport returns all personal data, that is stored about the user
+func (server *server) handleUsersExport() http.HandlerFunc {
+	type responseProfile struct {
+		Username      string `json:"username"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	type responseFavourite struct {
+		ID   uint64 `json:"id"`
+		Name string `json:"name"`
+	}
+	type responseSecurityEvent struct {
+		Type      string `json:"type"`
+		IP        string `json:"ip"`
+		UserAgent string `json:"user_agent"`
+		CreatedAt string `json:"created_at"`
+	}
+	type response struct {
+		ExportedAt     string                      `json:"exported_at"`
+		Profile        responseProfile             `json:"profile"`
+		Favourites     []responseFavourite         `json:"favourites"`
+		SecurityEvents []responseSecurityEvent     `json:"security_events"`
+		Identities     []responseIdentity          `json:"identities"`
+		OwnedGames     []responseOwnedGame         `json:"owned_games"`
+		Wishlists      []responseWishlistWithItems `json:"wishlists"`
+		Preferences    responsePreferences         `json:"preferences"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "UserExport"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		games, err := server.store.Games().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		securityEvents, err := server.store.SecurityEvents().FindAllByUser(user, 10000)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userIdentities, err := server.store.UserIdentities().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userOwnedGames, err := server.store.UserOwnedGames().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		wishlists, err := server.store.Wishlists().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userPreferences, err := server.userPreferences(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseStruct := response{
+			ExportedAt: time.Now().Format(responseDateTimeLayout),
+			Profile: responseProfile{
+				Username:      user.Username,
+				Email:         user.Email,
+				EmailVerified: user.EmailVerified,
+			},
+			Favourites:     []responseFavourite{},
+			SecurityEvents: []responseSecurityEvent{},
+			Identities:     []responseIdentity{},
+			OwnedGames:     []responseOwnedGame{},
+			Wishlists:      []responseWishlistWithItems{},
+			Preferences:    newResponsePreferences(userPreferences),
+		}
+
+		for _, game := range games {
+			responseStruct.Favourites = append(responseStruct.Favourites, responseFavourite{
+				ID:   game.ID,
+				Name: game.Name,
+			})
+		}
+
+		for _, securityEvent := range securityEvents {
+			responseStruct.SecurityEvents = append(responseStruct.SecurityEvents, responseSecurityEvent{
+				Type:      securityEvent.Type,
+				IP:        securityEvent.IP,
+				UserAgent: securityEvent.UserAgent,
+				CreatedAt: securityEvent.CreatedAt.Format(responseDateTimeLayout),
+			})
+		}
+
+		for _, userIdentity := range userIdentities {
+			responseStruct.Identities = append(responseStruct.Identities, newResponseIdentity(userIdentity))
+		}
+
+		for _, userOwnedGame := range userOwnedGames {
+			responseStruct.OwnedGames = append(responseStruct.OwnedGames, newResponseOwnedGame(userOwnedGame))
+		}
+
+		for _, wishlist := range wishlists {
+			wishlistItems, err := server.store.WishlistItems().FindAllByWishlist(wishlist)
+			if err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+
+			responseWishlistStruct := responseWishlistWithItems{
+				responseWishlist: newResponseWishlist(wishlist),
+				Items:            []responseWishlistItem{},
+			}
+			for _, wishlistItem := range wishlistItems {
+				responseWishlistStruct.Items = append(responseWishlistStruct.Items, newResponseWishlistItem(wishlistItem))
+			}
+
+			responseStruct.Wishlists = append(responseStruct.Wishlists, responseWishlistStruct)
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Content-Disposition", "attachment; filename=\"price-hunter-export.json\"")
+		server.respond(writer, req, http.StatusOK, responseStruct)
+	}
+}
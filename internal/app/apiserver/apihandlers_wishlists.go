@@ -0,0 +1,497 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type responseWishlist struct {
+	ID         uint64  `json:"id"`
+	Name       string  `json:"name"`
+	Position   int     `json:"position"`
+	IsDefault  bool    `json:"is_default"`
+	IsPublic   bool    `json:"is_public"`
+	PublicSlug *string `json:"public_slug"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+func newResponseWishlist(wishlist *model.Wishlist) responseWishlist {
+	return responseWishlist{
+		ID:         wishlist.ID,
+		Name:       wishlist.Name,
+		Position:   wishlist.Position,
+		IsDefault:  wishlist.IsDefault,
+		IsPublic:   wishlist.IsPublic,
+		PublicSlug: wishlist.PublicSlug,
+		CreatedAt:  wishlist.CreatedAt.Format(responseDateTimeLayout),
+	}
+}
+
+type responseWishlistItem struct {
+	ID             uint64 `json:"id"`
+	GameID         uint64 `json:"game_id"`
+	HeaderImageURL string `json:"header_image"`
+	Name           string `json:"name"`
+	Priority       int    `json:"priority"`
+	Note           string `json:"note"`
+	TargetPrice    *int   `json:"target_price"`
+	CreatedAt      string `json:"created_at"`
+}
+
+func newResponseWishlistItem(wishlistItem *model.WishlistItem) responseWishlistItem {
+	return responseWishlistItem{
+		ID:             wishlistItem.ID,
+		GameID:         wishlistItem.Game.ID,
+		HeaderImageURL: wishlistItem.Game.HeaderImageURL,
+		Name:           wishlistItem.Game.Name,
+		Priority:       wishlistItem.Priority,
+		Note:           wishlistItem.Note,
+		TargetPrice:    wishlistItem.TargetPrice,
+		CreatedAt:      wishlistItem.CreatedAt.Format(responseDateTimeLayout),
+	}
+}
+
+type responseWishlistWithItems struct {
+	responseWishlist
+	Items []responseWishlistItem `json:"items"`
+}
+
+// findUserWishlist answers 404 itself, if the list doesn't exist or belongs to another user
+func (server *server) findUserWishlist(writer http.ResponseWriter, req *http.Request, errWrapMessage string) (*model.Wishlist, bool) {
+	id, err := parseIDVar(req)
+	if err != nil {
+		errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+		server.error(writer, req, http.StatusBadRequest, errWrapped)
+		return nil, false
+	}
+
+	user := req.Context().Value(ctxKeyUser).(*model.User)
+
+	wishlist, err := server.store.Wishlists().Find(id)
+	if err == nil && wishlist.User.ID != user.ID {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+		return nil, false
+	}
+
+	return wishlist, true
+}
+
+// findUserWishlistItem answers 404 itself, if the item isn't in the list from the path
+func (server *server) findUserWishlistItem(writer http.ResponseWriter, req *http.Request, errWrapMessage string) (*model.WishlistItem, bool) {
+	wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+	if !ok {
+		return nil, false
+	}
+
+	itemID, err := strconv.ParseUint(mux.Vars(req)["item_id"], 10, 64)
+	if err != nil {
+		errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+		server.error(writer, req, http.StatusBadRequest, errWrapped)
+		return nil, false
+	}
+
+	wishlistItem, err := server.store.WishlistItems().Find(itemID)
+	if err == nil && wishlistItem.Wishlist.ID != wishlist.ID {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+		return nil, false
+	}
+
+	return wishlistItem, true
+}
+
+// handleWishlists returns lists of the user in their order, the default list is created if it doesn't exist yet
+func (server *server) handleWishlists() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "Wishlists"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		if _, err := server.store.Wishlists().FindOrCreateDefault(user); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		wishlists, err := server.store.Wishlists().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseData := []responseWishlist{}
+		for _, wishlist := range wishlists {
+			responseData = append(responseData, newResponseWishlist(wishlist))
+		}
+
+		server.respond(writer, req, http.StatusOK, responseData)
+	}
+}
+
+func (server *server) handleWishlistsCreate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsCreate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		wishlist := &model.Wishlist{
+			Name:      requestStruct.Name,
+			CreatedAt: time.Now(),
+			User:      user,
+		}
+
+		if err := server.store.Wishlists().Create(wishlist); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusCreated, newResponseWishlist(wishlist))
+	}
+}
+
+func (server *server) handleWishlistsGetByID() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsGetByID"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		wishlistItems, err := server.store.WishlistItems().FindAllByWishlist(wishlist)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		responseStruct := responseWishlistWithItems{
+			responseWishlist: newResponseWishlist(wishlist),
+			Items:            []responseWishlistItem{},
+		}
+		for _, wishlistItem := range wishlistItems {
+			responseStruct.Items = append(responseStruct.Items, newResponseWishlistItem(wishlistItem))
+		}
+
+		server.respond(writer, req, http.StatusOK, responseStruct)
+	}
+}
+
+// handleWishlistsUpdate renames the list
+func (server *server) handleWishlistsUpdate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsUpdate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		wishlist.Name = requestStruct.Name
+		if err := server.store.Wishlists().Update(wishlist); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponseWishlist(wishlist))
+	}
+}
+
+// handleWishlistsReorder expects IDs of all lists of the user in the new order
+func (server *server) handleWishlistsReorder() http.HandlerFunc {
+	type request struct {
+		IDs []uint64 `json:"ids"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsReorder"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		user := req.Context().Value(ctxKeyUser).(*model.User)
+
+		wishlists, err := server.store.Wishlists().FindAllByUser(user)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			return
+		}
+
+		userWishlistIDs := map[uint64]bool{}
+		for _, wishlist := range wishlists {
+			userWishlistIDs[wishlist.ID] = true
+		}
+
+		// Every list must be mentioned exactly once
+		for _, id := range requestStruct.IDs {
+			if !userWishlistIDs[id] {
+				errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+				server.error(writer, req, http.StatusBadRequest, errWrapped)
+				return
+			}
+			delete(userWishlistIDs, id)
+		}
+		if len(userWishlistIDs) != 0 || len(requestStruct.IDs) != len(wishlists) {
+			errWrapped := errors.Wrap(errWrongRequestFormat, errWrapMessage)
+			server.error(writer, req, http.StatusBadRequest, errWrapped)
+			return
+		}
+
+		if err := server.store.Wishlists().Reorder(user, requestStruct.IDs); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+// handleWishlistsDelete deletes the list with its items, the default list can't be deleted
+func (server *server) handleWishlistsDelete() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsDelete"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		if wishlist.IsDefault {
+			server.error(writer, req, http.StatusConflict, errDefaultWishlist)
+			return
+		}
+
+		if err := server.store.Wishlists().Delete(wishlist.ID); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
+
+// handleWishlistsSharingUpdate publishes or hides the list, the slug is kept while the list is hidden
+func (server *server) handleWishlistsSharingUpdate() http.HandlerFunc {
+	type request struct {
+		IsPublic bool `json:"is_public"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsSharingUpdate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		wishlist.IsPublic = requestStruct.IsPublic
+		if wishlist.IsPublic && wishlist.PublicSlug == nil {
+			slug, err := newWishlistSlug()
+			if err != nil {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+				return
+			}
+			wishlist.PublicSlug = &slug
+		}
+
+		if err := server.store.Wishlists().UpdateSharing(wishlist); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponseWishlist(wishlist))
+	}
+}
+
+// handleWishlistsSharingRevoke hides the list and forgets its slug, so links shared before stop working
+func (server *server) handleWishlistsSharingRevoke() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistsSharingRevoke"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		wishlist.IsPublic = false
+		wishlist.PublicSlug = nil
+		if err := server.store.Wishlists().UpdateSharing(wishlist); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponseWishlist(wishlist))
+	}
+}
+
+func (server *server) handleWishlistItemsCreate() http.HandlerFunc {
+	type request struct {
+		GameID      uint64 `json:"game_id"`
+		Priority    int    `json:"priority"`
+		Note        string `json:"note"`
+		TargetPrice *int   `json:"target_price"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistItemsCreate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlist, ok := server.findUserWishlist(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		game, err := server.store.Games().Find(requestStruct.GameID)
+		if err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		wishlistItem := &model.WishlistItem{
+			Priority:    requestStruct.Priority,
+			Note:        requestStruct.Note,
+			TargetPrice: requestStruct.TargetPrice,
+			CreatedAt:   time.Now(),
+			Wishlist:    wishlist,
+			Game:        game,
+		}
+
+		if err := server.store.WishlistItems().Create(wishlistItem); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusCreated, newResponseWishlistItem(wishlistItem))
+	}
+}
+
+// handleWishlistItemsUpdate changes priority, note and target price of the item
+func (server *server) handleWishlistItemsUpdate() http.HandlerFunc {
+	type request struct {
+		Priority    int    `json:"priority"`
+		Note        string `json:"note"`
+		TargetPrice *int   `json:"target_price"`
+	}
+
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistItemsUpdate"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlistItem, ok := server.findUserWishlistItem(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		requestStruct := &request{}
+		if err := json.NewDecoder(req.Body).Decode(requestStruct); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			server.log(errWrapped)
+			server.error(writer, req, http.StatusBadRequest, errWrongRequestFormat)
+			return
+		}
+
+		wishlistItem.Priority = requestStruct.Priority
+		wishlistItem.Note = requestStruct.Note
+		wishlistItem.TargetPrice = requestStruct.TargetPrice
+		if err := server.store.WishlistItems().Update(wishlistItem); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, newResponseWishlistItem(wishlistItem))
+	}
+}
+
+func (server *server) handleWishlistItemsDelete() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		methodName := "WishlistItemsDelete"
+		errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+		wishlistItem, ok := server.findUserWishlistItem(writer, req, errWrapMessage)
+		if !ok {
+			return
+		}
+
+		if err := server.store.WishlistItems().Delete(wishlistItem.ID); err != nil {
+			server.storeError(writer, req, errors.Wrap(err, errWrapMessage))
+			return
+		}
+
+		server.respond(writer, req, http.StatusOK, map[string]string{})
+	}
+}
@@ -2,19 +2,36 @@ package apiserver
 
 import (
 	"fmt"
+	"net"
 	"net/http"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apistore"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/identity"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/mailer"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/passwords"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/pricestats"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/ratelimit"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/recommend"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/steamimport"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store/sqlstore"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	identityRequestTimeout    = 10 * time.Second
+	steamImportRequestTimeout = 30 * time.Second
+)
+
 // Migrate from wrapping errors to logging (Lexa vk)
 
 func Start(config *Config) error {
@@ -34,28 +51,163 @@ func Start(config *Config) error {
 		return err
 	}
 
-	os.Setenv("TOKEN_SECRET", config.TokenSecret)
+	startLogger.Info("Configuring password hashing")
+	passwordHasher, err := passwords.NewHasher(passwords.Config{
+		Algorithm:         config.PasswordHashAlgorithm,
+		BcryptCost:        config.PasswordBcryptCost,
+		Argon2Memory:      config.PasswordArgon2MemoryKB,
+		Argon2Iterations:  config.PasswordArgon2Iterations,
+		Argon2Parallelism: config.PasswordArgon2Parallelism,
+	})
+	if err != nil {
+		return err
+	}
+
+	model.SetPasswordHasher(passwordHasher)
+
+	startLogger.Info("Loading token signing keys")
+	signingKeys := []*tokenutils.SigningKey{}
+	for _, keyConfig := range config.TokenKeys {
+		signingKey, err := tokenutils.LoadSigningKey(keyConfig.ID, keyConfig.File, keyConfig.ActiveFrom, keyConfig.RetireAt)
+		if err != nil {
+			return err
+		}
+		signingKeys = append(signingKeys, signingKey)
+	}
+
+	if len(signingKeys) == 0 {
+		startLogger.Warn("TOKEN_KEYS aren't set, tokens will be signed with a temporary key and won't survive restart")
+		signingKey, err := tokenutils.NewEphemeralSigningKey("ephemeral-" + time.Now().Format("20060102150405"))
+		if err != nil {
+			return err
+		}
+		signingKeys = append(signingKeys, signingKey)
+	}
 
 	startLogger.Info("Configuring Redis")
-	if err := tokenutils.SetupRedis(config.RedisAddr); err != nil {
+	redisClient, err := tokenutils.NewRedisClient(config.RedisAddr)
+	if err != nil {
 		return err
 	}
 
+	defer redisClient.Close()
+
+	// TokenSecret is only used to verify tokens issued before signing keys
+	tokens, err := tokenutils.NewService(tokenutils.NewRedisTokenStore(redisClient), signingKeys, config.TokenSecret)
+	if err != nil {
+		return err
+	}
+
+	bus := eventbus.New()
+	pricestats.New(store.PriceStats()).Subscribe(bus)
+
 	startLogger.Info("Updating games info")
-	if err := updateGames(*config, store); err != nil {
+	if err := updateGames(*config, store, bus, startLogger); err != nil {
+		return err
+	}
+
+	var mailSender mailer.Sender
+	if config.SMTPHost != "" {
+		mailSender = mailer.NewSMTPSender(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	} else {
+		startLogger.Warn("SMTP_HOST isn't set, emails won't be delivered")
+		mailSender = mailer.NewFakeSender()
+	}
+
+	limiter := ratelimit.New(redisClient)
+
+	steamImporter := steamimport.NewImporter(steamimport.NewClient(steamimport.Config{
+		APIURL: config.SteamImportAPIURL,
+		APIKey: config.SteamAPIKey,
+	}, &http.Client{Timeout: steamImportRequestTimeout}), store)
+
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
 		return err
 	}
 
-	srv := newServer(store)
+	srv := newServer(store, tokens, mailSender, limiter, newRateLimits(*config), config.AppBaseURL, config.TOTPIssuer, newIdentityRegistry(*config, tokens), steamImporter, trustedProxies)
 	startLogger.Info("Server started")
 
 	return http.ListenAndServe(config.BindAddr, srv)
 }
 
-func updateGames(config Config, st store.Store) error {
-	apiSteam := *apistore.NewAPISteam(config.SteamAPIKey, st)
-	apiEpicGames := *apistore.NewAPIEpicGames(st)
-	apiGOG := *apistore.NewAPIGOG(st)
+// parseTrustedProxies accepts both single addresses and networks in CIDR notation
+func parseTrustedProxies(addresses []string) ([]*net.IPNet, error) {
+	trustedProxies := []*net.IPNet{}
+
+	for _, address := range addresses {
+		if !strings.Contains(address, "/") {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				return nil, fmt.Errorf("Trusted proxy address %s is invalid", address)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			trustedProxies = append(trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Trusted proxy network %s is invalid", address))
+		}
+
+		trustedProxies = append(trustedProxies, network)
+	}
+
+	return trustedProxies, nil
+}
+
+// newIdentityRegistry configures external providers users can log in with, tokens remember nonces of their answers
+func newIdentityRegistry(config Config, tokens *tokenutils.Service) *identity.Registry {
+	client := &http.Client{Timeout: identityRequestTimeout}
+
+	providers := []identity.Provider{}
+	for _, providerConfig := range config.OIDCProviders {
+		providers = append(providers, identity.NewOIDCProvider(identity.OIDCConfig{
+			Name:         providerConfig.Name,
+			IssuerURL:    providerConfig.IssuerURL,
+			ClientID:     providerConfig.ClientID,
+			ClientSecret: providerConfig.ClientSecret,
+			Scopes:       providerConfig.Scopes,
+		}, client))
+	}
+
+	if config.SteamOpenIDEnabled {
+		providers = append(providers, identity.NewSteamProvider(identity.SteamConfig{
+			Endpoint: config.SteamOpenIDEndpoint,
+			Realm:    config.AppBaseURL,
+		}, client, tokens))
+	}
+
+	return identity.NewRegistry(providers...)
+}
+
+func updateGames(config Config, st store.Store, bus *eventbus.Bus, logger *logrus.Logger) error {
+	apiSteam := *apistore.NewAPISteam(config.SteamAPIKey, st, bus, apistore.FetchConfig{
+		Concurrency:     config.SteamConcurrency,
+		RequestInterval: time.Duration(config.SteamRequestIntervalMS) * time.Millisecond,
+		BatchSize:       config.SyncBatchSize,
+		MissesToDelist:  config.DelistingMissedSyncs,
+	})
+	apiEpicGames := *apistore.NewAPIEpicGames(st, bus, apistore.FetchConfig{
+		Concurrency:     config.EpicGamesConcurrency,
+		RequestInterval: time.Duration(config.EpicGamesRequestIntervalMS) * time.Millisecond,
+		BatchSize:       config.SyncBatchSize,
+		MissesToDelist:  config.DelistingMissedSyncs,
+	})
+	apiGOG := *apistore.NewAPIGOG(st, bus, apistore.FetchConfig{
+		Concurrency:     config.GOGConcurrency,
+		RequestInterval: time.Duration(config.GOGRequestIntervalMS) * time.Millisecond,
+		BatchSize:       config.SyncBatchSize,
+		MissesToDelist:  config.DelistingMissedSyncs,
+	})
 
 	if err := apiSteam.GetGames(); err != nil {
 		return err
@@ -80,10 +232,24 @@ func updateGames(config Config, st store.Store) error {
 		return err
 	}
 
+	// Giveaways are fetched after the catalogue sync, so they can be matched with the games.
+	// They are secondary, so the server starts even if the stores don't answer.
+	giveawaysProviders := []apistore.GiveawaysProvider{&apiEpicGames, &apiGOG}
+	for _, provider := range giveawaysProviders {
+		if err := provider.GetGiveaways(); err != nil {
+			logger.Warn(err)
+		}
+	}
+
 	// if err := apiSteam.UpdateGameMarketPrices(); err != nil {
 	// 	return err
 	// }
 
+	// Tags and the catalogue itself may have changed, so similar games are found anew
+	if err := recommend.RefreshNeighbours(st); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1,16 +1,71 @@
 package apiserver
 
+import "time"
+
+// TokenKeyConfig is a private key file tokens are signed with, see tokenutils.SigningKey
+type TokenKeyConfig struct {
+	ID         string    `toml:"ID"`
+	File       string    `toml:"FILE"`
+	ActiveFrom time.Time `toml:"ACTIVE_FROM"`
+	RetireAt   time.Time `toml:"RETIRE_AT"`
+}
+
+// OIDCProviderConfig is an OpenID Connect provider users can log in with, see identity.OIDCConfig
+type OIDCProviderConfig struct {
+	Name         string   `toml:"NAME"`
+	IssuerURL    string   `toml:"ISSUER_URL"`
+	ClientID     string   `toml:"CLIENT_ID"`
+	ClientSecret string   `toml:"CLIENT_SECRET"`
+	Scopes       []string `toml:"SCOPES"`
+}
+
 type Config struct {
-	BindAddr         string `toml:"BIND_ADDR"`
-	LogLevel         string `toml:"LOG_LEVEL"`
-	DatabaseHost     string `toml:"DATABASE_HOST"`
-	DatabaseDBName   string `toml:"DATABASE_DB"`
-	DatabaseUser     string `toml:"DATABASE_USER"`
-	DatabasePassword string `toml:"DATABASE_PASSWORD"`
-	DatabaseSSLMode  string `toml:"DATABASE_SSLMODE"`
-	RedisAddr        string `toml:"REDIS_ADDR"`
-	TokenSecret      string `toml:"TOKEN_SECRET"`
-	SteamAPIKey      string `toml:"STEAM_API_KEY"`
+	BindAddr                   string               `toml:"BIND_ADDR"`
+	LogLevel                   string               `toml:"LOG_LEVEL"`
+	DatabaseHost               string               `toml:"DATABASE_HOST"`
+	DatabaseDBName             string               `toml:"DATABASE_DB"`
+	DatabaseUser               string               `toml:"DATABASE_USER"`
+	DatabasePassword           string               `toml:"DATABASE_PASSWORD"`
+	DatabaseSSLMode            string               `toml:"DATABASE_SSLMODE"`
+	RedisAddr                  string               `toml:"REDIS_ADDR"`
+	TokenSecret                string               `toml:"TOKEN_SECRET"`
+	TokenKeys                  []TokenKeyConfig     `toml:"TOKEN_KEYS"`
+	SteamAPIKey                string               `toml:"STEAM_API_KEY"`
+	SteamConcurrency           int                  `toml:"STEAM_CONCURRENCY"`
+	SteamRequestIntervalMS     int                  `toml:"STEAM_REQUEST_INTERVAL_MS"`
+	EpicGamesConcurrency       int                  `toml:"EPIC_GAMES_CONCURRENCY"`
+	EpicGamesRequestIntervalMS int                  `toml:"EPIC_GAMES_REQUEST_INTERVAL_MS"`
+	GOGConcurrency             int                  `toml:"GOG_CONCURRENCY"`
+	GOGRequestIntervalMS       int                  `toml:"GOG_REQUEST_INTERVAL_MS"`
+	SyncBatchSize              int                  `toml:"SYNC_BATCH_SIZE"`
+	DelistingMissedSyncs       int                  `toml:"DELISTING_MISSED_SYNCS"`
+	AppBaseURL                 string               `toml:"APP_BASE_URL"`
+	SMTPHost                   string               `toml:"SMTP_HOST"`
+	SMTPPort                   int                  `toml:"SMTP_PORT"`
+	SMTPUsername               string               `toml:"SMTP_USERNAME"`
+	SMTPPassword               string               `toml:"SMTP_PASSWORD"`
+	MailFrom                   string               `toml:"MAIL_FROM"`
+	RateLimitAuthRequests      int                  `toml:"RATE_LIMIT_AUTH_REQUESTS"`
+	RateLimitAuthWindowSec     int                  `toml:"RATE_LIMIT_AUTH_WINDOW_SEC"`
+	RateLimitPrivateRequests   int                  `toml:"RATE_LIMIT_PRIVATE_REQUESTS"`
+	RateLimitPrivateWindowSec  int                  `toml:"RATE_LIMIT_PRIVATE_WINDOW_SEC"`
+	RateLimitPublicRequests    int                  `toml:"RATE_LIMIT_PUBLIC_REQUESTS"`
+	RateLimitPublicWindowSec   int                  `toml:"RATE_LIMIT_PUBLIC_WINDOW_SEC"`
+	TrustedProxies             []string             `toml:"TRUSTED_PROXIES"`
+	LoginLockoutThreshold      int                  `toml:"LOGIN_LOCKOUT_THRESHOLD"`
+	LoginLockoutBaseSec        int                  `toml:"LOGIN_LOCKOUT_BASE_SEC"`
+	LoginLockoutMaxSec         int                  `toml:"LOGIN_LOCKOUT_MAX_SEC"`
+	LoginLockoutResetSec       int                  `toml:"LOGIN_LOCKOUT_RESET_SEC"`
+	PasswordHashAlgorithm      string               `toml:"PASSWORD_HASH_ALGORITHM"`
+	PasswordBcryptCost         int                  `toml:"PASSWORD_BCRYPT_COST"`
+	PasswordArgon2MemoryKB     uint32               `toml:"PASSWORD_ARGON2_MEMORY_KB"`
+	PasswordArgon2Iterations   uint32               `toml:"PASSWORD_ARGON2_ITERATIONS"`
+	PasswordArgon2Parallelism  uint8                `toml:"PASSWORD_ARGON2_PARALLELISM"`
+	TOTPIssuer                 string               `toml:"TOTP_ISSUER"`
+	SteamOpenIDEnabled         bool                 `toml:"STEAM_OPENID_ENABLED"`
+	SteamOpenIDEndpoint        string               `toml:"STEAM_OPENID_ENDPOINT"`
+	SteamImportAPIURL          string               `toml:"STEAM_IMPORT_API_URL"`
+	OIDCProviders              []OIDCProviderConfig `toml:"OIDC_PROVIDERS"`
 }
 
 func NewConfig() *Config {
@@ -25,5 +80,36 @@ func NewConfig() *Config {
 		// RedisAddr: "",
 		// TokenSecret: "",
 		// SteamAPIKey: "",
+		SteamConcurrency:           4,
+		SteamRequestIntervalMS:     1500,
+		EpicGamesConcurrency:       4,
+		EpicGamesRequestIntervalMS: 250,
+		GOGConcurrency:             4,
+		GOGRequestIntervalMS:       250,
+		SyncBatchSize:              50,
+		DelistingMissedSyncs:       3,
+		AppBaseURL:                 "http://localhost:3000",
+		// SMTPHost: "",
+		SMTPPort: 587,
+		// SMTPUsername: "",
+		// SMTPPassword: "",
+		// MailFrom: "",
+		RateLimitAuthRequests:     20,
+		RateLimitAuthWindowSec:    60,
+		RateLimitPrivateRequests:  300,
+		RateLimitPrivateWindowSec: 60,
+		RateLimitPublicRequests:   60,
+		RateLimitPublicWindowSec:  60,
+		LoginLockoutThreshold:     5,
+		LoginLockoutBaseSec:       30,
+		LoginLockoutMaxSec:        3600,
+		LoginLockoutResetSec:      86400,
+		PasswordHashAlgorithm:     "argon2id",
+		PasswordBcryptCost:        12,
+		PasswordArgon2MemoryKB:    64 * 1024,
+		PasswordArgon2Iterations:  3,
+		PasswordArgon2Parallelism: 2,
+		TOTPIssuer:                "Price Hunter",
+		SteamImportAPIURL:         "https://api.steampowered.com",
 	}
 }
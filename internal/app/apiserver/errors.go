@@ -3,8 +3,24 @@ package apiserver
 import "github.com/pkg/errors"
 
 var (
-	errWrongRequestFormat = errors.New("Wrong request format")
-	errSomethingWentWrong = errors.New("Oops, something went wrong")
+	errWrongRequestFormat     = errors.New("Wrong request format")
+	errSomethingWentWrong     = errors.New("Oops, something went wrong")
+	errRefreshTokenReused     = errors.New("Refresh token has already been used, the session has been closed")
+	errSessionRequired        = errors.New("Token was issued before sessions, log in again")
+	errTooManyRequests        = errors.New("Too many requests, try again later")
+	errUserBanned             = errors.New("User has been banned")
+	errPermissionDenied       = errors.New("Permission denied")
+	errChangeOwnAccount       = errors.New("Can't change role or ban of your own account")
+	errTwoFactorEnabled       = errors.New("Two-factor authentication is already enabled")
+	errTwoFactorNotSetUp      = errors.New("Two-factor authentication isn't set up")
+	errIdentityLinkedToOther  = errors.New("This external account is linked to another user")
+	errIdentityProviderLinked = errors.New("Another account of this provider is already linked")
+	errLastLoginMethod        = errors.New("Can't unlink the only way to log in, set a password first")
+	errSteamNotLinked         = errors.New("Steam account isn't linked")
+	errSteamUnavailable       = errors.New("Couldn't get library from Steam, try again later")
+	errDefaultWishlist        = errors.New("Default list can't be deleted")
+	errBuiltInMarket          = errors.New("Built-in market can't be renamed or deleted")
+	errPublisherHasGames      = errors.New("Publisher still has games, delete or move them first")
 )
 
 const (
@@ -14,4 +30,5 @@ const (
 	errUserExistsEmailMessage         = "User with this email already exists"
 	errWrongUsernameOrPasswordMessage = "Wrong username or password"
 	errWrongPasswordMessage           = "Wrong password"
+	errWrongTwoFactorCodeMessage      = "Wrong or already used code"
 )
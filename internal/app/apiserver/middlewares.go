@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
 
 	"github.com/google/uuid"
@@ -76,7 +78,7 @@ func (server *server) authenticateUser(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenDetails, err := tokenutils.ExtractTokenMetadata(tokenString)
+		tokenDetails, err := server.tokens.ExtractTokenMetadata(tokenString)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 
@@ -93,7 +95,7 @@ func (server *server) authenticateUser(next http.Handler) http.Handler {
 			return
 		}
 
-		userId, err := tokenutils.FetchAuth(tokenDetails)
+		userId, err := server.tokens.FetchAuth(tokenDetails)
 		if err != nil {
 			errWrapped := errors.Wrap(err, errWrapMessage)
 
@@ -118,11 +120,62 @@ func (server *server) authenticateUser(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(writer, req.WithContext(context.WithValue(req.Context(), ctxKeyUser, user)))
+		if user.Banned {
+			errWrapped := errors.Wrap(errUserBanned, errWrapMessage)
+			server.error(writer, req, http.StatusForbidden, errWrapped)
+			return
+		}
+
+		// Tokens issued before sessions can't be revoked by closing sessions
+		if tokenDetails.SessionID == "" {
+			errWrapped := errors.Wrap(errSessionRequired, errWrapMessage)
+			server.error(writer, req, http.StatusUnauthorized, errWrapped)
+			return
+		}
+
+		if err := server.tokens.TouchSession(tokenDetails.SessionID, time.Now()); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+
+			switch errors.Cause(err) {
+			case tokenutils.ErrSessionNotFound:
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+			default:
+				server.log(errWrapped)
+				server.error(writer, req, http.StatusInternalServerError, errSomethingWentWrong)
+			}
+
+			return
+		}
+
+		ctx := context.WithValue(req.Context(), ctxKeyUser, user)
+		ctx = context.WithValue(ctx, ctxKeySessionID, tokenDetails.SessionID)
+
+		next.ServeHTTP(writer, req.WithContext(ctx))
 
 	})
 }
 
+// requirePermission must be used after authenticateUser
+func (server *server) requirePermission(permission model.Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			methodName := "RequirePermission"
+			errWrapMessage := fmt.Sprintf(errMiddlewareMessageFormat, methodName)
+
+			user := req.Context().Value(ctxKeyUser).(*model.User)
+
+			if !user.HasPermission(permission) {
+				errWrapped := errors.Wrap(errPermissionDenied, errWrapMessage)
+				errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Permission = %s", permission))
+				server.error(writer, req, http.StatusForbidden, errWrapped)
+				return
+			}
+
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
 type stackTracer interface {
 	StackTrace() errors.StackTrace
 }
@@ -0,0 +1,64 @@
+package apiserver
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/ratelimit"
+)
+
+// limitRequests limits requests to the route group from one IP
+func (server *server) limitRequests(group string, rule ratelimit.Rule) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			methodName := "LimitRequests"
+			errWrapMessage := fmt.Sprintf(errMiddlewareMessageFormat, methodName)
+
+			retryAfter, err := server.limiter.Allow(group+":"+server.clientIP(req), rule)
+			if err != nil {
+				// Redis being unavailable shouldn't take the whole API down
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				server.log(errWrapped)
+				next.ServeHTTP(writer, req)
+				return
+			}
+
+			if retryAfter > 0 {
+				server.tooManyRequests(writer, req, retryAfter)
+				return
+			}
+
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
+// loginFailed answers failed login attempt with the message and locks the account after too many of them
+func (server *server) loginFailed(writer http.ResponseWriter, req *http.Request, lockoutKey string, message string) {
+	methodName := "LoginFailed"
+	errWrapMessage := fmt.Sprintf(errHandlerMessageFormat, methodName)
+
+	lockedFor, err := server.limiter.RegisterFailure(lockoutKey, server.rateLimits.loginLockout)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		server.log(errWrapped)
+	}
+
+	if lockedFor > 0 {
+		server.tooManyRequests(writer, req, lockedFor)
+		return
+	}
+
+	server.respond(writer, req, http.StatusOK, map[string]string{"error": message})
+}
+
+func (server *server) tooManyRequests(writer http.ResponseWriter, req *http.Request, retryAfter time.Duration) {
+	writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	server.error(writer, req, http.StatusTooManyRequests, errTooManyRequests)
+}
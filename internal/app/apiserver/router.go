@@ -1,26 +1,134 @@
 package apiserver
 
-import "github.com/gorilla/handlers"
+import (
+	"github.com/gorilla/handlers"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
 
 func (server *server) configureRouter() {
 	server.router.Use(server.setRequestID)
 	server.router.Use(server.logRequest)
-	server.router.Use(handlers.CORS(handlers.AllowedOrigins([]string{"*"})))
-	server.router.HandleFunc("/registration", server.handleRegistration()).Methods("POST")
-	server.router.HandleFunc("/login", server.handleLogin()).Methods("POST")
-	server.router.HandleFunc("/logout", server.handleLogout()).Methods("POST")
-	server.router.HandleFunc("/token/refresh", server.handleRefreshToken()).Methods("POST")
+	server.router.Use(handlers.CORS(
+		handlers.AllowedOrigins([]string{"*"}),
+		handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}),
+	))
+
+	server.router.HandleFunc("/.well-known/jwks.json", server.handleJWKS()).Methods("GET")
+
+	auth := server.router.NewRoute().Subrouter()
+	auth.Use(server.limitRequests("auth", server.rateLimits.auth))
+	auth.HandleFunc("/registration", server.handleRegistration()).Methods("POST")
+	auth.HandleFunc("/login", server.handleLogin()).Methods("POST")
+	auth.HandleFunc("/login/2fa", server.handleLoginTwoFactor()).Methods("POST")
+	auth.HandleFunc("/logout", server.handleLogout()).Methods("POST")
+	auth.HandleFunc("/token/refresh", server.handleRefreshToken()).Methods("POST")
+	auth.HandleFunc("/email/verify/confirm", server.handleEmailVerificationConfirm()).Methods("POST")
+	auth.HandleFunc("/password/reset", server.handlePasswordResetRequest()).Methods("POST")
+	auth.HandleFunc("/password/reset/confirm", server.handlePasswordResetConfirm()).Methods("POST")
+	auth.HandleFunc("/auth/external/register", server.handleExternalRegistration()).Methods("POST")
+	auth.HandleFunc("/auth/{provider}", server.handleExternalAuth()).Methods("GET")
+	auth.HandleFunc("/auth/{provider}/callback", server.handleExternalCallback()).Methods("POST")
+
+	public := server.router.PathPrefix("/public").Subrouter()
+	public.Use(server.limitRequests("public", server.rateLimits.public))
+	public.HandleFunc("/lists/{slug}", server.handlePublicLists()).Methods("GET")
 
 	private := server.router.PathPrefix("/private").Subrouter()
+	private.Use(server.limitRequests("private", server.rateLimits.private))
 	private.Use(server.authenticateUser)
 	private.HandleFunc("/me", server.handleUsersMe()).Methods("GET")
+	private.HandleFunc("/me", server.handleUsersDelete()).Methods("DELETE")
+	private.HandleFunc("/me/export", server.handleUsersExport()).Methods("GET")
+	private.HandleFunc("/me/preferences", server.handlePreferences()).Methods("GET")
+	private.HandleFunc("/me/preferences", server.handlePreferencesUpdate()).Methods("PATCH")
+	private.HandleFunc("/sessions", server.handleSessions()).Methods("GET")
+	private.HandleFunc("/security-events", server.handleSecurityEvents()).Methods("GET")
+	private.HandleFunc("/sessions/{id}", server.handleSessionsDelete()).Methods("DELETE")
+	private.HandleFunc("/email/verify", server.handleEmailVerificationRequest()).Methods("POST")
 	private.HandleFunc("/change/email", server.handleUsersChangeEmail()).Methods("POST")
 	private.HandleFunc("/change/password", server.handleUsersChangePassword()).Methods("POST")
+	private.HandleFunc("/2fa", server.handleTwoFactor()).Methods("GET")
+	private.HandleFunc("/2fa", server.handleTwoFactorDisable()).Methods("DELETE")
+	private.HandleFunc("/2fa/totp/setup", server.handleTwoFactorSetup()).Methods("POST")
+	private.HandleFunc("/2fa/totp/enable", server.handleTwoFactorEnable()).Methods("POST")
+	private.HandleFunc("/2fa/recovery-codes", server.handleTwoFactorRecoveryCodes()).Methods("POST")
+	private.HandleFunc("/identities", server.handleIdentities()).Methods("GET")
+	private.HandleFunc("/identities/{provider}/link", server.handleIdentitiesLink()).Methods("GET")
+	private.HandleFunc("/identities/{provider}/callback", server.handleIdentitiesLinkCallback()).Methods("POST")
+	private.HandleFunc("/identities/{provider}", server.handleIdentitiesUnlink()).Methods("DELETE")
+	private.HandleFunc("/import/steam", server.handleImportSteam()).Methods("POST")
 
 	private.HandleFunc("/games", server.handleGames()).Methods("POST")
 	private.HandleFunc("/games/{id:[0-9]+}", server.handleGamesGetByID()).Methods("GET")
+	private.HandleFunc("/games/{id:[0-9]+}/similar", server.handleGamesSimilar()).Methods("GET")
+
+	private.HandleFunc("/deals", server.handleDeals()).Methods("GET")
+	private.HandleFunc("/giveaways", server.handleGiveaways()).Methods("GET")
+	private.HandleFunc("/recommendations", server.handleRecommendations()).Methods("GET")
+
+	private.HandleFunc("/owned", server.handleOwnedGames()).Methods("GET")
+	private.HandleFunc("/owned", server.handleOwnedGamesCreate()).Methods("POST")
+	private.HandleFunc("/owned/{id:[0-9]+}", server.handleOwnedGamesUpdate()).Methods("PUT")
+	private.HandleFunc("/owned/{id:[0-9]+}", server.handleOwnedGamesDelete()).Methods("DELETE")
+
+	private.HandleFunc("/wishlists", server.handleWishlists()).Methods("GET")
+	private.HandleFunc("/wishlists", server.handleWishlistsCreate()).Methods("POST")
+	private.HandleFunc("/wishlists/order", server.handleWishlistsReorder()).Methods("PUT")
+	private.HandleFunc("/wishlists/{id:[0-9]+}", server.handleWishlistsGetByID()).Methods("GET")
+	private.HandleFunc("/wishlists/{id:[0-9]+}", server.handleWishlistsUpdate()).Methods("PUT")
+	private.HandleFunc("/wishlists/{id:[0-9]+}", server.handleWishlistsDelete()).Methods("DELETE")
+	private.HandleFunc("/wishlists/{id:[0-9]+}/sharing", server.handleWishlistsSharingUpdate()).Methods("PUT")
+	private.HandleFunc("/wishlists/{id:[0-9]+}/sharing", server.handleWishlistsSharingRevoke()).Methods("DELETE")
+	private.HandleFunc("/wishlists/{id:[0-9]+}/items", server.handleWishlistItemsCreate()).Methods("POST")
+	private.HandleFunc("/wishlists/{id:[0-9]+}/items/{item_id:[0-9]+}", server.handleWishlistItemsUpdate()).Methods("PUT")
+	private.HandleFunc("/wishlists/{id:[0-9]+}/items/{item_id:[0-9]+}", server.handleWishlistItemsDelete()).Methods("DELETE")
 
 	private.HandleFunc("/favourites", server.handleFavourites()).Methods("GET")
 	private.HandleFunc("/favourites/add", server.handleFavouritesAdd()).Methods("POST")
 	private.HandleFunc("/favourites/remove", server.handleFavouritesRemove()).Methods("POST")
+
+	admin := server.router.PathPrefix("/admin").Subrouter()
+	admin.Use(server.limitRequests("private", server.rateLimits.private))
+	admin.Use(server.authenticateUser)
+
+	catalogue := admin.NewRoute().Subrouter()
+	catalogue.Use(server.requirePermission(model.PermissionManageCatalogue))
+	catalogue.HandleFunc("/games", server.handleAdminGames()).Methods("GET")
+	catalogue.HandleFunc("/games/{id:[0-9]+}", server.handleAdminGamesGetByID()).Methods("GET")
+	catalogue.HandleFunc("/games", server.handleAdminGamesCreate()).Methods("POST")
+	catalogue.HandleFunc("/games/{id:[0-9]+}", server.handleAdminGamesUpdate()).Methods("PUT")
+	catalogue.HandleFunc("/games/{id:[0-9]+}", server.handleAdminGamesDelete()).Methods("DELETE")
+	catalogue.HandleFunc("/publishers", server.handleAdminPublishers()).Methods("GET")
+	catalogue.HandleFunc("/publishers/{id:[0-9]+}", server.handleAdminPublishersGetByID()).Methods("GET")
+	catalogue.HandleFunc("/publishers", server.handleAdminPublishersCreate()).Methods("POST")
+	catalogue.HandleFunc("/publishers/{id:[0-9]+}", server.handleAdminPublishersUpdate()).Methods("PUT")
+	catalogue.HandleFunc("/publishers/{id:[0-9]+}", server.handleAdminPublishersDelete()).Methods("DELETE")
+	catalogue.HandleFunc("/tags", server.handleAdminTags()).Methods("GET")
+	catalogue.HandleFunc("/tags/{id:[0-9]+}", server.handleAdminTagsGetByID()).Methods("GET")
+	catalogue.HandleFunc("/tags", server.handleAdminTagsCreate()).Methods("POST")
+	catalogue.HandleFunc("/tags/{id:[0-9]+}", server.handleAdminTagsUpdate()).Methods("PUT")
+	catalogue.HandleFunc("/tags/{id:[0-9]+}", server.handleAdminTagsDelete()).Methods("DELETE")
+
+	blacklist := admin.NewRoute().Subrouter()
+	blacklist.Use(server.requirePermission(model.PermissionManageBlacklist))
+	blacklist.HandleFunc("/blacklist", server.handleAdminBlacklist()).Methods("GET")
+	blacklist.HandleFunc("/blacklist", server.handleAdminBlacklistCreate()).Methods("POST")
+	blacklist.HandleFunc("/blacklist/{id:[0-9]+}", server.handleAdminBlacklistDelete()).Methods("DELETE")
+
+	markets := admin.NewRoute().Subrouter()
+	markets.Use(server.requirePermission(model.PermissionManageMarkets))
+	markets.HandleFunc("/markets", server.handleAdminMarkets()).Methods("GET")
+	markets.HandleFunc("/markets/{id:[0-9]+}", server.handleAdminMarketsGetByID()).Methods("GET")
+	markets.HandleFunc("/markets", server.handleAdminMarketsCreate()).Methods("POST")
+	markets.HandleFunc("/markets/{id:[0-9]+}", server.handleAdminMarketsUpdate()).Methods("PUT")
+	markets.HandleFunc("/markets/{id:[0-9]+}", server.handleAdminMarketsDelete()).Methods("DELETE")
+
+	users := admin.NewRoute().Subrouter()
+	users.Use(server.requirePermission(model.PermissionManageUsers))
+	users.HandleFunc("/users", server.handleAdminUsersFind()).Methods("GET")
+	users.HandleFunc("/users/{id:[0-9]+}", server.handleAdminUsersGetByID()).Methods("GET")
+	users.HandleFunc("/users/{id:[0-9]+}/role", server.handleAdminUsersRole()).Methods("PUT")
+	users.HandleFunc("/users/{id:[0-9]+}/ban", server.handleAdminUsersBan(true)).Methods("POST")
+	users.HandleFunc("/users/{id:[0-9]+}/ban", server.handleAdminUsersBan(false)).Methods("DELETE")
 }
@@ -1,37 +1,99 @@
 package apiserver
 
 import (
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/gorilla/mux"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/identity"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/mailer"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/ratelimit"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/steamimport"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
 )
 
 const (
 	ctxKeyUser ctxKey = iota
 	ctxKeyRequestID
+	ctxKeySessionID
 )
 
 type ctxKey int8
 
+// rateLimits are limits of route groups, set up in configureRouter
+type rateLimits struct {
+	auth         ratelimit.Rule
+	private      ratelimit.Rule
+	public       ratelimit.Rule
+	loginLockout ratelimit.Lockout
+}
+
+func newRateLimits(config Config) rateLimits {
+	return rateLimits{
+		auth: ratelimit.Rule{
+			Limit:  config.RateLimitAuthRequests,
+			Window: time.Duration(config.RateLimitAuthWindowSec) * time.Second,
+		},
+		private: ratelimit.Rule{
+			Limit:  config.RateLimitPrivateRequests,
+			Window: time.Duration(config.RateLimitPrivateWindowSec) * time.Second,
+		},
+		public: ratelimit.Rule{
+			Limit:  config.RateLimitPublicRequests,
+			Window: time.Duration(config.RateLimitPublicWindowSec) * time.Second,
+		},
+		loginLockout: ratelimit.Lockout{
+			Threshold:    config.LoginLockoutThreshold,
+			BaseDuration: time.Duration(config.LoginLockoutBaseSec) * time.Second,
+			MaxDuration:  time.Duration(config.LoginLockoutMaxSec) * time.Second,
+			ResetAfter:   time.Duration(config.LoginLockoutResetSec) * time.Second,
+		},
+	}
+}
+
 type server struct {
-	router     *mux.Router
-	logger     *logrus.Logger
-	store      store.Store
-	sessionKey []byte
+	router            *mux.Router
+	logger            *logrus.Logger
+	store             store.Store
+	tokens            *tokenutils.Service
+	mailer            mailer.Sender
+	limiter           *ratelimit.Limiter
+	rateLimits        rateLimits
+	appBaseURL        string
+	totpIssuer        string
+	identities        *identity.Registry
+	steamImporter     *steamimport.Importer
+	trustedProxies    []*net.IPNet
+	neighboursRefresh chan struct{}
+	sessionKey        []byte
 }
 
-func newServer(store store.Store) *server {
+func newServer(store store.Store, tokens *tokenutils.Service, mailSender mailer.Sender, limiter *ratelimit.Limiter, limits rateLimits, appBaseURL string, totpIssuer string, identities *identity.Registry, steamImporter *steamimport.Importer, trustedProxies []*net.IPNet) *server {
 	server := &server{
-		router: mux.NewRouter(),
-		logger: logrus.New(),
-		store:  store,
+		router:            mux.NewRouter(),
+		logger:            logrus.New(),
+		store:             store,
+		tokens:            tokens,
+		mailer:            mailSender,
+		limiter:           limiter,
+		rateLimits:        limits,
+		appBaseURL:        strings.TrimRight(appBaseURL, "/"),
+		totpIssuer:        totpIssuer,
+		identities:        identities,
+		steamImporter:     steamImporter,
+		trustedProxies:    trustedProxies,
+		neighboursRefresh: make(chan struct{}, 1),
 	}
 
 	server.configureRouter()
 
+	go server.runNeighboursRefresh()
+
 	return server
 }
 
@@ -1,24 +1,28 @@
 package apistore
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
 type APIEpicGames struct {
-	store store.Store
+	store       store.Store
+	bus         *eventbus.Bus
+	fetchConfig FetchConfig
 }
 
-func NewAPIEpicGames(st store.Store) *APIEpicGames {
+func NewAPIEpicGames(st store.Store, bus *eventbus.Bus, fetchConfig FetchConfig) *APIEpicGames {
 	return &APIEpicGames{
-		store: st,
+		store:       st,
+		bus:         bus,
+		fetchConfig: fetchConfig,
 	}
 }
 
@@ -45,6 +49,7 @@ func (api *APIEpicGames) GetGames() error {
 		ProductSlug string                            `json:"productSlug"`
 		Title       string                            `json:"title"`
 		Price       responseDataCatalogStoreItemPrice `json:"price"`
+		Promotions  *epicGamesPromotions              `json:"promotions"`
 	}
 	type responseDataCatalogStore struct {
 		Elements []responseDataCatalogStoreItem `json:"elements,omitempty"`
@@ -69,37 +74,40 @@ func (api *APIEpicGames) GetGames() error {
 		return errWrapped
 	}
 
+	marketEpicGames, err := api.store.Markets().FindBy("name", "EpicGamesStore")
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+
+		return errWrapped
+	}
+
+	tracker := newOfferTracker(api.store, api.bus, marketEpicGames, api.fetchConfig.MissesToDelist)
 	counter := 0
 	fmt.Println("Getting prices from EpicGames")
 
-	for _, game := range games {
+	fetch := func(index int) (interface{}, error) {
+		game := games[index]
+
 		url := fmt.Sprintf("https://www.epicgames.com/graphql?query="+
 			"{Catalog {searchStore(keywords: \"%s\", country: \"RU\", locale: \"US\", count: 1)"+
 			"{elements {"+
 			"id productSlug namespace title description price(country: \"RU\") "+
-			"{totalPrice{discountPrice originalPrice discount } } } } } }", expectedEpicGamesURL(game.Name))
+			"{totalPrice{discountPrice originalPrice discount } } "+
+			"promotions {promotionalOffers {promotionalOffers {startDate endDate discountSetting {discountPercentage} } } } "+
+			"} } } }", expectedEpicGamesURL(game.Name))
 
 		url = strings.Replace(url, " ", "%20", -1)
 
-		resp, err := http.Get(url)
-		if err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
-		}
-
 		responseStruct := &response{}
 
-		if err := json.NewDecoder(resp.Body).Decode(responseStruct); err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
+		if err := getJSON(url, responseStruct); err != nil {
+			return nil, err
 		}
 
 		// JSON DEBUG
 		// var responseStruct json.RawMessage
 		//
-		// fmt.Printf("%+v\n\n", resp)
-		//
-		// if err := json.NewDecoder(resp.Body).Decode(&responseStruct); err != nil {
+		// if err := getJSON(url, &responseStruct); err != nil {
 		// 	errWrapped := errors.Wrap(err, errWrapMessage)
 		// 	panic(errWrapped)
 		// }
@@ -108,27 +116,19 @@ func (api *APIEpicGames) GetGames() error {
 		// 	panic(err)
 		// }
 		// fmt.Println(string(j))
-		// break
 
 		if len(responseStruct.Data.Catalog.Store.Elements) == 0 {
-			continue
+			return nil, nil
 		}
 
 		gameDataRaw := responseStruct.Data.Catalog.Store.Elements[0]
 
 		if gameDataRaw.Title != game.Name {
-			continue
+			return nil, nil
 		}
 
 		if gameDataRaw.ProductSlug == "" {
-			continue
-		}
-
-		marketEpicGames, err := api.store.Markets().FindBy("name", "EpicGamesStore")
-		if err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-
-			return errWrapped
+			return nil, nil
 		}
 
 		priceFinalFormatted := fmt.Sprintf("%d руб.", gameDataRaw.Price.TotalPrice.FinalValue/100)
@@ -143,36 +143,190 @@ func (api *APIEpicGames) GetGames() error {
 		gameMarketPrice := &model.GameMarketPrice{
 			InitialValueFormatted: priceInitialFormatted,
 			FinalValueFormatted:   priceFinalFormatted,
+			FinalValue:            gameDataRaw.Price.TotalPrice.FinalValue,
 			DiscountPercent:       gameDataRaw.Price.TotalPrice.DiscountPercent,
 			MarketGameURL:         marketGameURL,
 			Game:                  game,
 			Market:                marketEpicGames,
 		}
 
-		gameMarketPriceFound, err := api.store.GameMarketPrices().FindByGameMarket(game, marketEpicGames)
-		if err != nil {
-			if errors.Cause(err) != store.ErrNotFound {
-				errWrapped := errors.Wrap(err, errWrapMessage)
-				return errWrapped
+		if gameMarketPrice.DiscountPercent > 0 {
+			gameMarketPrice.SaleStartsAt, gameMarketPrice.SaleEndsAt = activeEpicGamesPromotion(gameDataRaw.Promotions, time.Now())
+		}
+
+		return gameMarketPrice, nil
+	}
+
+	write := func(batch []interface{}) error {
+		gameMarketPrices := make([]*model.GameMarketPrice, 0, len(batch))
+		for _, item := range batch {
+			gameMarketPrices = append(gameMarketPrices, item.(*model.GameMarketPrice))
+		}
+
+		if err := tracker.saveAll(gameMarketPrices); err != nil {
+			return err
+		}
+
+		counter += len(gameMarketPrices)
+
+		return nil
+	}
+
+	if err := RunFetchPool(api.fetchConfig, len(games), fetch, write); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := tracker.finish(); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	fmt.Printf("Successfully got prices from EpicGames for all %d games\n", counter)
+
+	return nil
+}
+
+type epicGamesPromotionalOffer struct {
+	StartDate       string `json:"startDate"`
+	EndDate         string `json:"endDate"`
+	DiscountSetting struct {
+		DiscountPercentage int `json:"discountPercentage"`
+	} `json:"discountSetting"`
+}
+
+type epicGamesPromotionalOffers struct {
+	PromotionalOffers []epicGamesPromotionalOffer `json:"promotionalOffers"`
+}
+
+type epicGamesPromotions struct {
+	PromotionalOffers         []epicGamesPromotionalOffers `json:"promotionalOffers"`
+	UpcomingPromotionalOffers []epicGamesPromotionalOffers `json:"upcomingPromotionalOffers"`
+}
+
+// activeEpicGamesPromotion returns start and end of the discount promotion, that is going on at the moment.
+// Returns nils if there is no such promotion.
+func activeEpicGamesPromotion(promotions *epicGamesPromotions, now time.Time) (*time.Time, *time.Time) {
+	if promotions == nil {
+		return nil, nil
+	}
+
+	for _, offers := range promotions.PromotionalOffers {
+		for _, offer := range offers.PromotionalOffers {
+			startsAt, err := time.Parse(time.RFC3339, offer.StartDate)
+			if err != nil {
+				continue
 			}
 
-			if err := api.store.GameMarketPrices().Create(gameMarketPrice); err != nil {
-				errWrapped := errors.Wrap(err, errWrapMessage)
-				return errWrapped
+			endsAt, err := time.Parse(time.RFC3339, offer.EndDate)
+			if err != nil {
+				continue
 			}
-		} else {
-			gameMarketPrice.ID = gameMarketPriceFound.ID
 
-			if err := api.store.GameMarketPrices().Update(gameMarketPrice); err != nil {
-				errWrapped := errors.Wrap(err, errWrapMessage)
-				return errWrapped
+			if startsAt.After(now) || !endsAt.After(now) {
+				continue
 			}
+
+			return &startsAt, &endsAt
 		}
+	}
+
+	return nil, nil
+}
 
-		counter += 1
+// GetGiveaways saves current and upcoming free promotions from the catalogue.
+// Epic stores the share of the price, that is left after the discount, so free offer has 0 percentage.
+func (api *APIEpicGames) GetGiveaways() error {
+	type responseDataCatalogStoreItem struct {
+		ProductSlug string               `json:"productSlug"`
+		Title       string               `json:"title"`
+		Promotions  *epicGamesPromotions `json:"promotions"`
+	}
+	type responseDataCatalogStore struct {
+		Elements []responseDataCatalogStoreItem `json:"elements,omitempty"`
+	}
+	type responseDataCatalog struct {
+		Store responseDataCatalogStore `json:"searchStore"`
+	}
+	type responseData struct {
+		Catalog responseDataCatalog `json:"Catalog"`
+	}
+	type response struct {
+		Data responseData `json:"data"`
 	}
 
-	fmt.Printf("Successfully got prices from EpicGames for all %d games\n", counter)
+	apiName := "EpicGames"
+	methodName := "GetGiveaways"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, apiName, methodName)
+
+	marketEpicGames, err := api.store.Markets().FindBy("name", "EpicGamesStore")
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	url := "https://www.epicgames.com/graphql?query=" +
+		"{Catalog {searchStore(category: \"freegames\", country: \"RU\", locale: \"en-US\", count: 100)" +
+		"{elements {" +
+		"title productSlug " +
+		"promotions {" +
+		"promotionalOffers {promotionalOffers {startDate endDate discountSetting {discountPercentage} } } " +
+		"upcomingPromotionalOffers {promotionalOffers {startDate endDate discountSetting {discountPercentage} } } " +
+		"} } } } }"
+
+	url = strings.Replace(url, " ", "%20", -1)
+
+	responseStruct := &response{}
+
+	if err := getJSON(url, responseStruct); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	counter := 0
+
+	for _, element := range responseStruct.Data.Catalog.Store.Elements {
+		if element.Promotions == nil || element.ProductSlug == "" {
+			continue
+		}
+
+		offersGroups := append(element.Promotions.PromotionalOffers, element.Promotions.UpcomingPromotionalOffers...)
+
+		for _, offers := range offersGroups {
+			for _, offer := range offers.PromotionalOffers {
+				if offer.DiscountSetting.DiscountPercentage != 0 {
+					continue
+				}
+
+				startsAt, err := time.Parse(time.RFC3339, offer.StartDate)
+				if err != nil {
+					continue
+				}
+
+				endsAt, err := time.Parse(time.RFC3339, offer.EndDate)
+				if err != nil {
+					continue
+				}
+
+				giveaway := &model.Giveaway{
+					Title:         element.Title,
+					MarketGameURL: strings.Split(element.ProductSlug, "/")[0],
+					StartsAt:      &startsAt,
+					EndsAt:        &endsAt,
+					Market:        marketEpicGames,
+				}
+
+				if err := saveGiveaway(api.store, giveaway); err != nil {
+					errWrapped := errors.Wrap(err, errWrapMessage)
+					return errWrapped
+				}
+
+				counter += 1
+			}
+		}
+	}
+
+	fmt.Printf("Successfully got %d giveaways from EpicGames\n", counter)
 
 	return nil
 }
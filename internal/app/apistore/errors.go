@@ -1,8 +1,12 @@
 package apistore
 
+import "github.com/pkg/errors"
+
 var (
-// ErrAppSkiped = errors.New("App skipped")
-// ErrGameInfo = errors.New("Couldn't get game info")
+	// ErrAppSkiped = errors.New("App skipped")
+	// ErrGameInfo = errors.New("Couldn't get game info")
+	ErrRateLimited = errors.New("API rate limit exceeded")
+	ErrWrongStatus = errors.New("API responded with unexpected status")
 )
 
 const (
@@ -0,0 +1,162 @@
+package apistore
+
+import (
+	"sync"
+	"time"
+)
+
+// FetchConfig controls how a provider syncs with its store API.
+// Concurrency is the number of parallel requests, RequestInterval is the minimal
+// pause between two requests started by any worker and BatchSize is the number
+// of fetched items handed to the store writer at once. MissesToDelist is the number
+// of syncs in a row, that didn't find an offer, after which the offer is marked as delisted.
+type FetchConfig struct {
+	Concurrency     int
+	RequestInterval time.Duration
+	BatchSize       int
+	MissesToDelist  int
+}
+
+func NewFetchConfig() FetchConfig {
+	return FetchConfig{
+		Concurrency:     4,
+		RequestInterval: 0,
+		BatchSize:       50,
+		MissesToDelist:  3,
+	}
+}
+
+// FetchFunc fetches the item with given index. Returning nil value means there is nothing to write.
+type FetchFunc func(index int) (interface{}, error)
+
+// WriteFunc saves a batch of fetched items. It is always called from the goroutine running RunFetchPool.
+type WriteFunc func(batch []interface{}) error
+
+type fetchResult struct {
+	index int
+	value interface{}
+	err   error
+}
+
+// RunFetchPool fetches count items with a bounded pool of workers and passes results to write
+// in batches. Batches preserve the order of indexes no matter in which order the requests finish,
+// so the store is written the same way as with a sequential loop. First error stops the pool.
+func RunFetchPool(config FetchConfig, count int, fetch FetchFunc, write WriteFunc) error {
+	concurrency := config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	batchSize := config.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	var limiter <-chan time.Time
+	if config.RequestInterval > 0 {
+		ticker := time.NewTicker(config.RequestInterval)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	jobs := make(chan int)
+	results := make(chan fetchResult)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for index := 0; index < count; index++ {
+			select {
+			case jobs <- index:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-done:
+						return
+					}
+				}
+
+				value, err := fetch(index)
+
+				select {
+				case results <- fetchResult{index: index, value: value, err: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var poolErr error
+	stop := func(err error) {
+		poolErr = err
+		close(done)
+	}
+
+	pending := make(map[int]fetchResult)
+	next := 0
+	batch := []interface{}{}
+
+	for result := range results {
+		if poolErr != nil {
+			// Draining results of requests that were already in flight
+			continue
+		}
+
+		pending[result.index] = result
+
+		for poolErr == nil {
+			current, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next += 1
+
+			if current.err != nil {
+				stop(current.err)
+				break
+			}
+
+			if current.value == nil {
+				continue
+			}
+
+			batch = append(batch, current.value)
+			if len(batch) >= batchSize {
+				if err := write(batch); err != nil {
+					stop(err)
+					break
+				}
+				batch = []interface{}{}
+			}
+		}
+	}
+
+	if poolErr != nil {
+		return poolErr
+	}
+
+	if len(batch) > 0 {
+		return write(batch)
+	}
+
+	return nil
+}
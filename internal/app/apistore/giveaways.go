@@ -0,0 +1,45 @@
+package apistore
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+// GiveawaysProvider is implemented by stores, that give games away for a limited time
+type GiveawaysProvider interface {
+	GetGiveaways() error
+}
+
+// saveGiveaway links giveaway with a catalogue game with the same name (if there is one) and saves it
+func saveGiveaway(st store.Store, giveaway *model.Giveaway) error {
+	methodName := "saveGiveaway"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, giveaway.Market.Name, methodName)
+
+	if err := matchGiveawayGame(st, giveaway); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	if err := st.Giveaways().Create(giveaway); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	return nil
+}
+
+// matchGiveawayGame sets the catalogue game with the same name as the giveaway, if there is one
+func matchGiveawayGame(st store.Store, giveaway *model.Giveaway) error {
+	game, err := st.Games().FindBy("name", cleanGameName(giveaway.Title))
+	if err != nil {
+		if errors.Cause(err) != store.ErrNotFound {
+			return err
+		}
+		game = nil
+	}
+
+	giveaway.Game = game
+
+	return nil
+}
@@ -1,24 +1,29 @@
 package apistore
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
 type APIGOG struct {
-	store store.Store
+	store       store.Store
+	bus         *eventbus.Bus
+	fetchConfig FetchConfig
 }
 
-func NewAPIGOG(st store.Store) *APIGOG {
+func NewAPIGOG(st store.Store, bus *eventbus.Bus, fetchConfig FetchConfig) *APIGOG {
 	return &APIGOG{
-		store: st,
+		store:       st,
+		bus:         bus,
+		fetchConfig: fetchConfig,
 	}
 }
 
@@ -32,6 +37,17 @@ func expectedGOGURL(gameName string) string {
 	return gameURL
 }
 
+// parsePriceMinorUnits converts price like "299.00" to 29900, wrong price is treated as 0
+func parsePriceMinorUnits(price string) int {
+	value, err := strconv.ParseFloat(strings.TrimSpace(price), 64)
+	if err != nil {
+		return 0
+	}
+
+	return int(math.Round(value * 100))
+}
+
+// GOG catalogue doesn't expose discount end, so sale times stay empty for its offers
 func (api *APIGOG) GetGames() error {
 
 	type responseProductPrice struct {
@@ -59,32 +75,28 @@ func (api *APIGOG) GetGames() error {
 		return errWrapped
 	}
 
+	marketGOG, err := api.store.Markets().FindBy("name", "GOG.com")
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+
+		return errWrapped
+	}
+
+	tracker := newOfferTracker(api.store, api.bus, marketGOG, api.fetchConfig.MissesToDelist)
 	counter := 0
 	fmt.Println("Getting prices from GOG")
 
-	for _, game := range games {
+	fetch := func(index int) (interface{}, error) {
+		game := games[index]
+
 		url := fmt.Sprintf("https://embed.gog.com/games/ajax/filtered?search=%s&language=en", game.Name)
 
 		url = strings.Replace(url, " ", "%20", -1)
 
-		resp, err := http.Get(url)
-		if err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
-		}
-
 		responseStruct := &response{}
 
-		if err := json.NewDecoder(resp.Body).Decode(responseStruct); err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
-		}
-
-		marketGOG, err := api.store.Markets().FindBy("name", "GOG.com")
-		if err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-
-			return errWrapped
+		if err := getJSON(url, responseStruct); err != nil {
+			return nil, err
 		}
 
 		for _, gameDataRaw := range responseStruct.Products {
@@ -103,41 +115,123 @@ func (api *APIGOG) GetGames() error {
 
 			marketGameURL := splitURL[len(splitURL)-1]
 
-			gameMarketPrice := &model.GameMarketPrice{
+			return &model.GameMarketPrice{
 				InitialValueFormatted: priceInitialFormatted,
 				FinalValueFormatted:   priceFinalFormatted,
+				FinalValue:            parsePriceMinorUnits(gameDataRaw.Price.FinalValue),
 				DiscountPercent:       gameDataRaw.Price.DiscountPercent,
 				MarketGameURL:         marketGameURL,
 				Game:                  game,
 				Market:                marketGOG,
-			}
+			}, nil
+		}
 
-			gameMarketPriceFound, err := api.store.GameMarketPrices().FindByGameMarket(game, marketGOG)
-			if err != nil {
-				if errors.Cause(err) != store.ErrNotFound {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
-				}
-
-				if err := api.store.GameMarketPrices().Create(gameMarketPrice); err != nil {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
-				}
-			} else {
-				gameMarketPrice.ID = gameMarketPriceFound.ID
-
-				if err := api.store.GameMarketPrices().Update(gameMarketPrice); err != nil {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
-				}
-			}
+		return nil, nil
+	}
 
-			counter += 1
-			break
+	write := func(batch []interface{}) error {
+		gameMarketPrices := make([]*model.GameMarketPrice, 0, len(batch))
+		for _, item := range batch {
+			gameMarketPrices = append(gameMarketPrices, item.(*model.GameMarketPrice))
 		}
+
+		if err := tracker.saveAll(gameMarketPrices); err != nil {
+			return err
+		}
+
+		counter += len(gameMarketPrices)
+
+		return nil
+	}
+
+	if err := RunFetchPool(api.fetchConfig, len(games), fetch, write); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := tracker.finish(); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
 	}
 
 	fmt.Printf("Successfully got prices from GOG for all %d games\n", counter)
 
 	return nil
 }
+
+// GetGiveaways saves games, that are discounted to zero. GOG doesn't expose promotion dates
+// in the catalogue, so the giveaways are saved without dates and replace the ones found by the previous sync.
+func (api *APIGOG) GetGiveaways() error {
+	type responseProductPrice struct {
+		FinalValue      string `json:"finalAmount"`
+		DiscountPercent int    `json:"discount"`
+		IsDiscounted    bool   `json:"isDiscounted"`
+	}
+	type responseProductSalesVisibility struct {
+		IsActive bool `json:"isActive"`
+	}
+	type responseProduct struct {
+		Title           string                         `json:"title"`
+		URL             string                         `json:"url"`
+		Price           responseProductPrice           `json:"price"`
+		SalesVisibility responseProductSalesVisibility `json:"salesVisibility"`
+	}
+	type response struct {
+		Products []responseProduct `json:"products"`
+	}
+
+	apiName := "GOG"
+	methodName := "GetGiveaways"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, apiName, methodName)
+
+	marketGOG, err := api.store.Markets().FindBy("name", "GOG.com")
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	url := "https://embed.gog.com/games/ajax/filtered?mediaType=game&price=discounted&sort=popularity&language=en"
+
+	responseStruct := &response{}
+
+	if err := getJSON(url, responseStruct); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	giveaways := []*model.Giveaway{}
+
+	for _, gameDataRaw := range responseStruct.Products {
+		if !gameDataRaw.Price.IsDiscounted || gameDataRaw.Price.DiscountPercent != 100 {
+			continue
+		}
+
+		if !gameDataRaw.SalesVisibility.IsActive {
+			continue
+		}
+
+		splitURL := strings.Split(gameDataRaw.URL, "/")
+
+		giveaway := &model.Giveaway{
+			Title:         gameDataRaw.Title,
+			MarketGameURL: splitURL[len(splitURL)-1],
+			Market:        marketGOG,
+		}
+
+		if err := matchGiveawayGame(api.store, giveaway); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return errWrapped
+		}
+
+		giveaways = append(giveaways, giveaway)
+	}
+
+	if err := api.store.Giveaways().ReplaceUndated(marketGOG, giveaways); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	fmt.Printf("Successfully got %d giveaways from GOG\n", len(giveaways))
+
+	return nil
+}
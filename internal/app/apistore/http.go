@@ -0,0 +1,70 @@
+package apistore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxRateLimitRetries    = 3
+	defaultRateLimitPause  = 10 * time.Second
+	maxRateLimitRetryPause = time.Minute
+)
+
+var httpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// getJSON decodes response from url into target. When store answers with 429 it waits
+// for Retry-After (or default pause) and repeats the request a few times before giving up.
+func getJSON(url string, target interface{}) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+
+			if attempt >= maxRateLimitRetries {
+				return errors.Wrap(ErrRateLimited, url)
+			}
+
+			time.Sleep(retryAfter(resp.Header.Get("Retry-After")))
+			continue
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return errors.Wrap(ErrWrongStatus, fmt.Sprintf("%s: %s", url, resp.Status))
+		}
+
+		return json.NewDecoder(resp.Body).Decode(target)
+	}
+}
+
+func retryAfter(header string) time.Duration {
+	pause := defaultRateLimitPause
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		pause = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		pause = time.Until(date)
+	}
+
+	if pause < 0 {
+		pause = 0
+	}
+	if pause > maxRateLimitRetryPause {
+		pause = maxRateLimitRetryPause
+	}
+
+	return pause
+}
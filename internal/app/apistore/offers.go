@@ -0,0 +1,237 @@
+package apistore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+// DetectPriceChanges returns types of events caused by replacing previous offer with current one.
+// Previous offer is nil when the game has just been found in the market, delisted previous offer
+// is treated the same way.
+func DetectPriceChanges(previous *model.GameMarketPrice, current *model.GameMarketPrice) []eventbus.EventType {
+	eventTypes := []eventbus.EventType{}
+
+	if previous == nil || previous.Delisted {
+		eventTypes = append(eventTypes, eventbus.EventPriceChanged)
+		if current.DiscountPercent > 0 {
+			eventTypes = append(eventTypes, eventbus.EventSaleStarted)
+		}
+		return eventTypes
+	}
+
+	// FinalValue is compared too, as offers stored before it was parsed have it zeroed
+	// while their formatted prices stay the same
+	if previous.InitialValueFormatted != current.InitialValueFormatted ||
+		previous.FinalValueFormatted != current.FinalValueFormatted ||
+		previous.FinalValue != current.FinalValue {
+		eventTypes = append(eventTypes, eventbus.EventPriceChanged)
+	}
+
+	if previous.DiscountPercent == 0 && current.DiscountPercent > 0 {
+		eventTypes = append(eventTypes, eventbus.EventSaleStarted)
+	}
+
+	if previous.DiscountPercent > 0 && current.DiscountPercent == 0 {
+		eventTypes = append(eventTypes, eventbus.EventSaleEnded)
+	}
+
+	return eventTypes
+}
+
+func gameMarketPriceChanged(previous *model.GameMarketPrice, current *model.GameMarketPrice) bool {
+	return previous.InitialValueFormatted != current.InitialValueFormatted ||
+		previous.FinalValueFormatted != current.FinalValueFormatted ||
+		previous.FinalValue != current.FinalValue ||
+		previous.DiscountPercent != current.DiscountPercent ||
+		!sameTime(previous.SaleStartsAt, current.SaleStartsAt) ||
+		!sameTime(previous.SaleEndsAt, current.SaleEndsAt) ||
+		previous.MarketGameURL != current.MarketGameURL ||
+		previous.Delisted != current.Delisted
+}
+
+func sameTime(previous *time.Time, current *time.Time) bool {
+	if previous == nil || current == nil {
+		return previous == current
+	}
+
+	return previous.Equal(*current)
+}
+
+// saveGameMarketPrice compares fetched offer with the stored one, writes it only if something differs
+// and publishes events about the change.
+func saveGameMarketPrice(st store.Store, bus *eventbus.Bus, gameMarketPrice *model.GameMarketPrice) error {
+	methodName := "saveGameMarketPrice"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, gameMarketPrice.Market.Name, methodName)
+
+	now := time.Now()
+	gameMarketPrice.LastSeenAt = now
+	gameMarketPrice.MissedSyncs = 0
+	gameMarketPrice.Delisted = false
+
+	gameMarketPriceFound, err := st.GameMarketPrices().FindByGameMarket(gameMarketPrice.Game, gameMarketPrice.Market)
+	if err != nil {
+		if errors.Cause(err) != store.ErrNotFound {
+			return errors.Wrap(err, errWrapMessage)
+		}
+
+		gameMarketPriceFound = nil
+
+		if err := st.GameMarketPrices().Create(gameMarketPrice); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+	} else {
+		if !gameMarketPriceChanged(gameMarketPriceFound, gameMarketPrice) {
+			gameMarketPrice.ID = gameMarketPriceFound.ID
+			return nil
+		}
+
+		gameMarketPrice.ID = gameMarketPriceFound.ID
+
+		if err := st.GameMarketPrices().Update(gameMarketPrice); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+	}
+
+	if err := publishPriceChanges(bus, gameMarketPriceFound, gameMarketPrice, now); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	return nil
+}
+
+func publishPriceChanges(bus *eventbus.Bus, previous *model.GameMarketPrice, current *model.GameMarketPrice, now time.Time) error {
+	for _, eventType := range DetectPriceChanges(previous, current) {
+		if err := bus.Publish(eventbus.Event{
+			Type:     eventType,
+			Previous: previous,
+			Current:  current,
+			Time:     now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// offerTracker remembers offers found during one provider sync,
+// so the offers that weren't found can be marked as missed afterwards.
+type offerTracker struct {
+	store          store.Store
+	bus            *eventbus.Bus
+	market         *model.Market
+	missesToDelist int
+	seenIDs        []uint64
+}
+
+func newOfferTracker(st store.Store, bus *eventbus.Bus, market *model.Market, missesToDelist int) *offerTracker {
+	if missesToDelist < 1 {
+		missesToDelist = 1
+	}
+
+	return &offerTracker{
+		store:          st,
+		bus:            bus,
+		market:         market,
+		missesToDelist: missesToDelist,
+		seenIDs:        []uint64{},
+	}
+}
+
+// saveAll writes one batch of fetched offers: stored offers are loaded with one query,
+// changed and new ones are written in one transaction, and events are published after it's committed
+func (tracker *offerTracker) saveAll(gameMarketPrices []*model.GameMarketPrice) error {
+	methodName := "offerTracker.saveAll"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, tracker.market.Name, methodName)
+
+	games := make([]*model.Game, 0, len(gameMarketPrices))
+	for _, gameMarketPrice := range gameMarketPrices {
+		games = append(games, gameMarketPrice.Game)
+	}
+
+	gameMarketPricesFound, err := tracker.store.GameMarketPrices().FindAllByGamesMarket(games, tracker.market)
+	if err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	gameMarketPricesFoundByGame := make(map[uint64]*model.GameMarketPrice, len(gameMarketPricesFound))
+	for _, gameMarketPriceFound := range gameMarketPricesFound {
+		gameMarketPricesFoundByGame[gameMarketPriceFound.Game.ID] = gameMarketPriceFound
+	}
+
+	now := time.Now()
+	changed := []*model.GameMarketPrice{}
+	for _, gameMarketPrice := range gameMarketPrices {
+		gameMarketPrice.LastSeenAt = now
+		gameMarketPrice.MissedSyncs = 0
+		gameMarketPrice.Delisted = false
+
+		if gameMarketPriceFound, ok := gameMarketPricesFoundByGame[gameMarketPrice.Game.ID]; ok {
+			gameMarketPrice.ID = gameMarketPriceFound.ID
+			if !gameMarketPriceChanged(gameMarketPriceFound, gameMarketPrice) {
+				continue
+			}
+		}
+
+		changed = append(changed, gameMarketPrice)
+	}
+
+	if err := tracker.store.GameMarketPrices().SaveAll(changed); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	for _, gameMarketPrice := range gameMarketPrices {
+		tracker.seenIDs = append(tracker.seenIDs, gameMarketPrice.ID)
+	}
+
+	for _, gameMarketPrice := range changed {
+		if err := publishPriceChanges(tracker.bus, gameMarketPricesFoundByGame[gameMarketPrice.Game.ID], gameMarketPrice, now); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+	}
+
+	return nil
+}
+
+// finish must be called only after a complete sync, otherwise offers that weren't checked would be counted as missed
+func (tracker *offerTracker) finish() error {
+	methodName := "offerTracker.finish"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, tracker.market.Name, methodName)
+
+	now := time.Now()
+
+	if err := tracker.store.GameMarketPrices().MarkSeen(tracker.seenIDs, now); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	delistedIDs, err := tracker.store.GameMarketPrices().MarkMissed(tracker.market, tracker.seenIDs, tracker.missesToDelist)
+	if err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	for _, id := range delistedIDs {
+		gameMarketPrice, err := tracker.store.GameMarketPrices().Find(id)
+		if err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+
+		if err := tracker.bus.Publish(eventbus.Event{
+			Type:    eventbus.EventDelisted,
+			Current: gameMarketPrice,
+			Time:    now,
+		}); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+	}
+
+	if len(delistedIDs) != 0 {
+		fmt.Printf("Marked %d games as no longer sold in %s\n", len(delistedIDs), tracker.market.Name)
+	}
+
+	return nil
+}
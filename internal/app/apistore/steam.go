@@ -3,26 +3,31 @@ package apistore
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
 // TODO: think about sexual content
 type APISteam struct {
-	apiKey string
-	store  store.Store
+	apiKey      string
+	store       store.Store
+	bus         *eventbus.Bus
+	fetchConfig FetchConfig
 }
 
-func NewAPISteam(apiKey string, st store.Store) *APISteam {
+func NewAPISteam(apiKey string, st store.Store, bus *eventbus.Bus, fetchConfig FetchConfig) *APISteam {
 	return &APISteam{
-		apiKey: apiKey,
-		store:  st,
+		apiKey:      apiKey,
+		store:       st,
+		bus:         bus,
+		fetchConfig: fetchConfig,
 	}
 }
 
@@ -46,15 +51,9 @@ func (api *APISteam) GetGames() error {
 
 	url := fmt.Sprintf("http://api.steampowered.com/ISteamApps/GetAppList/v2/?key=%s&format=json", api.apiKey)
 
-	resp, err := http.Get(url)
-	if err != nil {
-		errWrapped := errors.Wrap(err, errWrapMessage)
-		return errWrapped
-	}
-
 	responseStruct := &response{}
 
-	if err := json.NewDecoder(resp.Body).Decode(responseStruct); err != nil {
+	if err := getJSON(url, responseStruct); err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return errWrapped
 	}
@@ -80,6 +79,11 @@ func (api *APISteam) GetGames() error {
 	for _, game := range games {
 		gameMarketPriceFound, err := api.store.GameMarketPrices().FindByGameMarket(game, marketSteam)
 		if err != nil {
+			// Games added by moderators may not be sold in Steam
+			if errors.Cause(err) == store.ErrNotFound {
+				continue
+			}
+
 			errWrapped := errors.Wrap(err, errWrapMessage)
 			return errWrapped
 		}
@@ -127,16 +131,28 @@ func (api *APISteam) GetGames() error {
 	if maxGameCount > len(appIDs) {
 		maxGameCount = len(appIDs)
 	}
+	appIDs = appIDs[:maxGameCount]
 	counter := 0
 	fmt.Println("Getting GameInfo from Steam")
 
-	for _, appID := range appIDs[:maxGameCount] {
-		if err := api.getSteamGameInfo(appID, marketSteam); err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
+	fetch := func(index int) (interface{}, error) {
+		return api.fetchSteamGameInfo(appIDs[index])
+	}
+
+	write := func(batch []interface{}) error {
+		for _, item := range batch {
+			if err := api.saveSteamGameInfo(item.(*steamGameInfo), marketSteam); err != nil {
+				return err
+			}
+			counter += 1
+			fmt.Printf("%d/%d\r", counter, maxGameCount)
 		}
-		counter += 1
-		fmt.Printf("%d/%d\r", counter, maxGameCount)
+		return nil
+	}
+
+	if err := RunFetchPool(api.fetchConfig, len(appIDs), fetch, write); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
 	}
 
 	fmt.Printf("Successfully got GameInfo from Steam for all %d games\n", maxGameCount)
@@ -147,6 +163,7 @@ func (api *APISteam) GetGames() error {
 type updateSteamResponseAppDataPrice struct {
 	InitialFormatted string `json:"initial_formatted"`
 	FinalFormatted   string `json:"final_formatted"`
+	Final            int    `json:"final"`
 	DiscountPercent  int    `json:"discount_percent"`
 }
 
@@ -188,94 +205,90 @@ func (api *APISteam) UpdateGameMarketPrices(gamesToUpdate map[string]*model.Game
 	for appID := range gamesToUpdate {
 		appIDs = append(appIDs, appID)
 	}
+	sort.Strings(appIDs)
 
 	maxGamesCount := 100
-	offset := 0
+	chunks := [][]string{}
 
+	for offset := 0; offset < len(appIDs); offset += maxGamesCount {
+		end := offset + maxGamesCount
+		if end > len(appIDs) {
+			end = len(appIDs)
+		}
+		chunks = append(chunks, appIDs[offset:end])
+	}
+
+	type chunkResult struct {
+		appIDs   []string
+		response map[string]updateSteamResponseApp
+	}
+
+	tracker := newOfferTracker(api.store, api.bus, marketSteam, api.fetchConfig.MissesToDelist)
 	counter := 0
 	counterFails := 0
 	fmt.Println("UpdatingPrices from Steam")
 
-	for {
-		var currentAppIDs []string
-
-		if len(appIDs)-offset > maxGamesCount {
-			currentAppIDs = appIDs[offset : offset+maxGamesCount]
-			offset += maxGamesCount
-		} else if len(appIDs)-offset > 0 {
-			currentAppIDs = appIDs[offset:]
-			offset = len(appIDs)
-		} else {
-			break
-		}
-
+	fetch := func(index int) (interface{}, error) {
+		currentAppIDs := chunks[index]
 		url := fmt.Sprintf("http://store.steampowered.com/api/appdetails?appids=%s&filters=price_overview&cc=ru&l=en", strings.Join(currentAppIDs, ","))
 
-		resp, err := http.Get(url)
-		if err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
-		}
-
 		responseStruct := make(map[string]updateSteamResponseApp)
 
-		if err := json.NewDecoder(resp.Body).Decode(&responseStruct); err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
+		if err := getJSON(url, &responseStruct); err != nil {
+			return nil, err
 		}
 
-		// JSON DEBUG
-		// var responseStruct json.RawMessage
-		//
-		// if err := json.NewDecoder(resp.Body).Decode(&responseStruct); err != nil {
-		// 	errWrapped := errors.Wrap(err, errWrapMessage)
-		// 	return errWrapped
-		// }
-		// j, err := json.Marshal(&responseStruct)
-		// if err != nil {
-		// 	panic(err)
-		// }
-		// fmt.Println(string(j))
-
-		for _, appID := range currentAppIDs {
-			gameInfoRaw := responseStruct[appID]
-
-			if !gameInfoRaw.Success {
-				counterFails += 1
-			}
+		return &chunkResult{
+			appIDs:   currentAppIDs,
+			response: responseStruct,
+		}, nil
+	}
 
-			gameMarketPrice := &model.GameMarketPrice{
-				InitialValueFormatted: gameInfoRaw.Data.PriceOverview.InitialFormatted,
-				FinalValueFormatted:   gameInfoRaw.Data.PriceOverview.FinalFormatted,
-				DiscountPercent:       gameInfoRaw.Data.PriceOverview.DiscountPercent,
-				MarketGameURL:         appID,
-				Game:                  gamesToUpdate[appID],
-				Market:                marketSteam,
-			}
+	write := func(batch []interface{}) error {
+		gameMarketPrices := []*model.GameMarketPrice{}
+		for _, item := range batch {
+			result := item.(*chunkResult)
 
-			gameMarketPriceFound, err := api.store.GameMarketPrices().FindByGameMarket(gamesToUpdate[appID], marketSteam)
-			if err != nil {
-				if errors.Cause(err) != store.ErrNotFound {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
-				}
+			for _, appID := range result.appIDs {
+				gameInfoRaw := result.response[appID]
 
-				if err := api.store.GameMarketPrices().Create(gameMarketPrice); err != nil {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
+				if !gameInfoRaw.Success {
+					counterFails += 1
+					continue
 				}
-			} else {
-				gameMarketPrice.ID = gameMarketPriceFound.ID
 
-				if err := api.store.GameMarketPrices().Update(gameMarketPrice); err != nil {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
+				gameMarketPrice := &model.GameMarketPrice{
+					InitialValueFormatted: gameInfoRaw.Data.PriceOverview.InitialFormatted,
+					FinalValueFormatted:   gameInfoRaw.Data.PriceOverview.FinalFormatted,
+					FinalValue:            gameInfoRaw.Data.PriceOverview.Final,
+					DiscountPercent:       gameInfoRaw.Data.PriceOverview.DiscountPercent,
+					MarketGameURL:         appID,
+					Game:                  gamesToUpdate[appID],
+					Market:                marketSteam,
 				}
+
+				gameMarketPrices = append(gameMarketPrices, gameMarketPrice)
 			}
+		}
 
-			counter += 1
-			fmt.Printf("%d/%d\r", counter, len(gamesToUpdate))
+		if err := tracker.saveAll(gameMarketPrices); err != nil {
+			return err
 		}
+
+		counter += len(gameMarketPrices)
+		fmt.Printf("%d/%d\r", counter, len(gamesToUpdate))
+
+		return nil
+	}
+
+	if err := RunFetchPool(api.fetchConfig, len(chunks), fetch, write); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := tracker.finish(); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
 	}
 
 	fmt.Printf("Successfully updated prices from Steam for all %d games\n", len(gamesToUpdate)-counterFails)
@@ -283,62 +296,74 @@ func (api *APISteam) UpdateGameMarketPrices(gamesToUpdate map[string]*model.Game
 	return nil
 }
 
-func (api *APISteam) getSteamGameInfo(appID string, marketSteam *model.Market) error {
-	type responseAppDataGenre struct {
-		Description string `json:"description"`
-	}
+type steamGameInfoGenre struct {
+	Description string `json:"description"`
+}
 
-	type responseAppDataReleaseDate struct {
-		ComingSoon bool   `json:"coming_soon"`
-		Date       string `json:"date"`
-	}
+type steamGameInfoReleaseDate struct {
+	ComingSoon bool   `json:"coming_soon"`
+	Date       string `json:"date"`
+}
 
-	type responseAppDataPrice struct {
-		InitialFormatted string `json:"initial_formatted,omitempty"`
-		FinalFormatted   string `json:"final_formatted,omitempty"`
-		DiscountPercent  int    `json:"discount_percent,omitempty"`
-	}
+type steamGameInfoPrice struct {
+	InitialFormatted string `json:"initial_formatted,omitempty"`
+	FinalFormatted   string `json:"final_formatted,omitempty"`
+	Final            int    `json:"final,omitempty"`
+	DiscountPercent  int    `json:"discount_percent,omitempty"`
+}
 
-	type responseAppData struct {
-		Type          string                     `json:"type"`
-		Name          string                     `json:"name"`
-		HeaderImage   string                     `json:"header_image"`
-		Genres        []responseAppDataGenre     `json:"genres"`
-		ReleaseDate   responseAppDataReleaseDate `json:"release_date"`
-		Description   string                     `json:"short_description"`
-		Publishers    []string                   `jsin:"publishers"`
-		PriceOverview responseAppDataPrice       `json:"price_overview,omitempty"`
-	}
+type steamGameInfoData struct {
+	Type          string                   `json:"type"`
+	Name          string                   `json:"name"`
+	HeaderImage   string                   `json:"header_image"`
+	Genres        []steamGameInfoGenre     `json:"genres"`
+	ReleaseDate   steamGameInfoReleaseDate `json:"release_date"`
+	Description   string                   `json:"short_description"`
+	Publishers    []string                 `jsin:"publishers"`
+	PriceOverview steamGameInfoPrice       `json:"price_overview,omitempty"`
+}
 
-	type responseApp struct {
-		Success bool            `json:"success"`
-		Data    responseAppData `json:"data,omitempty"`
-	}
+type steamGameInfoApp struct {
+	Success bool              `json:"success"`
+	Data    steamGameInfoData `json:"data,omitempty"`
+}
 
+// steamGameInfo is the result of appdetails request, that is saved to the store by saveSteamGameInfo
+type steamGameInfo struct {
+	AppID string
+	App   steamGameInfoApp
+}
+
+func (api *APISteam) fetchSteamGameInfo(appID string) (*steamGameInfo, error) {
 	apiName := "Steam"
-	methodName := "getGamesInfo"
+	methodName := "fetchSteamGameInfo"
 	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, apiName, methodName)
 
 	url := fmt.Sprintf("http://store.steampowered.com/api/appdetails?appids=%s&cc=ru&l=en", appID)
 
-	resp, err := http.Get(url)
-	if err != nil {
-		errWrapped := errors.Wrap(err, errWrapMessage)
-		errWrapped = errors.Wrap(err, fmt.Sprintf("AppID: %s", appID))
-		return errWrapped
-	}
-
-	responseStruct := make(map[string]responseApp)
+	responseStruct := make(map[string]steamGameInfoApp)
 
-	if err := json.NewDecoder(resp.Body).Decode(&responseStruct); err != nil {
-		errWrapped := errors.Wrap(err, errWrapMessage)
-		errWrapped = errors.Wrap(err, fmt.Sprintf("AppID: %s", appID))
-		return errWrapped
+	if err := getJSON(url, &responseStruct); err != nil {
+		errWrapped := errors.Wrap(err, fmt.Sprintf("AppID: %s", appID))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
 	}
 
 	// fmt.Printf("responseStruct: %+v\n\n", responseStruct)
 
-	gameInfoRaw := responseStruct[appID]
+	return &steamGameInfo{
+		AppID: appID,
+		App:   responseStruct[appID],
+	}, nil
+}
+
+func (api *APISteam) saveSteamGameInfo(info *steamGameInfo, marketSteam *model.Market) error {
+	apiName := "Steam"
+	methodName := "saveSteamGameInfo"
+	errWrapMessage := fmt.Sprintf(errAPIStoreMessageFormat, apiName, methodName)
+
+	appID := info.AppID
+	gameInfoRaw := info.App
 	marketBlacklist := &model.MarketBlacklistItem{
 		MarketGameURL: appID,
 		Market:        marketSteam,
@@ -456,30 +481,14 @@ func (api *APISteam) getSteamGameInfo(appID string, marketSteam *model.Market) e
 	gameMarketPrice := &model.GameMarketPrice{
 		InitialValueFormatted: gameInfoRaw.Data.PriceOverview.InitialFormatted,
 		FinalValueFormatted:   gameInfoRaw.Data.PriceOverview.FinalFormatted,
+		FinalValue:            gameInfoRaw.Data.PriceOverview.Final,
 		DiscountPercent:       gameInfoRaw.Data.PriceOverview.DiscountPercent,
 		MarketGameURL:         appID,
 		Game:                  game,
 		Market:                marketSteam,
 	}
 
-	gameMarketPriceFound, err := api.store.GameMarketPrices().FindByGameMarket(game, marketSteam)
-	if err != nil {
-		if errors.Cause(err) != store.ErrNotFound {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
-		}
-
-		if err := api.store.GameMarketPrices().Create(gameMarketPrice); err != nil {
-			errWrapped := errors.Wrap(err, errWrapMessage)
-			return errWrapped
-		}
-
-		return nil
-	}
-
-	gameMarketPrice.ID = gameMarketPriceFound.ID
-
-	if err := api.store.GameMarketPrices().Update(gameMarketPrice); err != nil {
+	if err := saveGameMarketPrice(api.store, api.bus, gameMarketPrice); err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return errWrapped
 	}
@@ -0,0 +1,193 @@
+package apistore_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apistore"
+)
+
+func TestRunFetchPoolOrder(t *testing.T) {
+	count := 100
+	config := apistore.FetchConfig{
+		Concurrency: 8,
+		BatchSize:   7,
+	}
+
+	fetch := func(index int) (interface{}, error) {
+		time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+		if index%10 == 0 {
+			// Nothing to write for this item
+			return nil, nil
+		}
+		return index, nil
+	}
+
+	written := []int{}
+	batchSizes := []int{}
+
+	write := func(batch []interface{}) error {
+		batchSizes = append(batchSizes, len(batch))
+		for _, item := range batch {
+			written = append(written, item.(int))
+		}
+		return nil
+	}
+
+	if err := apistore.RunFetchPool(config, count, fetch, write); err != nil {
+		t.Errorf("Couldn't run fetch pool:\n\t%s", err.Error())
+		return
+	}
+
+	want := []int{}
+	for index := 0; index < count; index++ {
+		if index%10 != 0 {
+			want = append(want, index)
+		}
+	}
+
+	if len(written) != len(want) {
+		t.Errorf("Wrong number of written items:\n\tWanted: %d, Got: %d", len(want), len(written))
+		return
+	}
+
+	for i := range want {
+		if written[i] != want[i] {
+			t.Errorf("Items were written out of order:\n\tWanted: %v,\n\tGot: %v", want, written)
+			return
+		}
+	}
+
+	for i, size := range batchSizes {
+		if size > config.BatchSize || (size < config.BatchSize && i != len(batchSizes)-1) {
+			t.Errorf("Wrong batch sizes (batch size %d):\n\tGot: %v", config.BatchSize, batchSizes)
+			return
+		}
+	}
+}
+
+func TestRunFetchPoolConcurrencyLimit(t *testing.T) {
+	config := apistore.FetchConfig{
+		Concurrency: 3,
+		BatchSize:   10,
+	}
+
+	var mutex sync.Mutex
+	inFlight := 0
+	maxInFlight := 0
+
+	fetch := func(index int) (interface{}, error) {
+		mutex.Lock()
+		inFlight += 1
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mutex.Lock()
+		inFlight -= 1
+		mutex.Unlock()
+
+		return index, nil
+	}
+
+	write := func(batch []interface{}) error {
+		return nil
+	}
+
+	if err := apistore.RunFetchPool(config, 30, fetch, write); err != nil {
+		t.Errorf("Couldn't run fetch pool:\n\t%s", err.Error())
+		return
+	}
+
+	if maxInFlight > config.Concurrency {
+		t.Errorf("Too many concurrent requests:\n\tWanted: <= %d, Got: %d", config.Concurrency, maxInFlight)
+	}
+}
+
+func TestRunFetchPoolRequestInterval(t *testing.T) {
+	count := 5
+	config := apistore.FetchConfig{
+		Concurrency:     5,
+		RequestInterval: 10 * time.Millisecond,
+		BatchSize:       10,
+	}
+
+	fetch := func(index int) (interface{}, error) {
+		return index, nil
+	}
+
+	write := func(batch []interface{}) error {
+		return nil
+	}
+
+	start := time.Now()
+	if err := apistore.RunFetchPool(config, count, fetch, write); err != nil {
+		t.Errorf("Couldn't run fetch pool:\n\t%s", err.Error())
+		return
+	}
+
+	elapsed := time.Since(start)
+	minimal := time.Duration(count) * config.RequestInterval
+	if elapsed < minimal {
+		t.Errorf("Requests weren't rate limited:\n\tWanted: >= %v, Got: %v", minimal, elapsed)
+	}
+}
+
+func TestRunFetchPoolErrors(t *testing.T) {
+	config := apistore.FetchConfig{
+		Concurrency: 4,
+		BatchSize:   2,
+	}
+	errFetch := errors.New("Fetch error")
+	errWrite := errors.New("Write error")
+
+	fetchWithError := func(index int) (interface{}, error) {
+		if index == 13 {
+			return nil, errFetch
+		}
+		return index, nil
+	}
+
+	written := []int{}
+	write := func(batch []interface{}) error {
+		for _, item := range batch {
+			written = append(written, item.(int))
+		}
+		return nil
+	}
+
+	if err := apistore.RunFetchPool(config, 50, fetchWithError, write); errors.Cause(err) != errFetch {
+		t.Errorf("Wrong error when fetch fails:\n\tWanted: %v, Got: %v", errFetch, err)
+	}
+
+	for _, index := range written {
+		if index >= 13 {
+			t.Errorf("Items after failed fetch were written:\n\tGot: %v", written)
+			break
+		}
+	}
+
+	fetch := func(index int) (interface{}, error) {
+		return index, nil
+	}
+
+	writeCalls := 0
+	writeWithError := func(batch []interface{}) error {
+		writeCalls += 1
+		return errWrite
+	}
+
+	if err := apistore.RunFetchPool(config, 50, fetch, writeWithError); errors.Cause(err) != errWrite {
+		t.Errorf("Wrong error when write fails:\n\tWanted: %v, Got: %v", errWrite, err)
+	}
+
+	if writeCalls != 1 {
+		t.Errorf("Pool kept writing after error:\n\tWanted: 1, Got: %d", writeCalls)
+	}
+}
@@ -0,0 +1,77 @@
+package apistore_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apistore"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestDetectPriceChanges(t *testing.T) {
+	fullPrice := &model.GameMarketPrice{
+		InitialValueFormatted: "",
+		FinalValueFormatted:   "1000 руб.",
+		DiscountPercent:       0,
+	}
+	salePrice := &model.GameMarketPrice{
+		InitialValueFormatted: "1000 руб.",
+		FinalValueFormatted:   "500 руб.",
+		DiscountPercent:       50,
+	}
+	newFullPrice := &model.GameMarketPrice{
+		InitialValueFormatted: "",
+		FinalValueFormatted:   "1200 руб.",
+		DiscountPercent:       0,
+	}
+	unparsedPrice := &model.GameMarketPrice{
+		InitialValueFormatted: "",
+		FinalValueFormatted:   "1000 руб.",
+		FinalValue:            0,
+		DiscountPercent:       0,
+	}
+	parsedPrice := &model.GameMarketPrice{
+		InitialValueFormatted: "",
+		FinalValueFormatted:   "1000 руб.",
+		FinalValue:            100000,
+		DiscountPercent:       0,
+	}
+	delistedPrice := &model.GameMarketPrice{
+		InitialValueFormatted: "",
+		FinalValueFormatted:   "1000 руб.",
+		DiscountPercent:       0,
+		Delisted:              true,
+	}
+
+	testCases := []struct {
+		name     string
+		previous *model.GameMarketPrice
+		current  *model.GameMarketPrice
+		want     []eventbus.EventType
+	}{
+		{"new offer", nil, fullPrice, []eventbus.EventType{eventbus.EventPriceChanged}},
+		{"new offer on sale", nil, salePrice, []eventbus.EventType{eventbus.EventPriceChanged, eventbus.EventSaleStarted}},
+		{"nothing changed", fullPrice, fullPrice, []eventbus.EventType{}},
+		{"sale started", fullPrice, salePrice, []eventbus.EventType{eventbus.EventPriceChanged, eventbus.EventSaleStarted}},
+		{"sale ended", salePrice, fullPrice, []eventbus.EventType{eventbus.EventPriceChanged, eventbus.EventSaleEnded}},
+		{"price changed", fullPrice, newFullPrice, []eventbus.EventType{eventbus.EventPriceChanged}},
+		{"value parsed", unparsedPrice, parsedPrice, []eventbus.EventType{eventbus.EventPriceChanged}},
+		{"relisted", delistedPrice, fullPrice, []eventbus.EventType{eventbus.EventPriceChanged}},
+	}
+
+	for _, testCase := range testCases {
+		got := apistore.DetectPriceChanges(testCase.previous, testCase.current)
+
+		if len(got) != len(testCase.want) {
+			t.Errorf("Wrong events for %s:\n\tWanted: %v, Got: %v", testCase.name, testCase.want, got)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != testCase.want[i] {
+				t.Errorf("Wrong events for %s:\n\tWanted: %v, Got: %v", testCase.name, testCase.want, got)
+				break
+			}
+		}
+	}
+}
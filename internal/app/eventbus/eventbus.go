@@ -0,0 +1,87 @@
+package eventbus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+type EventType string
+
+const (
+	EventPriceChanged EventType = "price_changed"
+	EventSaleStarted  EventType = "sale_started"
+	EventSaleEnded    EventType = "sale_ended"
+	EventDelisted     EventType = "delisted"
+)
+
+// Event describes a change of a game offer in some market.
+// Previous is nil when the offer has just been found for the first time.
+type Event struct {
+	Type     EventType
+	Previous *model.GameMarketPrice
+	Current  *model.GameMarketPrice
+	Time     time.Time
+}
+
+// Handler returns an error, if the event couldn't be handled, so the publisher can stop
+type Handler func(Event) error
+
+type subscription struct {
+	handler    Handler
+	eventTypes map[EventType]bool
+}
+
+// Bus is an in-process publish/subscribe bus. Handlers are called synchronously
+// in the order they subscribed, so publisher waits until every subscriber is done.
+type Bus struct {
+	mutex         sync.RWMutex
+	subscriptions []subscription
+}
+
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers handler for given event types, or for all events if no types given.
+func (bus *Bus) Subscribe(handler Handler, eventTypes ...EventType) {
+	newSubscription := subscription{
+		handler:    handler,
+		eventTypes: make(map[EventType]bool),
+	}
+
+	for _, eventType := range eventTypes {
+		newSubscription.eventTypes[eventType] = true
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.subscriptions = append(bus.subscriptions, newSubscription)
+}
+
+// Publish passes the event to every subscriber, even if some of them fail,
+// and returns the first error.
+func (bus *Bus) Publish(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	bus.mutex.RLock()
+	subscriptions := bus.subscriptions
+	bus.mutex.RUnlock()
+
+	var firstErr error
+	for _, currentSubscription := range subscriptions {
+		if len(currentSubscription.eventTypes) != 0 && !currentSubscription.eventTypes[event.Type] {
+			continue
+		}
+
+		if err := currentSubscription.handler(event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,85 @@
+package eventbus_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
+)
+
+func TestBusPublish(t *testing.T) {
+	bus := eventbus.New()
+
+	receivedAll := []eventbus.EventType{}
+	receivedSales := []eventbus.EventType{}
+
+	bus.Subscribe(func(event eventbus.Event) error {
+		receivedAll = append(receivedAll, event.Type)
+		return nil
+	})
+	bus.Subscribe(func(event eventbus.Event) error {
+		receivedSales = append(receivedSales, event.Type)
+		return nil
+	}, eventbus.EventSaleStarted, eventbus.EventSaleEnded)
+
+	published := []eventbus.EventType{
+		eventbus.EventPriceChanged,
+		eventbus.EventSaleStarted,
+		eventbus.EventDelisted,
+		eventbus.EventSaleEnded,
+	}
+
+	for _, eventType := range published {
+		bus.Publish(eventbus.Event{Type: eventType})
+	}
+
+	if len(receivedAll) != len(published) {
+		t.Errorf("Subscriber without filter got wrong events:\n\tWanted: %v,\n\tGot: %v", published, receivedAll)
+	} else {
+		for i := range published {
+			if receivedAll[i] != published[i] {
+				t.Errorf("Subscriber without filter got events out of order:\n\tWanted: %v,\n\tGot: %v", published, receivedAll)
+				break
+			}
+		}
+	}
+
+	wantSales := []eventbus.EventType{eventbus.EventSaleStarted, eventbus.EventSaleEnded}
+	if len(receivedSales) != len(wantSales) || receivedSales[0] != wantSales[0] || receivedSales[1] != wantSales[1] {
+		t.Errorf("Subscriber with filter got wrong events:\n\tWanted: %v,\n\tGot: %v", wantSales, receivedSales)
+	}
+}
+
+func TestBusPublishSetsTime(t *testing.T) {
+	bus := eventbus.New()
+
+	bus.Subscribe(func(event eventbus.Event) error {
+		if event.Time.IsZero() {
+			t.Errorf("Published event has no time:\n\t%+v", event)
+		}
+		return nil
+	})
+
+	bus.Publish(eventbus.Event{Type: eventbus.EventPriceChanged})
+}
+
+func TestBusPublishReturnsError(t *testing.T) {
+	bus := eventbus.New()
+	errHandler := errors.New("handler error")
+
+	calledAfterError := false
+	bus.Subscribe(func(event eventbus.Event) error {
+		return errHandler
+	})
+	bus.Subscribe(func(event eventbus.Event) error {
+		calledAfterError = true
+		return nil
+	})
+
+	if err := bus.Publish(eventbus.Event{Type: eventbus.EventPriceChanged}); err != errHandler {
+		t.Errorf("Wrong publish error:\n\tWanted: %v, Got: %v", errHandler, err)
+	}
+	if !calledAfterError {
+		t.Errorf("Subscriber after failed one didn't get the event")
+	}
+}
@@ -0,0 +1,129 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+const (
+	// HorizonDays is the period, for which the probability of a discount is estimated
+	HorizonDays = 30
+	// trustedHistoryDays is the length of own history, after which publisher's patterns aren't taken into account
+	trustedHistoryDays = 365.0
+)
+
+// Forecast is an estimate of a discount on the game in the next HorizonDays days
+type Forecast struct {
+	Probability     float64
+	DiscountPercent int
+	SalesObserved   int
+}
+
+// history is a summary of price records: discounts of every observed sale start
+// and the number of days the games were observed in total
+type history struct {
+	discounts    []int
+	observedDays float64
+}
+
+// Estimate computes the forecast for the game from price records of its own offers and of all
+// offers of its publisher. Sales are treated as a Poisson process, whose rate is the game's own rate
+// blended with the publisher's one depending on how long the game has been observed.
+// Discount depth is the median of the game's own discounts, or the publisher's if the game was never on sale.
+// Returns nil if there is no history at all.
+func Estimate(game *model.Game, gameRecords []*model.PriceRecord, publisherRecords []*model.PriceRecord, now time.Time) *Forecast {
+	gameHistory := summarize(gameRecords, now, func(gameID uint64) bool {
+		return gameID == game.ID
+	})
+	publisherHistory := summarize(publisherRecords, now, func(gameID uint64) bool {
+		return gameID != game.ID
+	})
+
+	if gameHistory.observedDays == 0 && publisherHistory.observedDays == 0 {
+		return nil
+	}
+
+	weight := math.Min(1, gameHistory.observedDays/trustedHistoryDays)
+	if publisherHistory.observedDays == 0 {
+		weight = 1
+	}
+
+	rate := 0.0
+	if gameHistory.observedDays > 0 {
+		rate += weight * float64(len(gameHistory.discounts)) / gameHistory.observedDays
+	}
+	if publisherHistory.observedDays > 0 {
+		rate += (1 - weight) * float64(len(publisherHistory.discounts)) / publisherHistory.observedDays
+	}
+
+	discountPercent := median(gameHistory.discounts)
+	if len(gameHistory.discounts) == 0 {
+		discountPercent = median(publisherHistory.discounts)
+	}
+
+	return &Forecast{
+		Probability:     1 - math.Exp(-rate*HorizonDays),
+		DiscountPercent: discountPercent,
+		SalesObserved:   len(gameHistory.discounts),
+	}
+}
+
+// summarize finds sale starts, when the discount of an offer rises from zero, and sums up observation
+// periods of the games, from the first record of any of the game's offers until now.
+// Records must be ordered by time, include decides which games are taken into account.
+func summarize(records []*model.PriceRecord, now time.Time, include func(gameID uint64) bool) history {
+	result := history{
+		discounts: []int{},
+	}
+
+	lastDiscounts := make(map[uint64]int)
+	firstSeen := make(map[uint64]time.Time)
+
+	for _, record := range records {
+		if record.GameMarketPrice == nil || record.GameMarketPrice.Game == nil {
+			continue
+		}
+
+		gameID := record.GameMarketPrice.Game.ID
+		if !include(gameID) {
+			continue
+		}
+
+		if seenAt, ok := firstSeen[gameID]; !ok || record.RecordedAt.Before(seenAt) {
+			firstSeen[gameID] = record.RecordedAt
+		}
+
+		offerID := record.GameMarketPrice.ID
+		if record.DiscountPercent > 0 && lastDiscounts[offerID] == 0 {
+			result.discounts = append(result.discounts, record.DiscountPercent)
+		}
+		lastDiscounts[offerID] = record.DiscountPercent
+	}
+
+	for _, seenAt := range firstSeen {
+		if days := now.Sub(seenAt).Hours() / 24; days > 0 {
+			result.observedDays += days
+		}
+	}
+
+	return result
+}
+
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+
+	return int(math.Round(float64(sorted[middle-1]+sorted[middle]) / 2))
+}
@@ -0,0 +1,110 @@
+package forecast_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/forecast"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+var now = time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// seedHistory makes records of a single offer, that was seen daysAgo days ago and then went on sale
+// with given discounts at equal intervals, every sale lasting a week
+func seedHistory(game *model.Game, offerID uint64, daysAgo int, discounts []int) []*model.PriceRecord {
+	offer := &model.GameMarketPrice{ID: offerID, Game: game}
+	start := now.AddDate(0, 0, -daysAgo)
+
+	records := []*model.PriceRecord{
+		{FinalValue: 100000, RecordedAt: start, GameMarketPrice: offer},
+	}
+
+	for i, discount := range discounts {
+		saleStart := start.AddDate(0, 0, (i+1)*daysAgo/(len(discounts)+1))
+		records = append(records,
+			&model.PriceRecord{
+				FinalValue:      100000 * (100 - discount) / 100,
+				DiscountPercent: discount,
+				RecordedAt:      saleStart,
+				GameMarketPrice: offer,
+			},
+			&model.PriceRecord{
+				FinalValue:      100000,
+				RecordedAt:      saleStart.AddDate(0, 0, 7),
+				GameMarketPrice: offer,
+			},
+		)
+	}
+
+	return records
+}
+
+func TestEstimateOwnHistory(t *testing.T) {
+	game := &model.Game{ID: 1}
+
+	// 12 sales during 2 years, 30 days rate is 0.5
+	records := seedHistory(game, 1, 730, []int{10, 20, 30, 40, 50, 50, 50, 50, 60, 70, 80, 90})
+
+	result := forecast.Estimate(game, records, records, now)
+	if result == nil {
+		t.Errorf("No forecast for game with history")
+		return
+	}
+
+	want := 1 - math.Exp(-12.0/730*forecast.HorizonDays)
+	if math.Abs(result.Probability-want) > 0.001 {
+		t.Errorf("Wrong probability:\n\tWanted: %.3f, Got: %.3f", want, result.Probability)
+	}
+	if result.DiscountPercent != 50 {
+		t.Errorf("Wrong discount depth:\n\tWanted: %d, Got: %d", 50, result.DiscountPercent)
+	}
+	if result.SalesObserved != 12 {
+		t.Errorf("Wrong number of observed sales:\n\tWanted: %d, Got: %d", 12, result.SalesObserved)
+	}
+}
+
+func TestEstimatePublisherHistory(t *testing.T) {
+	game := &model.Game{ID: 1}
+	otherGame := &model.Game{ID: 2}
+
+	// The game was found just now, so only the publisher's other game is taken into account
+	gameRecords := []*model.PriceRecord{
+		{FinalValue: 100000, RecordedAt: now, GameMarketPrice: &model.GameMarketPrice{ID: 1, Game: game}},
+	}
+	publisherRecords := append(gameRecords, seedHistory(otherGame, 2, 365, []int{25, 75})...)
+
+	result := forecast.Estimate(game, gameRecords, publisherRecords, now)
+	if result == nil {
+		t.Errorf("No forecast for game with publisher history")
+		return
+	}
+
+	want := 1 - math.Exp(-2.0/365*forecast.HorizonDays)
+	if math.Abs(result.Probability-want) > 0.001 {
+		t.Errorf("Wrong probability:\n\tWanted: %.3f, Got: %.3f", want, result.Probability)
+	}
+	if result.DiscountPercent != 50 {
+		t.Errorf("Wrong discount depth:\n\tWanted: %d, Got: %d", 50, result.DiscountPercent)
+	}
+
+	// Half a year of own history without sales halves the publisher's influence
+	gameRecords = seedHistory(game, 1, 365/2, []int{})
+	publisherRecords = append(gameRecords, seedHistory(otherGame, 2, 365, []int{25, 75})...)
+
+	result = forecast.Estimate(game, gameRecords, publisherRecords, now)
+	weight := float64(365/2) / 365
+	want = 1 - math.Exp(-(1-weight)*2.0/365*forecast.HorizonDays)
+	if result == nil || math.Abs(result.Probability-want) > 0.001 {
+		t.Errorf("Wrong blended probability:\n\tWanted: %.3f, Got: %+v", want, result)
+	}
+}
+
+func TestEstimateWithoutHistory(t *testing.T) {
+	game := &model.Game{ID: 1}
+
+	if result := forecast.Estimate(game, []*model.PriceRecord{}, []*model.PriceRecord{}, now); result != nil {
+		t.Errorf("Forecast without history:\n\tWanted: nil, Got: %+v", result)
+	}
+}
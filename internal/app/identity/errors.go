@@ -0,0 +1,16 @@
+package identity
+
+import "github.com/pkg/errors"
+
+var (
+	ErrUnknownProvider  = errors.New("Unknown identity provider")
+	ErrProviderResponse = errors.New("Identity provider answered with error")
+	ErrAssertionInvalid = errors.New("Identity provider assertion is invalid")
+	ErrInternal         = errors.New("Internal error")
+)
+
+const (
+	errIdentityMessageFormat = "Identity %s error"
+	errRequestMessage        = "Couldn't request identity provider"
+	errDecodeMessage         = "Couldn't decode identity provider response"
+)
@@ -0,0 +1,91 @@
+package identity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Identity is the user as an external provider knows them
+type Identity struct {
+	Provider string
+	Subject  string
+	// Email is empty if the provider doesn't share it or hasn't verified it
+	Email string
+	// Name is a hint for the username, it isn't unique
+	Name string
+}
+
+// Provider lets users log in with an external account. The user is sent to AuthURL
+// and comes back to redirectURI with parameters, that Exchange turns into Identity.
+// Exchange gets the state the flow was started with, after it has been checked and consumed.
+type Provider interface {
+	Name() string
+	AuthURL(state string, nonce string, redirectURI string) (string, error)
+	Exchange(params url.Values, state string, nonce string, redirectURI string) (*Identity, error)
+}
+
+// NonceStore remembers nonces of provider answers for ttl, UseNonce returns false for the nonce seen before
+type NonceStore interface {
+	UseNonce(nonce string, ttl time.Duration) (bool, error)
+}
+
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{
+		providers: map[string]Provider{},
+	}
+
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+
+	return registry
+}
+
+func (registry *Registry) Provider(name string) (Provider, error) {
+	methodName := "Provider"
+	errWrapMessage := fmt.Sprintf(errIdentityMessageFormat, methodName)
+
+	provider, ok := registry.providers[name]
+	if !ok {
+		errWrapped := errors.Wrap(ErrUnknownProvider, fmt.Sprintf("Provider = %q", name))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return provider, nil
+}
+
+func (registry *Registry) Names() []string {
+	names := make([]string, 0, len(registry.providers))
+	for name := range registry.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewNonce returns random value to bind the provider's answer to the login attempt
+func NewNonce() (string, error) {
+	methodName := "NewNonce"
+	errWrapMessage := fmt.Sprintf(errIdentityMessageFormat, methodName)
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	return hex.EncodeToString(nonce), nil
+}
@@ -0,0 +1,323 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+type OIDCConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// Scopes default to "openid email profile"
+	Scopes []string
+}
+
+// OIDCProvider is OpenID Connect authorization code flow with the endpoints taken from discovery document
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcJSONWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func NewOIDCProvider(config OIDCConfig, client *http.Client) *OIDCProvider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &OIDCProvider{
+		config: config,
+		client: client,
+	}
+}
+
+func (oidcProvider *OIDCProvider) Name() string {
+	return oidcProvider.config.Name
+}
+
+func (oidcProvider *OIDCProvider) AuthURL(state string, nonce string, redirectURI string) (string, error) {
+	methodName := "OIDCAuthURL"
+	errWrapMessage := fmt.Sprintf(errIdentityMessageFormat, methodName)
+
+	discovery, err := oidcProvider.discover()
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return "", errWrapped
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", oidcProvider.config.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("scope", strings.Join(oidcProvider.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+
+	return discovery.AuthorizationEndpoint + "?" + query.Encode(), nil
+}
+
+func (oidcProvider *OIDCProvider) Exchange(params url.Values, state string, nonce string, redirectURI string) (*Identity, error) {
+	methodName := "OIDCExchange"
+	errWrapMessage := fmt.Sprintf(errIdentityMessageFormat, methodName)
+
+	if providerError := params.Get("error"); providerError != "" {
+		errWrapped := errors.Wrap(ErrProviderResponse, providerError)
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	code := params.Get("code")
+	if code == "" {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Code is empty")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	discovery, err := oidcProvider.discover()
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", oidcProvider.config.ClientID)
+	form.Set("client_secret", oidcProvider.config.ClientSecret)
+
+	resp, err := oidcProvider.client.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRequestMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errWrapped := errors.Wrap(ErrProviderResponse, fmt.Sprintf("Token endpoint answered with status %d", resp.StatusCode))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	tokenResponse := struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil || tokenResponse.IDToken == "" {
+		errWrapped := errors.Wrap(ErrProviderResponse, errDecodeMessage)
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	identity, err := oidcProvider.verifyIDToken(discovery, tokenResponse.IDToken, nonce)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return identity, nil
+}
+
+func (oidcProvider *OIDCProvider) verifyIDToken(discovery *oidcDiscovery, idToken string, nonce string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+
+	token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, errors.New("Unexpected signing method")
+		}
+
+		keyID, _ := token.Header["kid"].(string)
+		return oidcProvider.publicKey(discovery, keyID)
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.Wrap(ErrAssertionInvalid, fmt.Sprintf("ID token: %v", err))
+	}
+
+	if !claims.VerifyIssuer(discovery.Issuer, true) {
+		return nil, errors.Wrap(ErrAssertionInvalid, "Wrong issuer")
+	}
+	if !claims.VerifyAudience(oidcProvider.config.ClientID, true) {
+		return nil, errors.Wrap(ErrAssertionInvalid, "Wrong audience")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.Wrap(ErrAssertionInvalid, "Expiration is missing")
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.Wrap(ErrAssertionInvalid, "Wrong nonce")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.Wrap(ErrAssertionInvalid, "Subject is empty")
+	}
+
+	identity := &Identity{
+		Provider: oidcProvider.config.Name,
+		Subject:  subject,
+	}
+
+	// Some providers send email_verified as string
+	emailVerified := claims["email_verified"] == true || claims["email_verified"] == "true"
+	if email, _ := claims["email"].(string); email != "" && emailVerified {
+		identity.Email = email
+	}
+
+	if name, _ := claims["preferred_username"].(string); name != "" {
+		identity.Name = name
+	} else {
+		identity.Name, _ = claims["name"].(string)
+	}
+
+	return identity, nil
+}
+
+func (oidcProvider *OIDCProvider) discover() (*oidcDiscovery, error) {
+	oidcProvider.mu.Lock()
+	defer oidcProvider.mu.Unlock()
+
+	if oidcProvider.discovery != nil {
+		return oidcProvider.discovery, nil
+	}
+
+	issuerURL := strings.TrimRight(oidcProvider.config.IssuerURL, "/")
+	discovery := &oidcDiscovery{}
+	if err := oidcProvider.getJSON(issuerURL+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimRight(discovery.Issuer, "/") != issuerURL {
+		return nil, errors.Wrap(ErrProviderResponse, fmt.Sprintf("Discovery issuer %q doesn't match %q", discovery.Issuer, issuerURL))
+	}
+
+	oidcProvider.discovery = discovery
+
+	return discovery, nil
+}
+
+// publicKey fetches the provider's keys again when the key isn't known, so rotation works
+func (oidcProvider *OIDCProvider) publicKey(discovery *oidcDiscovery, keyID string) (crypto.PublicKey, error) {
+	oidcProvider.mu.Lock()
+	defer oidcProvider.mu.Unlock()
+
+	if key, ok := oidcProvider.keys[keyID]; ok {
+		return key, nil
+	}
+
+	jwks := struct {
+		Keys []oidcJSONWebKey `json:"keys"`
+	}{}
+	if err := oidcProvider.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jsonWebKey := range jwks.Keys {
+		if key, err := jsonWebKey.publicKey(); err == nil {
+			keys[jsonWebKey.KeyID] = key
+		}
+	}
+	oidcProvider.keys = keys
+
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, errors.Wrap(ErrAssertionInvalid, fmt.Sprintf("Unknown key %q", keyID))
+	}
+
+	return key, nil
+}
+
+func (oidcProvider *OIDCProvider) getJSON(address string, target interface{}) error {
+	resp, err := oidcProvider.client.Get(address)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRequestMessage)
+		return errors.Wrap(errWrapped, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrap(ErrProviderResponse, fmt.Sprintf("%s answered with status %d", address, resp.StatusCode))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		errWrapped := errors.Wrap(ErrProviderResponse, errDecodeMessage)
+		return errors.Wrap(errWrapped, err.Error())
+	}
+
+	return nil
+}
+
+func (jsonWebKey oidcJSONWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jsonWebKey.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jsonWebKey.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jsonWebKey.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if jsonWebKey.Curve != "P-256" {
+			return nil, errors.New("Unsupported curve")
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jsonWebKey.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jsonWebKey.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, errors.New("Unsupported key type")
+	}
+}
@@ -0,0 +1,210 @@
+package identity
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SteamProviderName    = "steam"
+	steamDefaultEndpoint = "https://steamcommunity.com/openid/login"
+	openIDNamespace      = "http://specs.openid.net/auth/2.0"
+	openIDIdentifier     = "http://specs.openid.net/auth/2.0/identifier_select"
+	// Assertions with nonces older or further in the future than this are rejected,
+	// so used nonces have to be remembered only for twice as long
+	steamNonceMaxAge     = time.Minute * 5
+	steamNonceTimeLayout = "2006-01-02T15:04:05Z"
+)
+
+// Claimed ID ends with SteamID64 of the user
+var steamClaimedIDRegexp = regexp.MustCompile(`^https?://[^/]+/openid/id/([0-9]{1,20})$`)
+
+type SteamConfig struct {
+	// Endpoint defaults to Steam's OpenID endpoint, it is changed in tests
+	Endpoint string
+	// Realm is the site Steam asks the user to trust, usually the frontend's base URL
+	Realm string
+}
+
+// SteamProvider is OpenID 2.0 login Steam supports. It gives only SteamID, without email or name.
+type SteamProvider struct {
+	config SteamConfig
+	client *http.Client
+	nonces NonceStore
+}
+
+func NewSteamProvider(config SteamConfig, client *http.Client, nonces NonceStore) *SteamProvider {
+	if config.Endpoint == "" {
+		config.Endpoint = steamDefaultEndpoint
+	}
+
+	return &SteamProvider{
+		config: config,
+		client: client,
+		nonces: nonces,
+	}
+}
+
+func (steamProvider *SteamProvider) Name() string {
+	return SteamProviderName
+}
+
+// AuthURL puts state into return_to, OpenID 2.0 has no separate parameter for it, nonce isn't used
+func (steamProvider *SteamProvider) AuthURL(state string, nonce string, redirectURI string) (string, error) {
+	methodName := "SteamAuthURL"
+	errWrapMessage := fmt.Sprintf(errIdentityMessageFormat, methodName)
+
+	returnTo, err := steamReturnTo(redirectURI, state)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	realm := steamProvider.config.Realm
+	if realm == "" {
+		realm = returnTo.Scheme + "://" + returnTo.Host
+	}
+
+	query := url.Values{}
+	query.Set("openid.ns", openIDNamespace)
+	query.Set("openid.mode", "checkid_setup")
+	query.Set("openid.return_to", returnTo.String())
+	query.Set("openid.realm", realm)
+	query.Set("openid.identity", openIDIdentifier)
+	query.Set("openid.claimed_id", openIDIdentifier)
+
+	return steamProvider.config.Endpoint + "?" + query.Encode(), nil
+}
+
+// steamReturnTo is redirectURI with the state of the flow, it is the only place OpenID 2.0 can keep the state in
+func steamReturnTo(redirectURI string, state string) (*url.URL, error) {
+	returnTo, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	returnToQuery := returnTo.Query()
+	returnToQuery.Set("state", state)
+	returnTo.RawQuery = returnToQuery.Encode()
+
+	return returnTo, nil
+}
+
+// Exchange asks Steam to confirm the assertion, so signatures don't have to be checked here.
+// Assertion must be made for the flow with this state, and its nonce must not have been seen before,
+// so a captured assertion can't be replayed.
+func (steamProvider *SteamProvider) Exchange(params url.Values, state string, nonce string, redirectURI string) (*Identity, error) {
+	methodName := "SteamExchange"
+	errWrapMessage := fmt.Sprintf(errIdentityMessageFormat, methodName)
+
+	if mode := params.Get("openid.mode"); mode != "id_res" {
+		errWrapped := errors.Wrap(ErrProviderResponse, fmt.Sprintf("openid.mode = %q", mode))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	returnToWanted, err := steamReturnTo(redirectURI, state)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	// Assertion made for another site or another flow must not be accepted here
+	if params.Get("openid.return_to") != returnToWanted.String() {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Wrong return_to")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	// Nonce starts with the time Steam made the assertion at
+	responseNonce := params.Get("openid.response_nonce")
+	if len(responseNonce) < len(steamNonceTimeLayout) {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Wrong response_nonce")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+	nonceTime, err := time.Parse(steamNonceTimeLayout, responseNonce[:len(steamNonceTimeLayout)])
+	if err != nil || time.Since(nonceTime) > steamNonceMaxAge || time.Until(nonceTime) > steamNonceMaxAge {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Wrong or expired response_nonce")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	if params.Get("openid.op_endpoint") != steamProvider.config.Endpoint {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Wrong op_endpoint")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	claimedIDMatch := steamClaimedIDRegexp.FindStringSubmatch(params.Get("openid.claimed_id"))
+	if claimedIDMatch == nil {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Wrong claimed_id")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	checkParams := url.Values{}
+	for key, values := range params {
+		if strings.HasPrefix(key, "openid.") {
+			checkParams[key] = values
+		}
+	}
+	checkParams.Set("openid.mode", "check_authentication")
+
+	resp, err := steamProvider.client.PostForm(steamProvider.config.Endpoint, checkParams)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRequestMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		errWrapped := errors.Wrap(ErrProviderResponse, fmt.Sprintf("Check answered with status %d", resp.StatusCode))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	// Answer is in key-value form: "key:value" lines
+	isValid := false
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.TrimSpace(line) == "is_valid:true" {
+			isValid = true
+		}
+	}
+
+	if !isValid {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Steam didn't confirm the assertion")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	// Nonce is remembered only after Steam confirmed it, so forged assertions can't use up real nonces
+	nonceUnused, err := steamProvider.nonces.UseNonce(SteamProviderName+":"+responseNonce, 2*steamNonceMaxAge)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+	if !nonceUnused {
+		errWrapped := errors.Wrap(ErrAssertionInvalid, "Assertion has already been used")
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return &Identity{
+		Provider: SteamProviderName,
+		Subject:  claimedIDMatch[1],
+	}, nil
+}
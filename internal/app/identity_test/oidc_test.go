@@ -0,0 +1,266 @@
+package identity_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/identity"
+)
+
+const (
+	testClientID     = "test-client"
+	testClientSecret = "test-secret"
+	testRedirectURI  = "http://localhost:3000/auth/mock/callback"
+	testCode         = "test-code"
+)
+
+// mockOIDC is a local OpenID Connect provider, that answers the code with ID token made by claims
+type mockOIDC struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	keyID  string
+	claims func(issuer string) jwt.MapClaims
+	// signingKey replaces the published key to sign ID tokens
+	signingKey *rsa.PrivateKey
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Couldn't generate key:\n\t%s", err.Error())
+	}
+
+	mock := &mockOIDC{
+		key:   key,
+		keyID: "mock-key",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(writer http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(writer).Encode(map[string]string{
+			"issuer":                 mock.server.URL,
+			"authorization_endpoint": mock.server.URL + "/authorize",
+			"token_endpoint":         mock.server.URL + "/token",
+			"jwks_uri":               mock.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(writer http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(writer).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": mock.keyID,
+				"n":   base64.RawURLEncoding.EncodeToString(mock.key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(mock.key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(writer http.ResponseWriter, req *http.Request) {
+		if req.PostFormValue("code") != testCode ||
+			req.PostFormValue("client_id") != testClientID ||
+			req.PostFormValue("client_secret") != testClientSecret ||
+			req.PostFormValue("redirect_uri") != testRedirectURI {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, mock.claims(mock.server.URL))
+		token.Header["kid"] = mock.keyID
+		signingKey := mock.key
+		if mock.signingKey != nil {
+			signingKey = mock.signingKey
+		}
+		idToken, err := token.SignedString(signingKey)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(writer).Encode(map[string]string{
+			"access_token": "mock-access-token",
+			"id_token":     idToken,
+		})
+	})
+
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+
+	return mock
+}
+
+func (mock *mockOIDC) provider() *identity.OIDCProvider {
+	return identity.NewOIDCProvider(identity.OIDCConfig{
+		Name:         "mock",
+		IssuerURL:    mock.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+	}, mock.server.Client())
+}
+
+func validClaims(nonce string) func(issuer string) jwt.MapClaims {
+	return func(issuer string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":                issuer,
+			"aud":                testClientID,
+			"sub":                "mock-subject",
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"nonce":              nonce,
+			"email":              "user@example.com",
+			"email_verified":     true,
+			"preferred_username": "mock_user",
+		}
+	}
+}
+
+func TestOIDCAuthURL(t *testing.T) {
+	mock := newMockOIDC(t)
+
+	authURL, err := mock.provider().AuthURL("test-state", "test-nonce", testRedirectURI)
+	if err != nil {
+		t.Fatalf("Couldn't create auth URL:\n\t%s", err.Error())
+	}
+
+	if !strings.HasPrefix(authURL, mock.server.URL+"/authorize?") {
+		t.Fatalf("Auth URL doesn't lead to authorization endpoint:\n\t%s", authURL)
+	}
+
+	parsedURL, _ := url.Parse(authURL)
+	query := parsedURL.Query()
+	wants := map[string]string{
+		"state":        "test-state",
+		"nonce":        "test-nonce",
+		"client_id":    testClientID,
+		"redirect_uri": testRedirectURI,
+	}
+	for key, want := range wants {
+		if got := query.Get(key); got != want {
+			t.Errorf("Wrong %s in auth URL:\n\tWanted: %s, Got: %s", key, want, got)
+		}
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	mock := newMockOIDC(t)
+	mock.claims = validClaims("test-nonce")
+
+	userIdentity, err := mock.provider().Exchange(url.Values{"code": {testCode}}, "test-state", "test-nonce", testRedirectURI)
+	if err != nil {
+		t.Fatalf("Couldn't exchange code:\n\t%s", err.Error())
+	}
+
+	want := identity.Identity{
+		Provider: "mock",
+		Subject:  "mock-subject",
+		Email:    "user@example.com",
+		Name:     "mock_user",
+	}
+	if *userIdentity != want {
+		t.Errorf("Wrong identity:\n\tWanted: %+v\n\tGot: %+v", want, *userIdentity)
+	}
+}
+
+func TestOIDCExchangeUnverifiedEmail(t *testing.T) {
+	mock := newMockOIDC(t)
+	mock.claims = func(issuer string) jwt.MapClaims {
+		claims := validClaims("test-nonce")(issuer)
+		claims["email_verified"] = false
+		return claims
+	}
+
+	userIdentity, err := mock.provider().Exchange(url.Values{"code": {testCode}}, "test-state", "test-nonce", testRedirectURI)
+	if err != nil {
+		t.Fatalf("Couldn't exchange code:\n\t%s", err.Error())
+	}
+
+	if userIdentity.Email != "" {
+		t.Errorf("Unverified email is returned: %s", userIdentity.Email)
+	}
+}
+
+func TestOIDCExchangeInvalid(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Couldn't generate key:\n\t%s", err.Error())
+	}
+
+	testCases := map[string]struct {
+		nonce    string
+		code     string
+		claims   func(claims jwt.MapClaims)
+		signWith *rsa.PrivateKey
+		errWant  error
+	}{
+		"wrong nonce": {
+			nonce:   "other-nonce",
+			errWant: identity.ErrAssertionInvalid,
+		},
+		"wrong audience": {
+			claims:  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+			errWant: identity.ErrAssertionInvalid,
+		},
+		"wrong issuer": {
+			claims:  func(claims jwt.MapClaims) { claims["iss"] = "http://other.example.com" },
+			errWant: identity.ErrAssertionInvalid,
+		},
+		"expired": {
+			claims:  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+			errWant: identity.ErrAssertionInvalid,
+		},
+		"bad signature": {
+			signWith: otherKey,
+			errWant:  identity.ErrAssertionInvalid,
+		},
+		"wrong code": {
+			code:    "other-code",
+			errWant: identity.ErrProviderResponse,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mock := newMockOIDC(t)
+			mock.claims = func(issuer string) jwt.MapClaims {
+				claims := validClaims("test-nonce")(issuer)
+				if testCase.claims != nil {
+					testCase.claims(claims)
+				}
+				return claims
+			}
+			mock.signingKey = testCase.signWith
+
+			nonce := testCase.nonce
+			if nonce == "" {
+				nonce = "test-nonce"
+			}
+			code := testCase.code
+			if code == "" {
+				code = testCode
+			}
+
+			_, err := mock.provider().Exchange(url.Values{"code": {code}}, "test-state", nonce, testRedirectURI)
+			if errors.Cause(err) != testCase.errWant {
+				t.Errorf("Wrong error:\n\tWanted: %v\n\tGot: %v", testCase.errWant, err)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeProviderError(t *testing.T) {
+	mock := newMockOIDC(t)
+
+	_, err := mock.provider().Exchange(url.Values{"error": {"access_denied"}}, "test-state", "test-nonce", testRedirectURI)
+	if errors.Cause(err) != identity.ErrProviderResponse {
+		t.Errorf("Wrong error:\n\tWanted: %v\n\tGot: %v", identity.ErrProviderResponse, err)
+	}
+}
@@ -0,0 +1,181 @@
+package identity_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/identity"
+)
+
+const testSteamRedirectURI = "http://localhost:3000/auth/steam/callback"
+
+// fakeNonceStore remembers nonces in memory without expiration
+type fakeNonceStore struct {
+	used map[string]bool
+}
+
+func newFakeNonceStore() *fakeNonceStore {
+	return &fakeNonceStore{used: map[string]bool{}}
+}
+
+func (nonceStore *fakeNonceStore) UseNonce(nonce string, ttl time.Duration) (bool, error) {
+	if nonceStore.used[nonce] {
+		return false, nil
+	}
+
+	nonceStore.used[nonce] = true
+
+	return true, nil
+}
+
+// newMockSteam is a local OpenID 2.0 endpoint, that confirms assertions if isValid
+func newMockSteam(t *testing.T, isValid bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.PostFormValue("openid.mode") != "check_authentication" {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprintf(writer, "ns:http://specs.openid.net/auth/2.0\nis_valid:%t\n", isValid)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func steamAssertion(endpoint string, returnTo string) url.Values {
+	return url.Values{
+		"openid.ns":             {"http://specs.openid.net/auth/2.0"},
+		"openid.mode":           {"id_res"},
+		"openid.op_endpoint":    {endpoint},
+		"openid.claimed_id":     {"https://steamcommunity.com/openid/id/76561197960287930"},
+		"openid.identity":       {"https://steamcommunity.com/openid/id/76561197960287930"},
+		"openid.return_to":      {returnTo},
+		"openid.response_nonce": {time.Now().UTC().Format("2006-01-02T15:04:05Z") + "abc"},
+		"openid.assoc_handle":   {"1234567890"},
+		"openid.signed":         {"signed,op_endpoint,claimed_id,identity,return_to,response_nonce,assoc_handle"},
+		"openid.sig":            {"c2lnbmF0dXJl"},
+	}
+}
+
+func TestSteamAuthURL(t *testing.T) {
+	server := newMockSteam(t, true)
+	provider := identity.NewSteamProvider(identity.SteamConfig{Endpoint: server.URL}, server.Client(), newFakeNonceStore())
+
+	authURL, err := provider.AuthURL("test-state", "", testSteamRedirectURI)
+	if err != nil {
+		t.Fatalf("Couldn't create auth URL:\n\t%s", err.Error())
+	}
+
+	if !strings.HasPrefix(authURL, server.URL+"?") {
+		t.Fatalf("Auth URL doesn't lead to the endpoint:\n\t%s", authURL)
+	}
+
+	parsedURL, _ := url.Parse(authURL)
+	returnTo := parsedURL.Query().Get("openid.return_to")
+	if returnTo != testSteamRedirectURI+"?state=test-state" {
+		t.Errorf("State isn't in return_to:\n\t%s", returnTo)
+	}
+}
+
+func TestSteamExchange(t *testing.T) {
+	server := newMockSteam(t, true)
+	provider := identity.NewSteamProvider(identity.SteamConfig{Endpoint: server.URL}, server.Client(), newFakeNonceStore())
+
+	params := steamAssertion(server.URL, testSteamRedirectURI+"?state=test-state")
+
+	userIdentity, err := provider.Exchange(params, "test-state", "", testSteamRedirectURI)
+	if err != nil {
+		t.Fatalf("Couldn't exchange assertion:\n\t%s", err.Error())
+	}
+
+	if userIdentity.Provider != identity.SteamProviderName || userIdentity.Subject != "76561197960287930" {
+		t.Errorf("Wrong identity: %+v", *userIdentity)
+	}
+
+	// Captured assertion must not be accepted again, even with the state of another flow
+	params.Set("openid.return_to", testSteamRedirectURI+"?state=other-state")
+	if _, err := provider.Exchange(params, "other-state", "", testSteamRedirectURI); errors.Cause(err) != identity.ErrAssertionInvalid {
+		t.Errorf("Replayed assertion was accepted:\n\tWanted: %v\n\tGot: %v", identity.ErrAssertionInvalid, err)
+	}
+}
+
+func TestSteamExchangeInvalid(t *testing.T) {
+	testCases := map[string]struct {
+		isValid bool
+		change  func(params url.Values)
+	}{
+		"not confirmed": {
+			isValid: false,
+		},
+		"wrong return_to": {
+			isValid: true,
+			change: func(params url.Values) {
+				params.Set("openid.return_to", "http://other.example.com/auth/steam/callback?state=test-state")
+			},
+		},
+		"state of another flow": {
+			isValid: true,
+			change: func(params url.Values) {
+				params.Set("openid.return_to", testSteamRedirectURI+"?state=other-state")
+			},
+		},
+		"expired nonce": {
+			isValid: true,
+			change: func(params url.Values) {
+				params.Set("openid.response_nonce", "2024-01-01T00:00:00Zabc")
+			},
+		},
+		"wrong op_endpoint": {
+			isValid: true,
+			change: func(params url.Values) {
+				params.Set("openid.op_endpoint", "http://other.example.com/openid/login")
+			},
+		},
+		"wrong claimed_id": {
+			isValid: true,
+			change: func(params url.Values) {
+				params.Set("openid.claimed_id", "https://steamcommunity.com/openid/id/not-a-number")
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := newMockSteam(t, testCase.isValid)
+			provider := identity.NewSteamProvider(identity.SteamConfig{Endpoint: server.URL}, server.Client(), newFakeNonceStore())
+
+			params := steamAssertion(server.URL, testSteamRedirectURI+"?state=test-state")
+			if testCase.change != nil {
+				testCase.change(params)
+			}
+
+			_, err := provider.Exchange(params, "test-state", "", testSteamRedirectURI)
+			if errors.Cause(err) != identity.ErrAssertionInvalid {
+				t.Errorf("Wrong error:\n\tWanted: %v\n\tGot: %v", identity.ErrAssertionInvalid, err)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := identity.NewRegistry(
+		identity.NewSteamProvider(identity.SteamConfig{}, http.DefaultClient, newFakeNonceStore()),
+		identity.NewOIDCProvider(identity.OIDCConfig{Name: "google"}, http.DefaultClient),
+	)
+
+	if names := registry.Names(); strings.Join(names, ",") != "google,steam" {
+		t.Errorf("Wrong provider names: %v", names)
+	}
+
+	if _, err := registry.Provider("github"); errors.Cause(err) != identity.ErrUnknownProvider {
+		t.Errorf("Wrong error:\n\tWanted: %v\n\tGot: %v", identity.ErrUnknownProvider, err)
+	}
+}
@@ -0,0 +1,109 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var ErrSendFailed = errors.New("Couldn't send email")
+
+const errMailerMessageFormat = "Mailer %s method %s error"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails to users
+type Sender interface {
+	Send(*Message) error
+}
+
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates sender, that uses PLAIN authentication if username is not empty
+func NewSMTPSender(host string, port int, username string, password string, from string) *SMTPSender {
+	sender := &SMTPSender{
+		addr: fmt.Sprintf("%s:%d", host, port),
+		from: from,
+	}
+
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender
+}
+
+func (sender *SMTPSender) Send(message *Message) error {
+	methodName := "Send"
+	errWrapMessage := fmt.Sprintf(errMailerMessageFormat, "SMTP", methodName)
+
+	rawMessage := strings.Join([]string{
+		"From: " + sender.from,
+		"To: " + message.To,
+		"Subject: " + message.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+		"",
+		message.Body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(sender.addr, sender.auth, sender.from, []string{message.To}, []byte(rawMessage)); err != nil {
+		return errors.Wrap(errors.Wrap(ErrSendFailed, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// FakeSender keeps sent messages in memory, it is used in tests and when SMTP isn't configured
+type FakeSender struct {
+	mutex    sync.Mutex
+	messages []*Message
+}
+
+func NewFakeSender() *FakeSender {
+	return &FakeSender{
+		messages: []*Message{},
+	}
+}
+
+func (sender *FakeSender) Send(message *Message) error {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	sender.messages = append(sender.messages, message)
+
+	return nil
+}
+
+// Messages returns copy of all sent messages in the order they were sent
+func (sender *FakeSender) Messages() []*Message {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	return append([]*Message{}, sender.messages...)
+}
+
+// LastMessageTo returns the latest message sent to the address or nil if there is none
+func (sender *FakeSender) LastMessageTo(to string) *Message {
+	sender.mutex.Lock()
+	defer sender.mutex.Unlock()
+
+	for i := len(sender.messages) - 1; i >= 0; i-- {
+		if sender.messages[i].To == to {
+			return sender.messages[i]
+		}
+	}
+
+	return nil
+}
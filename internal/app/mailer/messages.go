@@ -0,0 +1,23 @@
+package mailer
+
+import "fmt"
+
+func NewEmailVerificationMessage(to string, link string) *Message {
+	return &Message{
+		To:      to,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hello!\n\n"+
+			"To confirm your email for Price Hunter, open this link:\n%s\n\n"+
+			"If you didn't sign up, just ignore this email.\n", link),
+	}
+}
+
+func NewPasswordResetMessage(to string, link string) *Message {
+	return &Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello!\n\n"+
+			"To set a new password for Price Hunter, open this link:\n%s\n\n"+
+			"The link can be used only once. If you didn't ask to reset your password, just ignore this email.\n", link),
+	}
+}
@@ -0,0 +1,44 @@
+package mailer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/mailer"
+)
+
+func TestFakeSender(t *testing.T) {
+	var sender mailer.Sender = mailer.NewFakeSender()
+	fakeSender := sender.(*mailer.FakeSender)
+
+	link := "http://example.com/verify-email?token=abc"
+	messages := []*mailer.Message{
+		mailer.NewEmailVerificationMessage("first@example.com", link),
+		mailer.NewPasswordResetMessage("second@example.com", link),
+		mailer.NewPasswordResetMessage("first@example.com", link),
+	}
+
+	for _, message := range messages {
+		if err := sender.Send(message); err != nil {
+			t.Errorf("Couldn't send message to (%s):\n\t%s", message.To, err.Error())
+			return
+		}
+	}
+
+	if len(fakeSender.Messages()) != len(messages) {
+		t.Errorf("Wrong number of sent messages:\n\tWanted: %d, Got: %d", len(messages), len(fakeSender.Messages()))
+	}
+
+	lastMessage := fakeSender.LastMessageTo("first@example.com")
+	if lastMessage != messages[2] {
+		t.Errorf("Wrong last message:\n\tWanted: %+v, Got: %+v", messages[2], lastMessage)
+	}
+
+	if !strings.Contains(lastMessage.Body, link) {
+		t.Errorf("Message doesn't contain link:\n\tWanted: %s, Got: %s", link, lastMessage.Body)
+	}
+
+	if fakeSender.LastMessageTo("nobody@example.com") != nil {
+		t.Errorf("Found message to address, that got nothing")
+	}
+}
@@ -1,11 +1,19 @@
 package model
 
+import "time"
+
 type GameMarketPrice struct {
-	ID                    uint64  `json:"id" db:"id,omitempty"`
-	InitialValueFormatted string  `json:"initial_value_formatted" db:"initial_value_formatted"`
-	FinalValueFormatted   string  `json:"final_value_formatted" db:"final_value_formatted"`
-	DiscountPercent       int     `json:"discount_percent" db:"discount_percent"`
-	MarketGameURL         string  `json:"uri_string" db:"market_game_url"`
-	Game                  *Game   `json:"game" db:"game"`
-	Market                *Market `json:"market" db:"market"`
+	ID                    uint64     `json:"id" db:"id,omitempty"`
+	InitialValueFormatted string     `json:"initial_value_formatted" db:"initial_value_formatted"`
+	FinalValueFormatted   string     `json:"final_value_formatted" db:"final_value_formatted"`
+	FinalValue            int        `json:"final_value" db:"final_value"`
+	DiscountPercent       int        `json:"discount_percent" db:"discount_percent"`
+	SaleStartsAt          *time.Time `json:"sale_starts_at" db:"sale_starts_at"`
+	SaleEndsAt            *time.Time `json:"sale_ends_at" db:"sale_ends_at"`
+	MarketGameURL         string     `json:"uri_string" db:"market_game_url"`
+	LastSeenAt            time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	MissedSyncs           int        `json:"missed_syncs" db:"missed_syncs"`
+	Delisted              bool       `json:"delisted" db:"delisted"`
+	Game                  *Game      `json:"game" db:"game"`
+	Market                *Market    `json:"market" db:"market"`
 }
@@ -0,0 +1,9 @@
+package model
+
+// GameSimilarity is a precomputed neighbour of the game, Score is from 0 (nothing in common) to 1
+type GameSimilarity struct {
+	ID          uint64  `json:"id" db:"id,omitempty"`
+	Score       float64 `json:"score" db:"score"`
+	Game        *Game   `json:"game" db:"game"`
+	SimilarGame *Game   `json:"similar_game" db:"similar_game"`
+}
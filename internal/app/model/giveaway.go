@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Giveaway is a time-limited promotion, when a game can be claimed for free and kept forever.
+// Game is nil if the giveaway couldn't be matched with a game from the catalogue.
+// StartsAt and EndsAt are nil if the market doesn't publish them, such giveaway lasts
+// while the market sync still finds it.
+type Giveaway struct {
+	ID            uint64     `json:"id" db:"id,omitempty"`
+	Title         string     `json:"title" db:"title"`
+	MarketGameURL string     `json:"uri_string" db:"market_game_url"`
+	StartsAt      *time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at" db:"ends_at"`
+	Market        *Market    `json:"market" db:"market"`
+	Game          *Game      `json:"game" db:"game"`
+}
@@ -4,3 +4,17 @@ type Market struct {
 	ID   uint64 `json:"id" db:"id,omitempty"`
 	Name string `json:"name" db:"name"`
 }
+
+// BuiltInMarketNames are markets the prices are collected from, they are created on start
+// and looked up by these names, so they can't be renamed or deleted
+var BuiltInMarketNames = []string{"Steam", "EpicGamesStore", "GOG.com"}
+
+func (market *Market) IsBuiltIn() bool {
+	for _, name := range BuiltInMarketNames {
+		if market.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// PriceRecord is a price of a game offer, that was active since RecordedAt until the next record
+type PriceRecord struct {
+	ID              uint64           `json:"id" db:"id,omitempty"`
+	FinalValue      int              `json:"final_value" db:"final_value"`
+	DiscountPercent int              `json:"discount_percent" db:"discount_percent"`
+	RecordedAt      time.Time        `json:"recorded_at" db:"recorded_at"`
+	GameMarketPrice *GameMarketPrice `json:"game_market_price" db:"game_market_price"`
+}
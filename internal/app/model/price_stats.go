@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// PriceStats is aggregated price history of a game offer in a market.
+// Prices are stored in minor units, the same way as GameMarketPrice.FinalValue.
+// LowNDays fields are the lowest prices that were active during the last N days.
+// AveragePrice is weighted by time every price was active, so a short sale doesn't
+// weigh as much as a long period at full price.
+type PriceStats struct {
+	AllTimeLow      int              `json:"all_time_low" db:"all_time_low"`
+	AllTimeLowAt    time.Time        `json:"all_time_low_at" db:"all_time_low_at"`
+	Low30Days       int              `json:"low_30_days" db:"low_30_days"`
+	Low90Days       int              `json:"low_90_days" db:"low_90_days"`
+	Low365Days      int              `json:"low_365_days" db:"low_365_days"`
+	AveragePrice    int              `json:"average_price" db:"average_price"`
+	PriceSum        int64            `json:"price_sum" db:"price_sum"`
+	SamplesCount    int              `json:"samples_count" db:"samples_count"`
+	SalesCount      int              `json:"sales_count" db:"sales_count"`
+	FirstSeenAt     time.Time        `json:"first_seen_at" db:"first_seen_at"`
+	UpdatedAt       time.Time        `json:"updated_at" db:"updated_at"`
+	GameMarketPrice *GameMarketPrice `json:"game_market_price" db:"game_market_price"`
+}
+
+// SalesPerYear shows how often the game goes on sale. Periods shorter than a month
+// are counted as a month, so a sale right after the game was found doesn't look like 365 sales a year.
+func (stats *PriceStats) SalesPerYear(now time.Time) float64 {
+	period := now.Sub(stats.FirstSeenAt)
+	if period < 30*24*time.Hour {
+		period = 30 * 24 * time.Hour
+	}
+
+	return float64(stats.SalesCount) / (period.Hours() / (365 * 24))
+}
+
+// IsHistoricalLow reports if current price of the offer is the lowest one ever recorded.
+// Offer, whose price has never changed, isn't considered to be at historical low.
+func (stats *PriceStats) IsHistoricalLow() bool {
+	if stats.GameMarketPrice == nil || stats.GameMarketPrice.Delisted || stats.SamplesCount < 2 {
+		return false
+	}
+
+	return stats.GameMarketPrice.FinalValue <= stats.AllTimeLow
+}
@@ -0,0 +1,49 @@
+package model
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission is a right to use some part of the admin API
+type Permission string
+
+const (
+	// PermissionManageCatalogue allows editing games, publishers and tags
+	PermissionManageCatalogue Permission = "manage_catalogue"
+	// PermissionManageBlacklist allows hiding market offers, that shouldn't be fetched
+	PermissionManageBlacklist Permission = "manage_blacklist"
+	PermissionManageMarkets   Permission = "manage_markets"
+	// PermissionManageUsers allows banning users and changing their roles
+	PermissionManageUsers Permission = "manage_users"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleUser: {},
+	RoleModerator: {
+		PermissionManageCatalogue,
+		PermissionManageBlacklist,
+	},
+	RoleAdmin: {
+		PermissionManageCatalogue,
+		PermissionManageBlacklist,
+		PermissionManageMarkets,
+		PermissionManageUsers,
+	},
+}
+
+// HasPermission is always false for banned users
+func (user *User) HasPermission(permission Permission) bool {
+	if user.Banned {
+		return false
+	}
+
+	for _, rolePermission := range rolePermissions[user.Role] {
+		if rolePermission == permission {
+			return true
+		}
+	}
+
+	return false
+}
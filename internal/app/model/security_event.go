@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	// SecurityEventRefreshTokenReuse is recorded when an already rotated refresh token is presented again,
+	// the whole token family (session) is revoked then
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+)
+
+// SecurityEvent is something suspicious that happened with the user's account, shown to the user
+type SecurityEvent struct {
+	ID        uint64    `json:"id" db:"id,omitempty"`
+	Type      string    `json:"type" db:"type"`
+	SessionID string    `json:"session_id" db:"session_id"`
+	IP        string    `json:"ip" db:"ip"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	User      *User     `json:"user" db:"user"`
+}
@@ -5,14 +5,31 @@ import (
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/passwords"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordHasher hashes new passwords, it is replaced with the configured one on start
+var passwordHasher passwords.Hasher = defaultPasswordHasher()
+
+func defaultPasswordHasher() passwords.Hasher {
+	hasher, _ := passwords.NewBcryptHasher(bcrypt.DefaultCost)
+	return hasher
+}
+
+// SetPasswordHasher changes the way new passwords are hashed, existing hashes keep working
+func SetPasswordHasher(hasher passwords.Hasher) {
+	passwordHasher = hasher
+}
+
 type User struct {
 	ID                uint64 `json:"-" db:"id,omitempty"`
 	Username          string `json:"username" db:"username"`
 	Email             string `json:"email" db:"email"`
 	EncryptedPassword string `json:"-" db:"encrypted_password,omitempty"`
+	EmailVerified     bool   `json:"email_verified" db:"email_verified"`
+	Role              string `json:"role" db:"role"`
+	Banned            bool   `json:"banned" db:"banned"`
 	Password          string `json:"-"`
 }
 
@@ -33,7 +50,28 @@ func (user *User) Validate() error {
 	return nil
 }
 
+// ValidateWithoutPassword is used for users registered through external identity providers
+func (user *User) ValidateWithoutPassword() error {
+	modelName := "User"
+	methodName := "ValidateWithoutPassword"
+	errWrapMessage := fmt.Sprintf(errModelMessageFormat, modelName, methodName)
+
+	if err := validation.ValidateStruct(
+		user,
+		validation.Field(&user.Username, ValidationRulesUsername...),
+		validation.Field(&user.Email, ValidationRulesEmail...),
+	); err != nil {
+		return errors.Wrap(errors.Wrap(ErrValidationFailed, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
 func (user *User) BeforeCreate() error {
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+
 	if len(user.Password) > 0 {
 		enc, err := EncryptString(user.Password)
 		if err != nil {
@@ -51,15 +89,28 @@ func (user *User) Sanitize() {
 	user.Password = ""
 }
 
+// HasPassword is false for users registered through external identity providers,
+// they can set a password with password reset
+func (user *User) HasPassword() bool {
+	return user.EncryptedPassword != ""
+}
+
 func (user *User) ComparePassword(password string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(user.EncryptedPassword), []byte(password)) == nil
+	ok, err := passwords.Compare(user.EncryptedPassword, password)
+	return err == nil && ok
+}
+
+// PasswordNeedsRehash reports whether the password was hashed with outdated algorithm or parameters,
+// it can be rehashed only after ComparePassword succeeded, while the password is known
+func (user *User) PasswordNeedsRehash() bool {
+	return passwordHasher.NeedsRehash(user.EncryptedPassword)
 }
 
 func EncryptString(s string) (string, error) {
-	b, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.MinCost)
+	hash, err := passwordHasher.Hash(s)
 	if err != nil {
 		return "", errors.Wrap(ErrEncryptionFailed, err.Error())
 	}
 
-	return string(b), nil
+	return hash, nil
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// UserIdentity links the user with an account of an external identity provider
+type UserIdentity struct {
+	ID        uint64    `json:"id" db:"id,omitempty"`
+	Provider  string    `json:"provider" db:"provider"`
+	Subject   string    `json:"subject" db:"subject"`
+	Email     string    `json:"email" db:"email"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	User      *User     `json:"-" db:"user"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	// OwnedGameSourceManual is a game the user marked as owned themselves
+	OwnedGameSourceManual = "manual"
+	// OwnedGameSourceImported is a game found in the library of a linked account
+	OwnedGameSourceImported = "imported"
+)
+
+// UserOwnedGame is a game the user already has, so it isn't offered to them again.
+// Market is the store the game is owned in, it is nil if unknown.
+type UserOwnedGame struct {
+	ID        uint64    `json:"id" db:"id,omitempty"`
+	Source    string    `json:"source" db:"source"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	User      *User     `json:"user" db:"user"`
+	Game      *Game     `json:"game" db:"game"`
+	Market    *Market   `json:"market" db:"market"`
+}
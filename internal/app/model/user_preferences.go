@@ -0,0 +1,86 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+)
+
+// Prices are collected for this region and currency, they are the defaults for users without preferences
+const (
+	DefaultRegion   = "RU"
+	DefaultCurrency = "RUB"
+	DefaultLanguage = "en"
+)
+
+// UserPreferences tailor responses to the user. Empty EnabledMarkets means every market is enabled,
+// games with any of HiddenTags aren't shown in search, deals and recommendations.
+// Prices are collected only in DefaultRegion and DefaultCurrency, other ones are flagged in responses with prices.
+// Language is the one the client shows its interface in.
+type UserPreferences struct {
+	Region         string    `json:"region" db:"region"`
+	Currency       string    `json:"currency" db:"currency"`
+	Language       string    `json:"language" db:"language"`
+	EnabledMarkets []string  `json:"enabled_markets" db:"enabled_markets"`
+	HiddenTags     []string  `json:"hidden_tags" db:"hidden_tags"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	User           *User     `json:"user" db:"user"`
+}
+
+// NewUserPreferences returns preferences of the user, who hasn't changed anything yet
+func NewUserPreferences(user *User) *UserPreferences {
+	return &UserPreferences{
+		Region:         DefaultRegion,
+		Currency:       DefaultCurrency,
+		Language:       DefaultLanguage,
+		EnabledMarkets: []string{},
+		HiddenTags:     []string{},
+		User:           user,
+	}
+}
+
+func (userPreferences *UserPreferences) IsMarketEnabled(marketName string) bool {
+	if len(userPreferences.EnabledMarkets) == 0 {
+		return true
+	}
+
+	for _, enabledMarket := range userPreferences.EnabledMarkets {
+		if enabledMarket == marketName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsHidden tells if the game with these tags is filtered out
+func (userPreferences *UserPreferences) IsHidden(tagNames []string) bool {
+	for _, tagName := range tagNames {
+		for _, hiddenTag := range userPreferences.HiddenTags {
+			if tagName == hiddenTag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (userPreferences *UserPreferences) Validate() error {
+	modelName := "UserPreferences"
+	methodName := "Validate"
+	errWrapMessage := fmt.Sprintf(errModelMessageFormat, modelName, methodName)
+
+	if err := validation.ValidateStruct(
+		userPreferences,
+		validation.Field(&userPreferences.Region, ValidationRulesRegion...),
+		validation.Field(&userPreferences.Currency, ValidationRulesCurrency...),
+		validation.Field(&userPreferences.Language, ValidationRulesLanguage...),
+	); err != nil {
+		return errors.Wrap(errors.Wrap(ErrValidationFailed, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// UserTOTP is the second factor of the user. It is saved disabled during enrolment
+// and enabled after the user confirms the first code.
+type UserTOTP struct {
+	Secret       string    `json:"-" db:"secret"`
+	Enabled      bool      `json:"enabled" db:"enabled"`
+	LastUsedStep int64     `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	User         *User     `json:"-" db:"user"`
+}
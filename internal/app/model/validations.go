@@ -20,8 +20,44 @@ var ValidationRulesEmail = []validation.Rule{
 	is.Email,
 }
 
+var ValidationRulesRole = []validation.Rule{
+	validation.Required,
+	validation.In(RoleUser, RoleModerator, RoleAdmin),
+}
+
 var ValidationRulesUsername = []validation.Rule{
 	validation.Required,
 	validation.Length(6, 30),
 	validation.Match(regexp.MustCompile("^[a-zA-Z0-9_-]{6,30}$")),
 }
+
+var ValidationRulesWishlistName = []validation.Rule{
+	validation.Required,
+	validation.RuneLength(1, 100),
+}
+
+// Priority is from 0 (not set) to 5 (the most wanted)
+var ValidationRulesWishlistItemPriority = []validation.Rule{
+	validation.Min(0),
+	validation.Max(5),
+}
+
+var ValidationRulesWishlistItemNote = []validation.Rule{
+	validation.RuneLength(0, 1000),
+}
+
+var ValidationRulesRegion = []validation.Rule{
+	validation.Required,
+	is.CountryCode2,
+}
+
+var ValidationRulesCurrency = []validation.Rule{
+	validation.Required,
+	is.CurrencyCode,
+}
+
+// Only languages the interface is translated to
+var ValidationRulesLanguage = []validation.Rule{
+	validation.Required,
+	validation.In("en", "ru"),
+}
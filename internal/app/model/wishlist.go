@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+)
+
+// DefaultWishlistName is the name of the list favourites are kept in
+const DefaultWishlistName = "Favourites"
+
+// Wishlist is a named list of games. Every user has one default list,
+// that is created with the first favourite and can't be deleted.
+// Public list can be seen by anyone knowing PublicSlug, it is nil until the list is published first.
+type Wishlist struct {
+	ID         uint64    `json:"id" db:"id,omitempty"`
+	Name       string    `json:"name" db:"name"`
+	Position   int       `json:"position" db:"position"`
+	IsDefault  bool      `json:"is_default" db:"is_default"`
+	IsPublic   bool      `json:"is_public" db:"is_public"`
+	PublicSlug *string   `json:"public_slug" db:"public_slug"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	User       *User     `json:"user" db:"user"`
+}
+
+// WishlistItem is a game in a list. TargetPrice is in the units of GameMarketPrice.FinalValue,
+// it is nil if the user hasn't set it.
+type WishlistItem struct {
+	ID          uint64    `json:"id" db:"id,omitempty"`
+	Priority    int       `json:"priority" db:"priority"`
+	Note        string    `json:"note" db:"note"`
+	TargetPrice *int      `json:"target_price" db:"target_price"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	Wishlist    *Wishlist `json:"wishlist" db:"wishlist"`
+	Game        *Game     `json:"game" db:"game"`
+}
+
+func (wishlist *Wishlist) Validate() error {
+	modelName := "Wishlist"
+	methodName := "Validate"
+	errWrapMessage := fmt.Sprintf(errModelMessageFormat, modelName, methodName)
+
+	nameRules := []validation.Rule{}
+	nameRules = append(nameRules, ValidationRulesWishlistName...)
+	if !wishlist.IsDefault {
+		// Default list is created on first use and would conflict with another list of this name
+		nameRules = append(nameRules, validation.NotIn(DefaultWishlistName))
+	}
+
+	if err := validation.ValidateStruct(
+		wishlist,
+		validation.Field(&wishlist.Name, nameRules...),
+	); err != nil {
+		return errors.Wrap(errors.Wrap(ErrValidationFailed, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (wishlistItem *WishlistItem) Validate() error {
+	modelName := "WishlistItem"
+	methodName := "Validate"
+	errWrapMessage := fmt.Sprintf(errModelMessageFormat, modelName, methodName)
+
+	if err := validation.ValidateStruct(
+		wishlistItem,
+		validation.Field(&wishlistItem.Priority, ValidationRulesWishlistItemPriority...),
+		validation.Field(&wishlistItem.Note, ValidationRulesWishlistItemNote...),
+		validation.Field(&wishlistItem.TargetPrice, validation.Min(0)),
+	); err != nil {
+		return errors.Wrap(errors.Wrap(ErrValidationFailed, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestMarketIsBuiltIn(t *testing.T) {
+	for _, name := range model.BuiltInMarketNames {
+		if !(&model.Market{Name: name}).IsBuiltIn() {
+			t.Errorf("Market %s isn't built-in", name)
+		}
+	}
+
+	if (&model.Market{Name: "itch.io"}).IsBuiltIn() {
+		t.Error("Market added by admin is built-in")
+	}
+}
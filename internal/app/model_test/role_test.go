@@ -0,0 +1,30 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestUserHasPermission(t *testing.T) {
+	user := &model.User{Role: model.RoleUser}
+	moderator := &model.User{Role: model.RoleModerator}
+	admin := &model.User{Role: model.RoleAdmin}
+	adminBanned := &model.User{Role: model.RoleAdmin, Banned: true}
+
+	if user.HasPermission(model.PermissionManageCatalogue) {
+		t.Error("User can manage catalogue")
+	}
+	if !moderator.HasPermission(model.PermissionManageCatalogue) || !moderator.HasPermission(model.PermissionManageBlacklist) {
+		t.Error("Moderator can't manage catalogue or blacklist")
+	}
+	if moderator.HasPermission(model.PermissionManageUsers) || moderator.HasPermission(model.PermissionManageMarkets) {
+		t.Error("Moderator can manage users or markets")
+	}
+	if !admin.HasPermission(model.PermissionManageUsers) || !admin.HasPermission(model.PermissionManageMarkets) {
+		t.Error("Admin can't manage users or markets")
+	}
+	if adminBanned.HasPermission(model.PermissionManageCatalogue) {
+		t.Error("Banned admin still has permissions")
+	}
+}
@@ -0,0 +1,51 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestUserPreferencesValidate(t *testing.T) {
+	userPreferences := model.NewUserPreferences(&model.User{})
+	if err := userPreferences.Validate(); err != nil {
+		t.Errorf("Default preferences weren't accepted:\n\t%s", err.Error())
+	}
+
+	userPreferencesWrongRegion := model.NewUserPreferences(&model.User{})
+	userPreferencesWrongRegion.Region = "XX"
+	if err := userPreferencesWrongRegion.Validate(); err == nil {
+		t.Errorf("Wrong region (%s) was accepted", userPreferencesWrongRegion.Region)
+	}
+
+	userPreferencesWrongCurrency := model.NewUserPreferences(&model.User{})
+	userPreferencesWrongCurrency.Currency = "RUR"
+	if err := userPreferencesWrongCurrency.Validate(); err == nil {
+		t.Errorf("Wrong currency (%s) was accepted", userPreferencesWrongCurrency.Currency)
+	}
+
+	userPreferencesWrongLanguage := model.NewUserPreferences(&model.User{})
+	userPreferencesWrongLanguage.Language = "tlh"
+	if err := userPreferencesWrongLanguage.Validate(); err == nil {
+		t.Errorf("Unsupported language (%s) was accepted", userPreferencesWrongLanguage.Language)
+	}
+}
+
+func TestUserPreferencesFilters(t *testing.T) {
+	userPreferences := model.NewUserPreferences(&model.User{})
+	if !userPreferences.IsMarketEnabled("GOG.com") {
+		t.Error("Market is disabled by default")
+	}
+	if userPreferences.IsHidden([]string{"action"}) {
+		t.Error("Game is hidden by default")
+	}
+
+	userPreferences.EnabledMarkets = []string{"Steam"}
+	userPreferences.HiddenTags = []string{"horror"}
+	if !userPreferences.IsMarketEnabled("Steam") || userPreferences.IsMarketEnabled("GOG.com") {
+		t.Errorf("Wrong enabled markets, wanted only %v", userPreferences.EnabledMarkets)
+	}
+	if !userPreferences.IsHidden([]string{"action", "horror"}) || userPreferences.IsHidden([]string{"action"}) {
+		t.Errorf("Wrong hidden games, wanted games with %v", userPreferences.HiddenTags)
+	}
+}
@@ -66,3 +66,36 @@ func TestValidationRulesUsername(t *testing.T) {
 		t.Errorf("Correct username (%s) wasn't accepted:\n\t%s", usernameCorrect, err.Error())
 	}
 }
+
+func TestWishlistItemValidate(t *testing.T) {
+	targetPriceNegative := -1
+	wishlistItemCorrect := &model.WishlistItem{Priority: 3, Note: "On sale only"}
+	wishlistItemPriorityHigh := &model.WishlistItem{Priority: 6}
+	wishlistItemTargetPriceNegative := &model.WishlistItem{TargetPrice: &targetPriceNegative}
+
+	if err := wishlistItemCorrect.Validate(); err != nil {
+		t.Errorf("Correct wishlist item (%+v) wasn't accepted:\n\t%s", wishlistItemCorrect, err.Error())
+	}
+	if err := wishlistItemPriorityHigh.Validate(); err == nil {
+		t.Errorf("Wishlist item with high priority (%d) was accepted", wishlistItemPriorityHigh.Priority)
+	}
+	if err := wishlistItemTargetPriceNegative.Validate(); err == nil {
+		t.Errorf("Wishlist item with negative target price (%d) was accepted", targetPriceNegative)
+	}
+}
+
+func TestWishlistValidate(t *testing.T) {
+	wishlistCorrect := &model.Wishlist{Name: "Buy on sale"}
+	wishlistDefault := &model.Wishlist{Name: model.DefaultWishlistName, IsDefault: true}
+	wishlistDefaultName := &model.Wishlist{Name: model.DefaultWishlistName}
+
+	if err := wishlistCorrect.Validate(); err != nil {
+		t.Errorf("Correct wishlist (%+v) wasn't accepted:\n\t%s", wishlistCorrect, err.Error())
+	}
+	if err := wishlistDefault.Validate(); err != nil {
+		t.Errorf("Default wishlist (%+v) wasn't accepted:\n\t%s", wishlistDefault, err.Error())
+	}
+	if err := wishlistDefaultName.Validate(); err == nil {
+		t.Errorf("Not default wishlist with default name (%s) was accepted", wishlistDefaultName.Name)
+	}
+}
@@ -0,0 +1,117 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+// Hashes are encoded in PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>, salt and hash are base64 without padding
+const (
+	argon2idPrefix     = "$argon2id$"
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+type Argon2idParams struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) (*Argon2idHasher, error) {
+	methodName := "NewArgon2idHasher"
+	errWrapMessage := fmt.Sprintf(errPasswordsMessageFormat, methodName)
+
+	if params.Iterations == 0 || params.Parallelism == 0 || params.Memory < 8*uint32(params.Parallelism) {
+		errWrapped := errors.Wrap(ErrInternal, fmt.Sprintf("Wrong argon2id params: %+v", params))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return &Argon2idHasher{
+		params: params,
+	}, nil
+}
+
+func (argon2idHasher *Argon2idHasher) Hash(password string) (string, error) {
+	methodName := "Argon2idHash"
+	errWrapMessage := fmt.Sprintf(errPasswordsMessageFormat, methodName)
+
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	params := argon2idHasher.params
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argon2idKeyLength)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (argon2idHasher *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, _, key, err := decodeArgon2id(encodedHash)
+	return err != nil || params != argon2idHasher.params || len(key) != argon2idKeyLength
+}
+
+func decodeArgon2id(encodedHash string) (Argon2idParams, []byte, []byte, error) {
+	params := Argon2idParams{}
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, errors.Wrap(ErrHashDamaged, "Wrong argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.Wrap(ErrHashDamaged, "Unsupported argon2id version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errors.Wrap(ErrHashDamaged, err.Error())
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errors.Wrap(ErrHashDamaged, err.Error())
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.Wrap(ErrHashDamaged, "Wrong argon2id key")
+	}
+
+	return params, salt, key, nil
+}
+
+func compareArgon2id(encodedHash string, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
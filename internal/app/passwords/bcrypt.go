@@ -0,0 +1,69 @@
+package passwords
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	methodName := "NewBcryptHasher"
+	errWrapMessage := fmt.Sprintf(errPasswordsMessageFormat, methodName)
+
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		errWrapped := errors.Wrap(ErrInternal, fmt.Sprintf("Bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return &BcryptHasher{
+		cost: cost,
+	}, nil
+}
+
+func (bcryptHasher *BcryptHasher) Hash(password string) (string, error) {
+	methodName := "BcryptHash"
+	errWrapMessage := fmt.Sprintf(errPasswordsMessageFormat, methodName)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptHasher.cost)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	return string(hash), nil
+}
+
+func (bcryptHasher *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	if !isBcryptHash(encodedHash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost != bcryptHasher.cost
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func compareBcrypt(encodedHash string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(ErrHashDamaged, err.Error())
+	}
+
+	return true, nil
+}
@@ -0,0 +1,13 @@
+package passwords
+
+import "github.com/pkg/errors"
+
+var (
+	ErrUnknownAlgorithm = errors.New("Unknown password hashing algorithm")
+	ErrHashDamaged      = errors.New("Password hash has been damaged")
+	ErrInternal         = errors.New("Internal error")
+)
+
+const (
+	errPasswordsMessageFormat = "Passwords %s error"
+)
@@ -0,0 +1,77 @@
+package passwords
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Hasher hashes new passwords. Every hash records its algorithm and parameters,
+// so hashes made with any hasher can be checked by Compare.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether the hash was made with another algorithm or parameters
+	NeedsRehash(encodedHash string) bool
+}
+
+// Config describes the hasher new passwords are hashed with
+type Config struct {
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      uint32 // in KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+func NewHasher(config Config) (Hasher, error) {
+	methodName := "NewHasher"
+	errWrapMessage := fmt.Sprintf(errPasswordsMessageFormat, methodName)
+
+	switch config.Algorithm {
+	case AlgorithmBcrypt:
+		return NewBcryptHasher(config.BcryptCost)
+	case AlgorithmArgon2id:
+		return NewArgon2idHasher(Argon2idParams{
+			Memory:      config.Argon2Memory,
+			Iterations:  config.Argon2Iterations,
+			Parallelism: config.Argon2Parallelism,
+		})
+	default:
+		errWrapped := errors.Wrap(ErrUnknownAlgorithm, fmt.Sprintf("Algorithm = %q", config.Algorithm))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+}
+
+// Compare checks the password against hash made by any of the supported algorithms
+func Compare(encodedHash string, password string) (bool, error) {
+	methodName := "Compare"
+	errWrapMessage := fmt.Sprintf(errPasswordsMessageFormat, methodName)
+
+	var (
+		ok  bool
+		err error
+	)
+
+	switch {
+	case isBcryptHash(encodedHash):
+		ok, err = compareBcrypt(encodedHash, password)
+	case strings.HasPrefix(encodedHash, argon2idPrefix):
+		ok, err = compareArgon2id(encodedHash, password)
+	default:
+		err = ErrUnknownAlgorithm
+	}
+
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return false, errWrapped
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,116 @@
+package passwords_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/passwords"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = passwords.Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+}
+
+func TestHashers(t *testing.T) {
+	bcryptHasher, err := passwords.NewBcryptHasher(bcrypt.MinCost)
+	if err != nil {
+		t.Errorf("Couldn't create bcrypt hasher:\n\t%s", err.Error())
+		return
+	}
+	argon2idHasher, err := passwords.NewArgon2idHasher(testArgon2idParams)
+	if err != nil {
+		t.Errorf("Couldn't create argon2id hasher:\n\t%s", err.Error())
+		return
+	}
+
+	hashers := map[string]passwords.Hasher{
+		"$2a$04$":                        bcryptHasher,
+		"$argon2id$v=19$m=1024,t=1,p=1$": argon2idHasher,
+	}
+
+	for prefix, hasher := range hashers {
+		hash, err := hasher.Hash("Test_password_1")
+		if err != nil {
+			t.Errorf("Couldn't hash password:\n\t%s", err.Error())
+			continue
+		}
+
+		if !strings.HasPrefix(hash, prefix) {
+			t.Errorf("Hash doesn't record algorithm and parameters:\n\tWanted prefix: %s, Got: %s", prefix, hash)
+		}
+
+		if ok, err := passwords.Compare(hash, "Test_password_1"); err != nil || !ok {
+			t.Errorf("Correct password wasn't accepted for %s:\n\tGot: %v (%v)", hash, ok, err)
+		}
+		if ok, err := passwords.Compare(hash, "Test_password_2"); err != nil || ok {
+			t.Errorf("Wrong password was accepted for %s:\n\tGot: %v (%v)", hash, ok, err)
+		}
+
+		if hasher.NeedsRehash(hash) {
+			t.Errorf("Fresh hash needs rehash: %s", hash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptWeak, _ := passwords.NewBcryptHasher(bcrypt.MinCost)
+	bcryptStrong, _ := passwords.NewBcryptHasher(bcrypt.MinCost + 1)
+	argon2idWeak, _ := passwords.NewArgon2idHasher(testArgon2idParams)
+	argon2idStrong, _ := passwords.NewArgon2idHasher(passwords.Argon2idParams{
+		Memory:      2048,
+		Iterations:  2,
+		Parallelism: 1,
+	})
+
+	bcryptHash, _ := bcryptWeak.Hash("Test_password_1")
+	argon2idHash, _ := argon2idWeak.Hash("Test_password_1")
+
+	if !bcryptStrong.NeedsRehash(bcryptHash) {
+		t.Errorf("Bcrypt hash with lower cost doesn't need rehash")
+	}
+	if !argon2idStrong.NeedsRehash(argon2idHash) {
+		t.Errorf("Argon2id hash with weaker parameters doesn't need rehash")
+	}
+	if !argon2idWeak.NeedsRehash(bcryptHash) {
+		t.Errorf("Bcrypt hash doesn't need rehash for argon2id hasher")
+	}
+	if !bcryptWeak.NeedsRehash(argon2idHash) {
+		t.Errorf("Argon2id hash doesn't need rehash for bcrypt hasher")
+	}
+}
+
+func TestCompareDamagedHash(t *testing.T) {
+	hashes := []string{
+		"",
+		"plain_text_password",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHQ$a2V5",
+	}
+
+	for _, hash := range hashes {
+		if ok, err := passwords.Compare(hash, "Test_password_1"); err == nil || ok {
+			t.Errorf("Damaged hash %q was accepted:\n\tGot: %v (%v)", hash, ok, err)
+		}
+	}
+
+	if _, err := passwords.Compare("unknown", ""); errors.Cause(err) != passwords.ErrUnknownAlgorithm {
+		t.Errorf("Wrong error for unknown algorithm:\n\tWanted: %v, Got: %v", passwords.ErrUnknownAlgorithm, err)
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	if _, err := passwords.NewHasher(passwords.Config{Algorithm: "md5"}); errors.Cause(err) != passwords.ErrUnknownAlgorithm {
+		t.Errorf("Wrong error for unknown algorithm:\n\tWanted: %v, Got: %v", passwords.ErrUnknownAlgorithm, err)
+	}
+	if _, err := passwords.NewHasher(passwords.Config{Algorithm: passwords.AlgorithmBcrypt, BcryptCost: 100}); err == nil {
+		t.Errorf("Bcrypt hasher with wrong cost was created")
+	}
+	if _, err := passwords.NewHasher(passwords.Config{Algorithm: passwords.AlgorithmArgon2id}); err == nil {
+		t.Errorf("Argon2id hasher with zero parameters was created")
+	}
+}
@@ -0,0 +1,65 @@
+package pricestats
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+const errTrackerMessageFormat = "Price stats tracker %s error"
+
+// Tracker keeps price statistics up to date with offer changes published to the event bus,
+// so the statistics are updated incrementally instead of being recomputed after every sync.
+type Tracker struct {
+	repository store.PriceStatsRepository
+}
+
+func New(repository store.PriceStatsRepository) *Tracker {
+	return &Tracker{
+		repository: repository,
+	}
+}
+
+func (tracker *Tracker) Subscribe(bus *eventbus.Bus) {
+	bus.Subscribe(tracker.HandleEvent, eventbus.EventPriceChanged, eventbus.EventSaleStarted)
+}
+
+// HandleEvent errors are returned to the publisher, so the sync fails instead of
+// leaving statistics behind the stored offers
+func (tracker *Tracker) HandleEvent(event eventbus.Event) error {
+	methodName := "HandleEvent"
+	errWrapMessage := fmt.Sprintf(errTrackerMessageFormat, methodName)
+
+	if event.Current == nil {
+		return nil
+	}
+
+	switch event.Type {
+	case eventbus.EventPriceChanged:
+		if err := tracker.repository.AddRecord(event.Current, event.Time); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+	case eventbus.EventSaleStarted:
+		err := tracker.repository.AddSale(event.Current)
+		if err == nil {
+			return nil
+		}
+
+		if errors.Cause(err) != store.ErrNotFound {
+			return errors.Wrap(err, errWrapMessage)
+		}
+
+		// Sale started without price change, so the offer wasn't recorded yet
+		if err := tracker.repository.AddRecord(event.Current, event.Time); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+
+		if err := tracker.repository.AddSale(event.Current); err != nil {
+			return errors.Wrap(err, errWrapMessage)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package pricestats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apistore"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/eventbus"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/pricestats"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+// fakeRepository aggregates records in memory the same way as the SQL repository
+type fakeRepository struct {
+	stats map[uint64]*model.PriceStats
+}
+
+func (repository *fakeRepository) AddRecord(gameMarketPrice *model.GameMarketPrice, recordedAt time.Time) error {
+	stats, ok := repository.stats[gameMarketPrice.ID]
+	if !ok {
+		stats = &model.PriceStats{
+			AllTimeLow:   gameMarketPrice.FinalValue,
+			AllTimeLowAt: recordedAt,
+			FirstSeenAt:  recordedAt,
+		}
+		repository.stats[gameMarketPrice.ID] = stats
+	}
+
+	if gameMarketPrice.FinalValue < stats.AllTimeLow {
+		stats.AllTimeLow = gameMarketPrice.FinalValue
+		stats.AllTimeLowAt = recordedAt
+	}
+	stats.PriceSum += int64(gameMarketPrice.FinalValue)
+	stats.SamplesCount += 1
+	stats.UpdatedAt = recordedAt
+	stats.GameMarketPrice = gameMarketPrice
+
+	return nil
+}
+
+func (repository *fakeRepository) AddSale(gameMarketPrice *model.GameMarketPrice) error {
+	stats, ok := repository.stats[gameMarketPrice.ID]
+	if !ok {
+		return errors.Wrap(store.ErrNotFound, "fake repository")
+	}
+
+	stats.SalesCount += 1
+
+	return nil
+}
+
+func (repository *fakeRepository) FindByGameMarketPrice(gameMarketPrice *model.GameMarketPrice, now time.Time) (*model.PriceStats, error) {
+	stats, ok := repository.stats[gameMarketPrice.ID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return stats, nil
+}
+
+func (repository *fakeRepository) FindAllByGame(game *model.Game, now time.Time) ([]*model.PriceStats, error) {
+	return []*model.PriceStats{}, nil
+}
+
+func (repository *fakeRepository) FindAllByGames(games []*model.Game, now time.Time) ([]*model.PriceStats, error) {
+	return []*model.PriceStats{}, nil
+}
+
+func (repository *fakeRepository) FindAllRecordsByGame(game *model.Game) ([]*model.PriceRecord, error) {
+	return []*model.PriceRecord{}, nil
+}
+
+func (repository *fakeRepository) FindAllRecordsByPublisher(publisher *model.Publisher) ([]*model.PriceRecord, error) {
+	return []*model.PriceRecord{}, nil
+}
+
+func TestTracker(t *testing.T) {
+	repository := &fakeRepository{stats: make(map[uint64]*model.PriceStats)}
+	bus := eventbus.New()
+	pricestats.New(repository).Subscribe(bus)
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	offers := []*model.GameMarketPrice{
+		{ID: 1, FinalValueFormatted: "1000 руб.", FinalValue: 100000},
+		{ID: 1, FinalValueFormatted: "600 руб.", FinalValue: 60000, DiscountPercent: 40},
+		{ID: 1, FinalValueFormatted: "1000 руб.", FinalValue: 100000},
+		{ID: 1, FinalValueFormatted: "500 руб.", FinalValue: 50000, DiscountPercent: 50},
+	}
+
+	var previous *model.GameMarketPrice
+	for i, offer := range offers {
+		for _, eventType := range apistore.DetectPriceChanges(previous, offer) {
+			if err := bus.Publish(eventbus.Event{
+				Type:     eventType,
+				Previous: previous,
+				Current:  offer,
+				Time:     start.AddDate(0, i, 0),
+			}); err != nil {
+				t.Errorf("Couldn't handle %s event:\n\t%s", eventType, err.Error())
+				return
+			}
+		}
+		previous = offer
+	}
+
+	stats, err := repository.FindByGameMarketPrice(offers[0], start)
+	if err != nil {
+		t.Errorf("Stats weren't created:\n\t%s", err.Error())
+		return
+	}
+
+	if stats.AllTimeLow != 50000 || !stats.AllTimeLowAt.Equal(start.AddDate(0, 3, 0)) {
+		t.Errorf("Wrong all-time low:\n\tWanted: %d at %v, Got: %d at %v", 50000, start.AddDate(0, 3, 0), stats.AllTimeLow, stats.AllTimeLowAt)
+	}
+	if stats.SamplesCount != len(offers) || stats.PriceSum != 310000 {
+		t.Errorf("Wrong price samples:\n\tWanted: sum %d of %d samples, Got: sum %d of %d samples", 310000, len(offers), stats.PriceSum, stats.SamplesCount)
+	}
+	if stats.SalesCount != 2 {
+		t.Errorf("Wrong sales count:\n\tWanted: %d, Got: %d", 2, stats.SalesCount)
+	}
+	if !stats.IsHistoricalLow() {
+		t.Errorf("Current price isn't historical low:\n\tGot: %+v", stats)
+	}
+}
+
+func TestTrackerSaleWithoutRecord(t *testing.T) {
+	repository := &fakeRepository{stats: make(map[uint64]*model.PriceStats)}
+	tracker := pricestats.New(repository)
+
+	offer := &model.GameMarketPrice{ID: 2, FinalValue: 30000, DiscountPercent: 25}
+	if err := tracker.HandleEvent(eventbus.Event{Type: eventbus.EventSaleStarted, Current: offer, Time: time.Now()}); err != nil {
+		t.Errorf("Couldn't handle sale start:\n\t%s", err.Error())
+		return
+	}
+
+	stats, err := repository.FindByGameMarketPrice(offer, time.Now())
+	if err != nil {
+		t.Errorf("Stats weren't created:\n\t%s", err.Error())
+		return
+	}
+	if stats.SamplesCount != 1 || stats.SalesCount != 1 {
+		t.Errorf("Wrong stats after sale start:\n\tWanted: 1 sample and 1 sale, Got: %d samples and %d sales", stats.SamplesCount, stats.SalesCount)
+	}
+}
+
+func TestPriceStatsSalesPerYear(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		stats *model.PriceStats
+		want  float64
+	}{
+		{stats: &model.PriceStats{SalesCount: 4, FirstSeenAt: now.AddDate(-2, 0, 0)}, want: 2},
+		{stats: &model.PriceStats{SalesCount: 0, FirstSeenAt: now.AddDate(-1, 0, 0)}, want: 0},
+		{stats: &model.PriceStats{SalesCount: 1, FirstSeenAt: now}, want: 365.0 / 30},
+	}
+
+	for _, testCase := range testCases {
+		got := testCase.stats.SalesPerYear(now)
+		if got < testCase.want-0.01 || got > testCase.want+0.01 {
+			t.Errorf("Wrong sales per year:\n\tWanted: %v, Got: %v", testCase.want, got)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package ratelimit
+
+import "github.com/pkg/errors"
+
+var (
+	ErrInternal = errors.New("Internal error")
+)
+
+const (
+	errRateLimitMessageFormat = "RateLimit %s error"
+	errRedisMessage           = "Something wrong in redis"
+)
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// Rule allows Limit hits of a key during Window, counted in fixed windows
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Lockout blocks a key after Threshold failures in a row.
+// Every next failure doubles the lock, starting from BaseDuration up to MaxDuration.
+// Failures are forgotten after ResetAfter without new ones.
+type Lockout struct {
+	Threshold    int
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	ResetAfter   time.Duration
+}
+
+// Duration returns how long the key is locked after the given count of failures
+func (lockout Lockout) Duration(failures int) time.Duration {
+	if lockout.Threshold <= 0 || failures < lockout.Threshold {
+		return 0
+	}
+
+	duration := lockout.BaseDuration
+	for i := lockout.Threshold; i < failures && duration < lockout.MaxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > lockout.MaxDuration {
+		return lockout.MaxDuration
+	}
+
+	return duration
+}
+
+type Limiter struct {
+	client *redis.Client
+}
+
+func New(client *redis.Client) *Limiter {
+	return &Limiter{
+		client: client,
+	}
+}
+
+func hitsKey(key string) string {
+	return "rate_limit:hits:" + key
+}
+
+func failuresKey(key string) string {
+	return "rate_limit:failures:" + key
+}
+
+func lockKey(key string) string {
+	return "rate_limit:lock:" + key
+}
+
+// Allow counts a hit of the key. If the rule is exceeded, returns how long to wait before the next try.
+func (limiter *Limiter) Allow(key string, rule Rule) (time.Duration, error) {
+	methodName := "Allow"
+	errWrapMessage := fmt.Sprintf(errRateLimitMessageFormat, methodName)
+
+	var hits *redis.IntCmd
+	var ttl *redis.DurationCmd
+	if _, err := limiter.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		// Window starts with the first hit, later hits don't prolong it
+		pipe.SetNX(hitsKey(key), 0, rule.Window)
+		hits = pipe.Incr(hitsKey(key))
+		ttl = pipe.PTTL(hitsKey(key))
+		return nil
+	}); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return 0, errWrapped
+	}
+
+	if hits.Val() <= int64(rule.Limit) {
+		return 0, nil
+	}
+
+	return positiveDuration(ttl.Val(), rule.Window), nil
+}
+
+// LockedFor returns how long the key stays locked, zero if it isn't
+func (limiter *Limiter) LockedFor(key string) (time.Duration, error) {
+	methodName := "LockedFor"
+	errWrapMessage := fmt.Sprintf(errRateLimitMessageFormat, methodName)
+
+	ttl, err := limiter.client.PTTL(lockKey(key)).Result()
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return 0, errWrapped
+	}
+
+	// PTTL is negative if the key doesn't exist
+	if ttl <= 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// RegisterFailure counts a failure for the key and locks it according to lockout.
+// Returns the lock duration, zero if the key isn't locked yet.
+func (limiter *Limiter) RegisterFailure(key string, lockout Lockout) (time.Duration, error) {
+	methodName := "RegisterFailure"
+	errWrapMessage := fmt.Sprintf(errRateLimitMessageFormat, methodName)
+
+	var failures *redis.IntCmd
+	if _, err := limiter.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		failures = pipe.Incr(failuresKey(key))
+		pipe.Expire(failuresKey(key), lockout.ResetAfter)
+		return nil
+	}); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return 0, errWrapped
+	}
+
+	duration := lockout.Duration(int(failures.Val()))
+	if duration == 0 {
+		return 0, nil
+	}
+
+	if err := limiter.client.Set(lockKey(key), failures.Val(), duration).Err(); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return 0, errWrapped
+	}
+
+	return duration, nil
+}
+
+// Reset forgets failures and lock of the key, e.g. after successful login
+func (limiter *Limiter) Reset(key string) error {
+	methodName := "Reset"
+	errWrapMessage := fmt.Sprintf(errRateLimitMessageFormat, methodName)
+
+	if err := limiter.client.Del(failuresKey(key), lockKey(key)).Err(); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+// positiveDuration falls back to the whole window if redis didn't return the key's TTL
+func positiveDuration(ttl time.Duration, fallback time.Duration) time.Duration {
+	if ttl <= 0 {
+		return fallback
+	}
+
+	return ttl
+}
@@ -0,0 +1,107 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apiserver"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/ratelimit"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	lockout := ratelimit.Lockout{
+		Threshold:    3,
+		BaseDuration: 30 * time.Second,
+		MaxDuration:  time.Minute * 3,
+	}
+
+	durations := map[int]time.Duration{
+		0: 0,
+		2: 0,
+		3: 30 * time.Second,
+		4: time.Minute,
+		5: time.Minute * 2,
+		6: time.Minute * 3,
+		9: time.Minute * 3,
+	}
+
+	for failures, durationWanted := range durations {
+		if duration := lockout.Duration(failures); duration != durationWanted {
+			t.Errorf("Wrong lockout duration after %d failures:\n\tWanted: %v, Got: %v", failures, durationWanted, duration)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := newTestLimiter(t)
+	key := uuid.New().String()
+
+	rule := ratelimit.Rule{
+		Limit:  2,
+		Window: time.Minute,
+	}
+
+	for i := 0; i < rule.Limit; i++ {
+		retryAfter, err := limiter.Allow(key, rule)
+		if err != nil {
+			t.Errorf("Couldn't count hit:\n\t%s", err.Error())
+			return
+		}
+		if retryAfter != 0 {
+			t.Errorf("Hit %d within the limit was rejected, retry after %v", i+1, retryAfter)
+		}
+	}
+
+	retryAfter, err := limiter.Allow(key, rule)
+	if err != nil {
+		t.Errorf("Couldn't count hit:\n\t%s", err.Error())
+		return
+	}
+	if retryAfter <= 0 || retryAfter > rule.Window {
+		t.Errorf("Wrong retry after for hit over the limit:\n\tGot: %v", retryAfter)
+	}
+
+	lockout := ratelimit.Lockout{
+		Threshold:    2,
+		BaseDuration: time.Minute,
+		MaxDuration:  time.Hour,
+		ResetAfter:   time.Hour,
+	}
+
+	if lockedFor, err := limiter.RegisterFailure(key, lockout); err != nil || lockedFor != 0 {
+		t.Errorf("Key was locked before threshold:\n\tGot: %v, err: %v", lockedFor, err)
+	}
+	if lockedFor, err := limiter.RegisterFailure(key, lockout); err != nil || lockedFor != lockout.BaseDuration {
+		t.Errorf("Key wasn't locked at threshold:\n\tWanted: %v, Got: %v, err: %v", lockout.BaseDuration, lockedFor, err)
+	}
+	if lockedFor, err := limiter.LockedFor(key); err != nil || lockedFor <= 0 {
+		t.Errorf("Locked key isn't reported as locked:\n\tGot: %v, err: %v", lockedFor, err)
+	}
+
+	if err := limiter.Reset(key); err != nil {
+		t.Errorf("Couldn't reset key:\n\t%s", err.Error())
+	}
+	if lockedFor, err := limiter.LockedFor(key); err != nil || lockedFor != 0 {
+		t.Errorf("Key is locked after reset:\n\tGot: %v, err: %v", lockedFor, err)
+	}
+}
+
+func newTestLimiter(t *testing.T) *ratelimit.Limiter {
+	config := apiserver.NewConfig()
+
+	if _, err := toml.DecodeFile("../../../configs/local_test.toml", config); err != nil {
+		t.Skipf("Couldn't get config:\n\t%s", err.Error())
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: config.RedisAddr,
+	})
+	if _, err := client.Ping().Result(); err != nil {
+		t.Skipf("Couldn't setup Redis:\n\t%s", err.Error())
+	}
+
+	return ratelimit.New(client)
+}
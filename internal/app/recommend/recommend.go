@@ -0,0 +1,167 @@
+package recommend
+
+import (
+	"math"
+	"sort"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+const (
+	// tagsWeight and publisherWeight split the taste score between shared tags and the publisher
+	tagsWeight      = 0.8
+	publisherWeight = 0.2
+	// discountBoost is how much the score of a game grows, if it is given away for free
+	discountBoost = 0.5
+)
+
+// Recommendation is a game the user may like, BecauseOf is the liked game it resembles most
+type Recommendation struct {
+	Game      *model.Game
+	Score     float64
+	BecauseOf *model.Game
+}
+
+// TagWeights returns inverse document frequency of every tag in the catalogue,
+// so rare tags tell more about a game than the common ones
+func TagWeights(tagsByGame map[uint64][]string) map[string]float64 {
+	gamesByTag := make(map[string]int)
+	for _, tagNames := range tagsByGame {
+		for _, tagName := range tagNames {
+			gamesByTag[tagName]++
+		}
+	}
+
+	gamesCount := float64(len(tagsByGame))
+	weights := make(map[string]float64)
+	for tagName, count := range gamesByTag {
+		weights[tagName] = math.Log(1 + gamesCount/float64(count))
+	}
+
+	return weights
+}
+
+// Recommend scores catalogue games by the taste of the user, made of tags and publishers of liked games.
+// Tag score is the cosine similarity of weighted tags of the game and of all liked games together,
+// publisher score is the share of liked games by the same publisher. Games on sale get a boost
+// proportional to discount percent from discounts. Liked and excluded games and games that have
+// nothing in common with the liked ones aren't recommended.
+// Returns at most limit recommendations, the best first.
+func Recommend(catalogue []*model.Game, liked []*model.Game, tagsByGame map[uint64][]string, discounts map[uint64]int, exclude map[uint64]bool, limit int) []*Recommendation {
+	tagWeights := TagWeights(tagsByGame)
+
+	likedIDs := make(map[uint64]bool)
+	likedUnique := []*model.Game{}
+	profile := make(map[string]float64)
+	publisherShares := make(map[uint64]float64)
+
+	for _, game := range liked {
+		if likedIDs[game.ID] {
+			continue
+		}
+		likedIDs[game.ID] = true
+		likedUnique = append(likedUnique, game)
+
+		for _, tagName := range tagsByGame[game.ID] {
+			profile[tagName] += tagWeights[tagName]
+		}
+	}
+
+	for _, game := range likedUnique {
+		if game.Publisher != nil {
+			publisherShares[game.Publisher.ID] += 1 / float64(len(likedUnique))
+		}
+	}
+
+	profileNorm := 0.0
+	for _, weight := range profile {
+		profileNorm += weight * weight
+	}
+	profileNorm = math.Sqrt(profileNorm)
+
+	recommendations := []*Recommendation{}
+
+	for _, game := range catalogue {
+		if likedIDs[game.ID] || exclude[game.ID] {
+			continue
+		}
+
+		tagsScore := 0.0
+		if profileNorm > 0 {
+			product := 0.0
+			gameNorm := 0.0
+			for _, tagName := range tagsByGame[game.ID] {
+				product += profile[tagName] * tagWeights[tagName]
+				gameNorm += tagWeights[tagName] * tagWeights[tagName]
+			}
+			if gameNorm > 0 {
+				tagsScore = product / (profileNorm * math.Sqrt(gameNorm))
+			}
+		}
+
+		publisherScore := 0.0
+		if game.Publisher != nil {
+			publisherScore = publisherShares[game.Publisher.ID]
+		}
+
+		taste := tagsWeight*tagsScore + publisherWeight*publisherScore
+		if taste == 0 {
+			continue
+		}
+
+		recommendations = append(recommendations, &Recommendation{
+			Game:      game,
+			Score:     taste * (1 + discountBoost*float64(discounts[game.ID])/100),
+			BecauseOf: closest(game, likedUnique, tagsByGame, tagWeights),
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].Game.ID < recommendations[j].Game.ID
+	})
+
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations
+}
+
+// closest returns the liked game with the biggest weight of tags shared with the game,
+// the same publisher counts as much as the rarest tag, but not less than 1 for catalogues without tags
+func closest(game *model.Game, liked []*model.Game, tagsByGame map[uint64][]string, tagWeights map[string]float64) *model.Game {
+	gameTags := make(map[string]bool)
+	for _, tagName := range tagsByGame[game.ID] {
+		gameTags[tagName] = true
+	}
+
+	maxTagWeight := 1.0
+	for _, weight := range tagWeights {
+		maxTagWeight = math.Max(maxTagWeight, weight)
+	}
+
+	var closestGame *model.Game
+	closestSimilarity := 0.0
+
+	for _, likedGame := range liked {
+		similarity := 0.0
+		for _, tagName := range tagsByGame[likedGame.ID] {
+			if gameTags[tagName] {
+				similarity += tagWeights[tagName]
+			}
+		}
+		if game.Publisher != nil && likedGame.Publisher != nil && game.Publisher.ID == likedGame.Publisher.ID {
+			similarity += maxTagWeight
+		}
+
+		if similarity > closestSimilarity {
+			closestGame = likedGame
+			closestSimilarity = similarity
+		}
+	}
+
+	return closestGame
+}
@@ -0,0 +1,165 @@
+package recommend
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+const (
+	// NeighboursCount is the number of similar games stored for every game
+	NeighboursCount = 20
+
+	// similarTagsWeight, similarPublisherWeight and similarEraWeight split similarity score
+	// between shared tags, the same publisher and close release dates
+	similarTagsWeight      = 0.7
+	similarPublisherWeight = 0.15
+	similarEraWeight       = 0.15
+	// eraYears is the difference in release years, after which games don't count as the same era
+	eraYears = 5.0
+
+	releaseDateLayout = "02.01.2006"
+)
+
+// TagsByGame groups tag names by game ID
+func TagsByGame(gameTags []*model.GameTag) map[uint64][]string {
+	tagsByGame := make(map[uint64][]string)
+	for _, gameTag := range gameTags {
+		tagsByGame[gameTag.Game.ID] = append(tagsByGame[gameTag.Game.ID], gameTag.Tag.Name)
+	}
+
+	return tagsByGame
+}
+
+// similarityGame is a game with everything needed to compare it computed once
+type similarityGame struct {
+	game        *model.Game
+	tags        map[string]bool
+	tagsNorm    float64
+	releaseYear float64
+	hasRelease  bool
+}
+
+// Neighbours finds at most count most similar games for every game of the catalogue.
+// Similarity is the cosine similarity of tags weighted by TagWeights, plus bonuses for the same publisher
+// and for release dates closer than eraYears. Games having neither tags nor publisher in common
+// aren't neighbours, however close their release dates are.
+func Neighbours(catalogue []*model.Game, tagsByGame map[uint64][]string, count int) []*model.GameSimilarity {
+	tagWeights := TagWeights(tagsByGame)
+
+	similarityGames := []*similarityGame{}
+	gamesByTag := make(map[string][]int)
+	gamesByPublisher := make(map[uint64][]int)
+
+	for _, game := range catalogue {
+		current := &similarityGame{
+			game: game,
+			tags: make(map[string]bool),
+		}
+
+		for _, tagName := range tagsByGame[game.ID] {
+			if current.tags[tagName] {
+				continue
+			}
+			current.tags[tagName] = true
+			current.tagsNorm += tagWeights[tagName] * tagWeights[tagName]
+			gamesByTag[tagName] = append(gamesByTag[tagName], len(similarityGames))
+		}
+		current.tagsNorm = math.Sqrt(current.tagsNorm)
+
+		if releaseDate, err := time.Parse(releaseDateLayout, game.ReleaseDate); err == nil {
+			current.releaseYear = float64(releaseDate.Year()) + float64(releaseDate.YearDay())/366
+			current.hasRelease = true
+		}
+
+		if game.Publisher != nil {
+			gamesByPublisher[game.Publisher.ID] = append(gamesByPublisher[game.Publisher.ID], len(similarityGames))
+		}
+
+		similarityGames = append(similarityGames, current)
+	}
+
+	gameSimilarities := []*model.GameSimilarity{}
+
+	for index, current := range similarityGames {
+		// Only games sharing something with the current one are compared
+		candidates := make(map[int]bool)
+		for tagName := range current.tags {
+			for _, candidate := range gamesByTag[tagName] {
+				candidates[candidate] = true
+			}
+		}
+		if current.game.Publisher != nil {
+			for _, candidate := range gamesByPublisher[current.game.Publisher.ID] {
+				candidates[candidate] = true
+			}
+		}
+		delete(candidates, index)
+
+		neighbours := []*model.GameSimilarity{}
+		for candidate := range candidates {
+			neighbours = append(neighbours, &model.GameSimilarity{
+				Score:       similarity(current, similarityGames[candidate], tagWeights),
+				Game:        current.game,
+				SimilarGame: similarityGames[candidate].game,
+			})
+		}
+
+		sort.Slice(neighbours, func(i, j int) bool {
+			if neighbours[i].Score != neighbours[j].Score {
+				return neighbours[i].Score > neighbours[j].Score
+			}
+			return neighbours[i].SimilarGame.ID < neighbours[j].SimilarGame.ID
+		})
+
+		if len(neighbours) > count {
+			neighbours = neighbours[:count]
+		}
+
+		gameSimilarities = append(gameSimilarities, neighbours...)
+	}
+
+	return gameSimilarities
+}
+
+func similarity(first *similarityGame, second *similarityGame, tagWeights map[string]float64) float64 {
+	score := 0.0
+
+	if first.tagsNorm > 0 && second.tagsNorm > 0 {
+		product := 0.0
+		for tagName := range first.tags {
+			if second.tags[tagName] {
+				product += tagWeights[tagName] * tagWeights[tagName]
+			}
+		}
+		score += similarTagsWeight * product / (first.tagsNorm * second.tagsNorm)
+	}
+
+	if first.game.Publisher != nil && second.game.Publisher != nil && first.game.Publisher.ID == second.game.Publisher.ID {
+		score += similarPublisherWeight
+	}
+
+	if first.hasRelease && second.hasRelease {
+		score += similarEraWeight * math.Max(0, 1-math.Abs(first.releaseYear-second.releaseYear)/eraYears)
+	}
+
+	return score
+}
+
+// RefreshNeighbours recomputes similar games of the whole catalogue, it is run after catalogue syncs and moderator changes
+func RefreshNeighbours(st store.Store) error {
+	catalogue, err := st.Games().FindAll()
+	if err != nil {
+		return err
+	}
+
+	gameTags, err := st.GameTags().FindAll()
+	if err != nil {
+		return err
+	}
+
+	return st.GameSimilarities().ReplaceAll(Neighbours(catalogue, TagsByGame(gameTags), NeighboursCount))
+}
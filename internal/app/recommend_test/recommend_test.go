@@ -0,0 +1,89 @@
+package recommend_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/recommend"
+)
+
+var (
+	publisherIndie  = &model.Publisher{ID: 1, Name: "Indie"}
+	publisherGiant  = &model.Publisher{ID: 2, Name: "Giant"}
+	gameSurvival    = &model.Game{ID: 1, Name: "Survival", Publisher: publisherIndie}
+	gameCrafting    = &model.Game{ID: 2, Name: "Crafting", Publisher: publisherIndie}
+	gameShooter     = &model.Game{ID: 3, Name: "Shooter", Publisher: publisherGiant}
+	gameSandbox     = &model.Game{ID: 4, Name: "Sandbox", Publisher: publisherGiant}
+	gameSportsDrama = &model.Game{ID: 5, Name: "Sports Drama", Publisher: publisherGiant}
+
+	catalogue = []*model.Game{gameSurvival, gameCrafting, gameShooter, gameSandbox, gameSportsDrama}
+
+	tagsByGame = map[uint64][]string{
+		gameSurvival.ID:    {"survival", "open_world", "singleplayer"},
+		gameCrafting.ID:    {"crafting", "survival", "singleplayer"},
+		gameShooter.ID:     {"shooter", "multiplayer", "singleplayer"},
+		gameSandbox.ID:     {"crafting", "open_world", "multiplayer"},
+		gameSportsDrama.ID: {"sports"},
+	}
+)
+
+func TestTagWeights(t *testing.T) {
+	tagWeights := recommend.TagWeights(tagsByGame)
+
+	if tagWeights["sports"] <= tagWeights["survival"] || tagWeights["survival"] <= tagWeights["singleplayer"] {
+		t.Errorf("Rare tags don't weigh more than common ones:\n\tGot: %v", tagWeights)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	recommendations := recommend.Recommend(catalogue, []*model.Game{gameSurvival, gameSurvival}, tagsByGame, map[uint64]int{}, map[uint64]bool{}, 10)
+
+	if len(recommendations) != 3 {
+		t.Errorf("Wrong number of recommendations:\n\tWanted: 3, Got: %d", len(recommendations))
+		return
+	}
+	for _, recommendation := range recommendations {
+		if recommendation.Game.ID == gameSurvival.ID || recommendation.Game.ID == gameSportsDrama.ID {
+			t.Errorf("Liked or unrelated game (%s) was recommended", recommendation.Game.Name)
+		}
+		if recommendation.BecauseOf != gameSurvival {
+			t.Errorf("Wrong explanation of game (%s):\n\tWanted: %+v, Got: %+v", recommendation.Game.Name, gameSurvival, recommendation.BecauseOf)
+		}
+	}
+	if recommendations[0].Game.ID != gameCrafting.ID {
+		t.Errorf("Wrong best recommendation:\n\tWanted: %s, Got: %s", gameCrafting.Name, recommendations[0].Game.Name)
+	}
+}
+
+func TestRecommendDiscountsAndExclusions(t *testing.T) {
+	discounts := map[uint64]int{gameShooter.ID: 90}
+	exclude := map[uint64]bool{gameCrafting.ID: true}
+
+	recommendations := recommend.Recommend(catalogue, []*model.Game{gameSurvival}, tagsByGame, discounts, exclude, 10)
+
+	scores := map[uint64]float64{}
+	for _, recommendation := range recommendations {
+		scores[recommendation.Game.ID] = recommendation.Score
+	}
+
+	if _, ok := scores[gameCrafting.ID]; ok {
+		t.Errorf("Excluded game (%s) was recommended", gameCrafting.Name)
+	}
+
+	recommendationsFull := recommend.Recommend(catalogue, []*model.Game{gameSurvival}, tagsByGame, map[uint64]int{}, map[uint64]bool{}, 10)
+	for _, recommendation := range recommendationsFull {
+		if recommendation.Game.ID == gameShooter.ID && scores[gameShooter.ID] <= recommendation.Score {
+			t.Errorf("Discount didn't boost the score:\n\tWithout discount: %f, With: %f", recommendation.Score, scores[gameShooter.ID])
+		}
+	}
+
+	if recommendationsLimited := recommend.Recommend(catalogue, []*model.Game{gameSurvival}, tagsByGame, discounts, exclude, 1); len(recommendationsLimited) != 1 {
+		t.Errorf("Limit wasn't applied:\n\tWanted: 1, Got: %d", len(recommendationsLimited))
+	}
+}
+
+func TestRecommendNothingLiked(t *testing.T) {
+	if recommendations := recommend.Recommend(catalogue, []*model.Game{}, tagsByGame, map[uint64]int{}, map[uint64]bool{}, 10); len(recommendations) != 0 {
+		t.Errorf("Games were recommended without any liked games:\n\tGot: %d", len(recommendations))
+	}
+}
@@ -0,0 +1,48 @@
+package recommend_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/recommend"
+)
+
+func TestNeighbours(t *testing.T) {
+	gameOld := &model.Game{ID: 1, Name: "Old", ReleaseDate: "01.06.2005", Publisher: publisherIndie}
+	gameNew := &model.Game{ID: 2, Name: "New", ReleaseDate: "01.06.2021", Publisher: publisherGiant}
+	gameNewSequel := &model.Game{ID: 3, Name: "New Sequel", ReleaseDate: "01.06.2022", Publisher: publisherGiant}
+	gameUnrelated := &model.Game{ID: 4, Name: "Unrelated", ReleaseDate: "01.06.2021", Publisher: publisherIndie}
+
+	// New is equally close to Old and New Sequel by tags, but shares publisher and era only with the sequel
+	tagsByGameSimilar := map[uint64][]string{
+		gameOld.ID:       {"survival", "crafting"},
+		gameNew.ID:       {"survival", "crafting"},
+		gameNewSequel.ID: {"survival", "crafting"},
+		gameUnrelated.ID: {"sports"},
+	}
+
+	gameSimilarities := recommend.Neighbours([]*model.Game{gameOld, gameNew, gameNewSequel, gameUnrelated}, tagsByGameSimilar, 1)
+
+	neighbours := map[uint64]*model.GameSimilarity{}
+	for _, gameSimilarity := range gameSimilarities {
+		if _, ok := neighbours[gameSimilarity.Game.ID]; ok {
+			t.Errorf("Game (%s) has more neighbours than asked", gameSimilarity.Game.Name)
+		}
+		neighbours[gameSimilarity.Game.ID] = gameSimilarity
+	}
+
+	if neighbour, ok := neighbours[gameNew.ID]; !ok || neighbour.SimilarGame.ID != gameNewSequel.ID {
+		t.Errorf("Wrong neighbour of game (%s):\n\tWanted: %s, Got: %+v", gameNew.Name, gameNewSequel.Name, neighbour)
+	}
+
+	// Unrelated game shares only the publisher with Old
+	if neighbour, ok := neighbours[gameUnrelated.ID]; !ok || neighbour.SimilarGame.ID != gameOld.ID {
+		t.Errorf("Wrong neighbour of game (%s):\n\tWanted: %s, Got: %+v", gameUnrelated.Name, gameOld.Name, neighbour)
+	}
+
+	for _, gameSimilarity := range gameSimilarities {
+		if gameSimilarity.Score <= 0 || gameSimilarity.Score > 1 {
+			t.Errorf("Similarity score is out of range:\n\tGot: %f", gameSimilarity.Score)
+		}
+	}
+}
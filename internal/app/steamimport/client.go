@@ -0,0 +1,133 @@
+package steamimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultAPIURL = "https://api.steampowered.com"
+
+type Config struct {
+	// APIURL defaults to Steam Web API, it is changed in tests
+	APIURL string
+	// APIKey is needed only for owned games
+	APIKey string
+}
+
+// Client reads public library of a Steam user
+type Client struct {
+	config Config
+	client *http.Client
+}
+
+func NewClient(config Config, client *http.Client) *Client {
+	if config.APIURL == "" {
+		config.APIURL = defaultAPIURL
+	}
+	config.APIURL = strings.TrimRight(config.APIURL, "/")
+
+	return &Client{
+		config: config,
+		client: client,
+	}
+}
+
+// WishlistAppIDs returns app IDs from the user's wishlist. Private wishlist looks the same as empty one.
+func (client *Client) WishlistAppIDs(steamID string) ([]string, error) {
+	methodName := "WishlistAppIDs"
+	errWrapMessage := fmt.Sprintf(errSteamImportMessageFormat, methodName)
+
+	type responseItem struct {
+		AppID int `json:"appid"`
+	}
+
+	type response struct {
+		Response struct {
+			Items []responseItem `json:"items"`
+		} `json:"response"`
+	}
+
+	query := url.Values{}
+	query.Set("steamid", steamID)
+
+	responseStruct := &response{}
+	if err := client.getJSON("/IWishlistService/GetWishlist/v1/", query, responseStruct); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	appIDs := make([]string, 0, len(responseStruct.Response.Items))
+	for _, item := range responseStruct.Response.Items {
+		appIDs = append(appIDs, strconv.Itoa(item.AppID))
+	}
+
+	return appIDs, nil
+}
+
+// OwnedAppIDs returns app IDs of games the user owns, including free games they have played
+func (client *Client) OwnedAppIDs(steamID string) ([]string, error) {
+	methodName := "OwnedAppIDs"
+	errWrapMessage := fmt.Sprintf(errSteamImportMessageFormat, methodName)
+
+	type responseGame struct {
+		AppID int `json:"appid"`
+	}
+
+	// Steam answers with empty object, when game details of the profile are private
+	type response struct {
+		Response struct {
+			GameCount *int           `json:"game_count"`
+			Games     []responseGame `json:"games"`
+		} `json:"response"`
+	}
+
+	query := url.Values{}
+	query.Set("key", client.config.APIKey)
+	query.Set("steamid", steamID)
+	query.Set("include_played_free_games", "1")
+	query.Set("format", "json")
+
+	responseStruct := &response{}
+	if err := client.getJSON("/IPlayerService/GetOwnedGames/v1/", query, responseStruct); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	if responseStruct.Response.GameCount == nil {
+		errWrapped := errors.Wrap(ErrProfilePrivate, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	appIDs := make([]string, 0, len(responseStruct.Response.Games))
+	for _, game := range responseStruct.Response.Games {
+		appIDs = append(appIDs, strconv.Itoa(game.AppID))
+	}
+
+	return appIDs, nil
+}
+
+func (client *Client) getJSON(path string, query url.Values, target interface{}) error {
+	resp, err := client.client.Get(client.config.APIURL + path + "?" + query.Encode())
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errRequestMessage)
+		return errors.Wrap(errWrapped, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrap(ErrSteamResponse, fmt.Sprintf("%s answered with status %d", path, resp.StatusCode))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		errWrapped := errors.Wrap(ErrSteamResponse, errDecodeMessage)
+		return errors.Wrap(errWrapped, err.Error())
+	}
+
+	return nil
+}
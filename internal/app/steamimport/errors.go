@@ -0,0 +1,15 @@
+package steamimport
+
+import "github.com/pkg/errors"
+
+var (
+	ErrProfilePrivate = errors.New("Steam profile or its game details are private")
+	ErrSteamResponse  = errors.New("Steam answered with error")
+	ErrInternal       = errors.New("Internal error")
+)
+
+const (
+	errSteamImportMessageFormat = "SteamImport %s error"
+	errRequestMessage           = "Couldn't request Steam"
+	errDecodeMessage            = "Couldn't decode Steam response"
+)
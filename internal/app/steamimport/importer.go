@@ -0,0 +1,155 @@
+package steamimport
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+// Result tells how much of the Steam library was found in the catalogue
+type Result struct {
+	WishlistTotal   int `json:"wishlist_total"`
+	FavouritesAdded int `json:"favourites_added"`
+	OwnedTotal      int `json:"owned_total"`
+	OwnedAdded      int `json:"owned_added"`
+	// NotInCatalogue is the number of apps, that couldn't be matched with catalogue games
+	NotInCatalogue int `json:"not_in_catalogue"`
+	// OwnedPrivate is true, when the wishlist was imported, but owned games are hidden by privacy settings
+	OwnedPrivate bool `json:"owned_private"`
+}
+
+// Importer turns Steam wishlist into favourites and Steam library into owned games.
+// Apps are matched with the catalogue by Steam offers' market URLs, which are app IDs.
+type Importer struct {
+	client *Client
+	store  store.Store
+}
+
+func NewImporter(client *Client, st store.Store) *Importer {
+	return &Importer{
+		client: client,
+		store:  st,
+	}
+}
+
+// Import can be repeated, games already in favourites or owned aren't added twice
+func (importer *Importer) Import(user *model.User, steamID string) (*Result, error) {
+	methodName := "Import"
+	errWrapMessage := fmt.Sprintf(errSteamImportMessageFormat, methodName)
+
+	result := &Result{}
+
+	wishlistAppIDs, err := importer.client.WishlistAppIDs(steamID)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	ownedAppIDs, err := importer.client.OwnedAppIDs(steamID)
+	if err != nil {
+		if errors.Cause(err) != ErrProfilePrivate {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return nil, errWrapped
+		}
+
+		result.OwnedPrivate = true
+	}
+
+	marketSteam, err := importer.store.Markets().FindBy("name", "Steam")
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	wishlistGames, err := importer.findGames(marketSteam, wishlistAppIDs)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+	result.WishlistTotal = len(wishlistAppIDs)
+	result.NotInCatalogue += len(wishlistAppIDs) - len(wishlistGames)
+
+	ownedGames, err := importer.findGames(marketSteam, ownedAppIDs)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+	result.OwnedTotal = len(ownedAppIDs)
+	result.NotInCatalogue += len(ownedAppIDs) - len(ownedGames)
+
+	for _, game := range wishlistGames {
+		if _, err := importer.store.UserGameFavourites().FindByUserGame(user, game); err == nil {
+			continue
+		} else if errors.Cause(err) != store.ErrNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return nil, errWrapped
+		}
+
+		userGameFavourite := &model.UserGameFavourite{
+			User: user,
+			Game: game,
+		}
+		if err := importer.store.UserGameFavourites().Create(userGameFavourite); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return nil, errWrapped
+		}
+		result.FavouritesAdded++
+	}
+
+	alreadyOwned, err := importer.store.UserOwnedGames().FindAllByUser(user)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	alreadyOwnedIDs := map[uint64]bool{}
+	for _, userOwnedGame := range alreadyOwned {
+		alreadyOwnedIDs[userOwnedGame.Game.ID] = true
+	}
+
+	now := time.Now()
+	for _, game := range ownedGames {
+		if alreadyOwnedIDs[game.ID] {
+			continue
+		}
+
+		userOwnedGame := &model.UserOwnedGame{
+			Source:    model.OwnedGameSourceImported,
+			CreatedAt: now,
+			User:      user,
+			Game:      game,
+			Market:    marketSteam,
+		}
+		if err := importer.store.UserOwnedGames().Create(userOwnedGame); err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return nil, errWrapped
+		}
+		result.OwnedAdded++
+	}
+
+	return result, nil
+}
+
+// findGames returns catalogue games of the apps, apps without Steam offer are skipped
+func (importer *Importer) findGames(marketSteam *model.Market, appIDs []string) ([]*model.Game, error) {
+	gameMarketPrices, err := importer.store.GameMarketPrices().FindAllByMarketURLs(marketSteam, appIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]*model.Game, 0, len(gameMarketPrices))
+	seenIDs := map[uint64]bool{}
+	for _, gameMarketPrice := range gameMarketPrices {
+		if seenIDs[gameMarketPrice.Game.ID] {
+			continue
+		}
+		seenIDs[gameMarketPrice.Game.ID] = true
+		games = append(games, gameMarketPrice.Game)
+	}
+
+	return games, nil
+}
@@ -0,0 +1,95 @@
+package steamimport_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/steamimport"
+)
+
+const (
+	testSteamID = "76561197960287930"
+	testAPIKey  = "test-key"
+)
+
+// newStubSteam answers like Steam Web API for the test user, other users have private profiles
+func newStubSteam(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/IWishlistService/GetWishlist/v1/", func(writer http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("steamid") != testSteamID {
+			fmt.Fprint(writer, `{"response":{}}`)
+			return
+		}
+
+		fmt.Fprint(writer, `{"response":{"items":[{"appid":730,"priority":1,"date_added":1700000000},{"appid":305620,"priority":2,"date_added":1700000001}]}}`)
+	})
+	mux.HandleFunc("/IPlayerService/GetOwnedGames/v1/", func(writer http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("key") != testAPIKey {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if req.URL.Query().Get("steamid") != testSteamID {
+			fmt.Fprint(writer, `{"response":{}}`)
+			return
+		}
+
+		fmt.Fprint(writer, `{"response":{"game_count":1,"games":[{"appid":427520,"playtime_forever":120}]}}`)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClientWishlistAppIDs(t *testing.T) {
+	server := newStubSteam(t)
+	client := steamimport.NewClient(steamimport.Config{APIURL: server.URL, APIKey: testAPIKey}, server.Client())
+
+	appIDs, err := client.WishlistAppIDs(testSteamID)
+	if err != nil {
+		t.Fatalf("Couldn't get wishlist:\n\t%s", err.Error())
+	}
+
+	if want := []string{"730", "305620"}; !reflect.DeepEqual(appIDs, want) {
+		t.Errorf("Wrong wishlist:\n\tWanted: %v, Got: %v", want, appIDs)
+	}
+
+	// Private wishlist can't be told from empty one
+	appIDs, err = client.WishlistAppIDs("1")
+	if err != nil || len(appIDs) != 0 {
+		t.Errorf("Wrong private wishlist:\n\tWanted: [], Got: %v (%v)", appIDs, err)
+	}
+}
+
+func TestClientOwnedAppIDs(t *testing.T) {
+	server := newStubSteam(t)
+	client := steamimport.NewClient(steamimport.Config{APIURL: server.URL, APIKey: testAPIKey}, server.Client())
+
+	appIDs, err := client.OwnedAppIDs(testSteamID)
+	if err != nil {
+		t.Fatalf("Couldn't get owned games:\n\t%s", err.Error())
+	}
+
+	if want := []string{"427520"}; !reflect.DeepEqual(appIDs, want) {
+		t.Errorf("Wrong owned games:\n\tWanted: %v, Got: %v", want, appIDs)
+	}
+
+	if _, err := client.OwnedAppIDs("1"); errors.Cause(err) != steamimport.ErrProfilePrivate {
+		t.Errorf("Wrong error for private profile:\n\tWanted: %v, Got: %v", steamimport.ErrProfilePrivate, err)
+	}
+}
+
+func TestClientOwnedAppIDsWrongKey(t *testing.T) {
+	server := newStubSteam(t)
+	client := steamimport.NewClient(steamimport.Config{APIURL: server.URL, APIKey: "other-key"}, server.Client())
+
+	if _, err := client.OwnedAppIDs(testSteamID); errors.Cause(err) != steamimport.ErrSteamResponse {
+		t.Errorf("Wrong error for wrong key:\n\tWanted: %v, Got: %v", steamimport.ErrSteamResponse, err)
+	}
+}
@@ -5,10 +5,12 @@ import "github.com/pkg/errors"
 var (
 	ErrUnknownSQL = errors.New("Something wrong with SQL request")
 	ErrNotFound   = errors.New("Record not found")
+	ErrConflict   = errors.New("Record already exists")
 )
 
 const (
 	ErrRepositoryMessageFormat        = "%s repository %s error"
 	ErrCreateTablesMessageFormat      = "Creating %s table error"
+	ErrAlterTablesMessageFormat       = "Altering %s table error"
 	ErrTestDataInsertionMessageFormat = "Inserting data in %s table error"
 )
@@ -1,15 +1,21 @@
 package store
 
 import (
+	"time"
+
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 )
 
 type UserRepository interface {
 	Create(*model.User) error
+	CreateWithoutPassword(*model.User) error
 	Find(uint64) (*model.User, error)
 	FindBy(string, interface{}) (*model.User, error)
 	UpdateEmail(string, uint64) error
+	UpdateEmailVerified(bool, uint64) error
 	UpdatePassword(string, uint64) error
+	UpdateRole(string, uint64) error
+	UpdateBanned(bool, uint64) error
 	Delete(uint64) error
 }
 
@@ -17,6 +23,7 @@ type PublisherRepository interface {
 	Create(*model.Publisher) error
 	Find(uint64) (*model.Publisher, error)
 	FindBy(string, interface{}) (*model.Publisher, error)
+	FindAll() ([]*model.Publisher, error)
 	Update(*model.Publisher) error
 	Delete(uint64) error
 }
@@ -36,6 +43,7 @@ type TagRepository interface {
 	Create(*model.Tag) error
 	Find(uint64) (*model.Tag, error)
 	FindBy(string, interface{}) (*model.Tag, error)
+	FindAll() ([]*model.Tag, error)
 	FindAllByGame(*model.Game) ([]*model.Tag, error)
 	Update(*model.Tag) error
 	Delete(uint64) error
@@ -45,6 +53,7 @@ type MarketRepository interface {
 	Create(*model.Market) error
 	Find(uint64) (*model.Market, error)
 	FindBy(string, interface{}) (*model.Market, error)
+	FindAll() ([]*model.Market, error)
 	Update(*model.Market) error
 	Delete(uint64) error
 }
@@ -62,22 +71,130 @@ type GameTagRepository interface {
 	Create(*model.GameTag) error
 	Find(uint64) (*model.GameTag, error)
 	FindBy(string, interface{}) (*model.GameTag, error)
+	FindAll() ([]*model.GameTag, error)
 	Update(*model.GameTag) error
 	Delete(uint64) error
 }
 
+// DealsSort is the order of discounted offers returned by GameMarketPriceRepository.FindAllDeals
+type DealsSort string
+
+const (
+	DealsSortEndingSoon DealsSort = "ending_soon"
+	DealsSortDiscount   DealsSort = "discount"
+	DealsSortPrice      DealsSort = "price"
+)
+
+// DealsFilter narrows deals down to the ones the user wants to see, zero value doesn't filter anything.
+// Empty EnabledMarkets mean every market, the same way as in model.UserPreferences.
+type DealsFilter struct {
+	ExcludeOwnedBy *model.User
+	EnabledMarkets []string
+	HiddenTags     []string
+}
+
 type GameMarketPriceRepository interface {
 	Create(*model.GameMarketPrice) error
 	Find(uint64) (*model.GameMarketPrice, error)
 	FindBy(string, interface{}) (*model.GameMarketPrice, error)
 	FindByGameMarket(*model.Game, *model.Market) (*model.GameMarketPrice, error)
 	FindAllByGame(*model.Game) ([]*model.GameMarketPrice, error)
+	FindAllByMarketURLs(*model.Market, []string) ([]*model.GameMarketPrice, error)
+	FindAllByGamesMarket([]*model.Game, *model.Market) ([]*model.GameMarketPrice, error)
+	FindAllBestByGames([]*model.Game, []string) ([]*model.GameMarketPrice, error)
+	FindAllDeals(DealsSort, int, DealsFilter) ([]*model.GameMarketPrice, error)
 	Update(*model.GameMarketPrice) error
+	SaveAll([]*model.GameMarketPrice) error
+	MarkSeen([]uint64, time.Time) error
+	MarkMissed(*model.Market, []uint64, int) ([]uint64, error)
 	Delete(uint64) error
 }
 
 type MarketBlacklistItemRepository interface {
 	Create(*model.MarketBlacklistItem) error
 	CheckByURL(string) (bool, error)
+	FindAll() ([]*model.MarketBlacklistItem, error)
+	Delete(uint64) error
+}
+
+type GiveawayRepository interface {
+	Create(*model.Giveaway) error
+	Find(uint64) (*model.Giveaway, error)
+	FindAllNotEnded(time.Time) ([]*model.Giveaway, error)
+	ReplaceUndated(*model.Market, []*model.Giveaway) error
+	Delete(uint64) error
+}
+
+type PriceStatsRepository interface {
+	AddRecord(*model.GameMarketPrice, time.Time) error
+	AddSale(*model.GameMarketPrice) error
+	FindByGameMarketPrice(*model.GameMarketPrice, time.Time) (*model.PriceStats, error)
+	FindAllByGame(*model.Game, time.Time) ([]*model.PriceStats, error)
+	FindAllByGames([]*model.Game, time.Time) ([]*model.PriceStats, error)
+	FindAllRecordsByGame(*model.Game) ([]*model.PriceRecord, error)
+	FindAllRecordsByPublisher(*model.Publisher) ([]*model.PriceRecord, error)
+}
+
+type SecurityEventRepository interface {
+	Create(*model.SecurityEvent) error
+	FindAllByUser(*model.User, int) ([]*model.SecurityEvent, error)
+}
+
+type UserTOTPRepository interface {
+	Save(*model.UserTOTP) error
+	FindByUser(*model.User) (*model.UserTOTP, error)
+	UseStep(*model.User, int64) error
+	Enable(*model.User, []string) error
+	Delete(*model.User) error
+}
+
+type RecoveryCodeRepository interface {
+	ReplaceAll(*model.User, []string) error
+	Use(*model.User, string, time.Time) error
+	CountUnused(*model.User) (int, error)
+}
+
+type UserIdentityRepository interface {
+	Create(*model.UserIdentity) error
+	FindByProviderSubject(string, string) (*model.UserIdentity, error)
+	FindAllByUser(*model.User) ([]*model.UserIdentity, error)
+	DeleteByUserProvider(*model.User, string) error
+}
+
+type UserOwnedGameRepository interface {
+	Create(*model.UserOwnedGame) error
+	Find(uint64) (*model.UserOwnedGame, error)
+	FindAllByUser(*model.User) ([]*model.UserOwnedGame, error)
+	Update(*model.UserOwnedGame) error
+	Delete(uint64) error
+}
+
+type WishlistRepository interface {
+	Create(*model.Wishlist) error
+	Find(uint64) (*model.Wishlist, error)
+	FindAllByUser(*model.User) ([]*model.Wishlist, error)
+	FindByPublicSlug(string) (*model.Wishlist, error)
+	FindOrCreateDefault(*model.User) (*model.Wishlist, error)
+	Update(*model.Wishlist) error
+	UpdateSharing(*model.Wishlist) error
+	Reorder(*model.User, []uint64) error
+	Delete(uint64) error
+}
+
+type WishlistItemRepository interface {
+	Create(*model.WishlistItem) error
+	Find(uint64) (*model.WishlistItem, error)
+	FindAllByWishlist(*model.Wishlist) ([]*model.WishlistItem, error)
+	Update(*model.WishlistItem) error
 	Delete(uint64) error
 }
+
+type UserPreferencesRepository interface {
+	FindByUser(*model.User) (*model.UserPreferences, error)
+	Save(*model.UserPreferences) error
+}
+
+type GameSimilarityRepository interface {
+	ReplaceAll([]*model.GameSimilarity) error
+	FindAllByGame(*model.Game, int) ([]*model.GameSimilarity, error)
+}
@@ -9,9 +9,20 @@ import (
 )
 
 var tableNames = []string{
+	"game_similarities",
+	"user_preferences",
+	"wishlist_items",
+	"wishlists",
+	"user_owned_games",
+	"user_identities",
+	"user_recovery_codes",
+	"user_totp",
+	"security_events",
+	"price_stats",
+	"price_history",
+	"giveaways",
 	"game_market_prices",
 	"game_tags",
-	"user_game_favourites",
 	// "markets",
 	"tags",
 	"games",
@@ -36,22 +47,8 @@ func (st *Store) insertDataMarkets() error {
 	tableName := "Markets"
 	errWrapMessage := fmt.Sprintf(store.ErrTestDataInsertionMessageFormat, tableName)
 
-	var markets []*model.Market
-
-	markets = append(markets, &model.Market{
-		Name: "Steam",
-	})
-
-	markets = append(markets, &model.Market{
-		Name: "EpicGamesStore",
-	})
-
-	markets = append(markets, &model.Market{
-		Name: "GOG.com",
-	})
-
-	for _, market := range markets {
-		if err := st.Markets().Create(market); err != nil {
+	for _, marketName := range model.BuiltInMarketNames {
+		if err := st.Markets().Create(&model.Market{Name: marketName}); err != nil {
 			return errors.Wrap(err, errWrapMessage)
 		}
 	}
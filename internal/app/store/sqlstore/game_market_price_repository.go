@@ -3,7 +3,9 @@ package sqlstore
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
@@ -14,23 +16,54 @@ type GameMarketPriceRepository struct {
 	store *Store
 }
 
+const gameMarketPriceCreateQuery = "INSERT INTO game_market_prices (initial_value_formatted, final_value_formatted, final_value, discount_percent, sale_starts_at, sale_ends_at, market_game_url, last_seen_at, missed_syncs, delisted, game_id, market_id) " +
+	"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id;"
+
+const gameMarketPriceUpdateQuery = "UPDATE game_market_prices " +
+	"SET initial_value_formatted = :initial_value_formatted, " +
+	"final_value_formatted = :final_value_formatted, " +
+	"final_value = :final_value, " +
+	"discount_percent = :discount_percent, " +
+	"sale_starts_at = :sale_starts_at, " +
+	"sale_ends_at = :sale_ends_at, " +
+	"market_game_url = :market_game_url, " +
+	"last_seen_at = :last_seen_at, " +
+	"missed_syncs = :missed_syncs, " +
+	"delisted = :delisted, " +
+	"game_id = :game.id, " +
+	"market_id = :market.id " +
+	"WHERE id = :id;"
+
+func gameMarketPriceCreateArgs(gameMarketPrice *model.GameMarketPrice) []interface{} {
+	return []interface{}{
+		gameMarketPrice.InitialValueFormatted,
+		gameMarketPrice.FinalValueFormatted,
+		gameMarketPrice.FinalValue,
+		gameMarketPrice.DiscountPercent,
+		gameMarketPrice.SaleStartsAt,
+		gameMarketPrice.SaleEndsAt,
+		gameMarketPrice.MarketGameURL,
+		gameMarketPrice.LastSeenAt,
+		gameMarketPrice.MissedSyncs,
+		gameMarketPrice.Delisted,
+		gameMarketPrice.Game.ID,
+		gameMarketPrice.Market.ID,
+	}
+}
+
 func (gameMarketPriceRepository *GameMarketPriceRepository) Create(gameMarketPrice *model.GameMarketPrice) error {
 	repositoryName := "GameMarketPrice"
 	methodName := "Create"
 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
 
-	createQuery := "INSERT INTO game_market_prices (initial_value_formatted, final_value_formatted, discount_percent, market_game_url, game_id, market_id) " +
-		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;"
+	if gameMarketPrice.LastSeenAt.IsZero() {
+		gameMarketPrice.LastSeenAt = time.Now()
+	}
 
 	if err := gameMarketPriceRepository.store.db.Get(
 		&gameMarketPrice.ID,
-		createQuery,
-		gameMarketPrice.InitialValueFormatted,
-		gameMarketPrice.FinalValueFormatted,
-		gameMarketPrice.DiscountPercent,
-		gameMarketPrice.MarketGameURL,
-		gameMarketPrice.Game.ID,
-		gameMarketPrice.Market.ID,
+		gameMarketPriceCreateQuery,
+		gameMarketPriceCreateArgs(gameMarketPrice)...,
 	); err != nil {
 		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
 	}
@@ -53,8 +86,14 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) FindBy(columnName st
 		"game_market_prices.id AS id, "+
 		"game_market_prices.initial_value_formatted AS initial_value_formatted, "+
 		"game_market_prices.final_value_formatted AS final_value_formatted, "+
+		"game_market_prices.final_value AS final_value, "+
 		"game_market_prices.discount_percent AS discount_percent, "+
+		"game_market_prices.sale_starts_at AS sale_starts_at, "+
+		"game_market_prices.sale_ends_at AS sale_ends_at, "+
 		"game_market_prices.market_game_url AS market_game_url, "+
+		"game_market_prices.last_seen_at AS last_seen_at, "+
+		"game_market_prices.missed_syncs AS missed_syncs, "+
+		"game_market_prices.delisted AS delisted, "+
 
 		"games.id AS \"game.id\", "+
 		"games.header_image_url AS \"game.header_image_url\", "+
@@ -62,12 +101,12 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) FindBy(columnName st
 		"games.description AS \"game.description\", "+
 
 		"publishers.id AS \"game.publisher.id\", "+
-		"publishers.name AS \"game.publisher.name\" "+
+		"publishers.name AS \"game.publisher.name\", "+
 
 		"markets.id AS \"market.id\", "+
 		"markets.name AS \"market.name\" "+
 
-		"FROM games "+
+		"FROM game_market_prices "+
 
 		"LEFT JOIN games "+
 		"ON (game_market_prices.game_id = games.id) "+
@@ -78,7 +117,7 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) FindBy(columnName st
 		"LEFT JOIN markets "+
 		"ON (game_market_prices.market_id = markets.id) "+
 
-		"WHERE %s = $1 LIMIT 1;", columnName)
+		"WHERE game_market_prices.%s = $1 LIMIT 1;", columnName)
 
 	if err := gameMarketPriceRepository.store.db.Get(
 		gameMarketPrice,
@@ -105,8 +144,14 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) FindByGameMarket(gam
 		"game_market_prices.id AS id, " +
 		"game_market_prices.initial_value_formatted AS initial_value_formatted, " +
 		"game_market_prices.final_value_formatted AS final_value_formatted, " +
+		"game_market_prices.final_value AS final_value, " +
 		"game_market_prices.discount_percent AS discount_percent, " +
+		"game_market_prices.sale_starts_at AS sale_starts_at, " +
+		"game_market_prices.sale_ends_at AS sale_ends_at, " +
 		"game_market_prices.market_game_url AS market_game_url, " +
+		"game_market_prices.last_seen_at AS last_seen_at, " +
+		"game_market_prices.missed_syncs AS missed_syncs, " +
+		"game_market_prices.delisted AS delisted, " +
 
 		"games.id AS \"game.id\", " +
 		"games.header_image_url AS \"game.header_image_url\", " +
@@ -158,8 +203,14 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) FindAllByGame(game *
 		"game_market_prices.id AS id, " +
 		"game_market_prices.initial_value_formatted AS initial_value_formatted, " +
 		"game_market_prices.final_value_formatted AS final_value_formatted, " +
+		"game_market_prices.final_value AS final_value, " +
 		"game_market_prices.discount_percent AS discount_percent, " +
+		"game_market_prices.sale_starts_at AS sale_starts_at, " +
+		"game_market_prices.sale_ends_at AS sale_ends_at, " +
 		"game_market_prices.market_game_url AS market_game_url, " +
+		"game_market_prices.last_seen_at AS last_seen_at, " +
+		"game_market_prices.missed_syncs AS missed_syncs, " +
+		"game_market_prices.delisted AS delisted, " +
 
 		"games.id AS \"game.id\", " +
 		"games.header_image_url AS \"game.header_image_url\", " +
@@ -200,22 +251,314 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) FindAllByGame(game *
 	return gameMarketPrices, nil
 }
 
+// FindAllByMarketURLs returns offers of the market for given market URLs, offers of unknown URLs are skipped
+func (gameMarketPriceRepository *GameMarketPriceRepository) FindAllByMarketURLs(market *model.Market, marketGameURLs []string) ([]*model.GameMarketPrice, error) {
+	repositoryName := "GameMarketPrice"
+	methodName := "FindAllByMarketURLs"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	gameMarketPrices := []*model.GameMarketPrice{}
+	if len(marketGameURLs) == 0 {
+		return gameMarketPrices, nil
+	}
+
+	findQuery := "SELECT " +
+		"game_market_prices.id AS id, " +
+		"game_market_prices.initial_value_formatted AS initial_value_formatted, " +
+		"game_market_prices.final_value_formatted AS final_value_formatted, " +
+		"game_market_prices.final_value AS final_value, " +
+		"game_market_prices.discount_percent AS discount_percent, " +
+		"game_market_prices.sale_starts_at AS sale_starts_at, " +
+		"game_market_prices.sale_ends_at AS sale_ends_at, " +
+		"game_market_prices.market_game_url AS market_game_url, " +
+		"game_market_prices.last_seen_at AS last_seen_at, " +
+		"game_market_prices.missed_syncs AS missed_syncs, " +
+		"game_market_prices.delisted AS delisted, " +
+
+		"games.id AS \"game.id\", " +
+		"games.header_image_url AS \"game.header_image_url\", " +
+		"games.name AS \"game.name\", " +
+		"games.description AS \"game.description\", " +
+
+		"publishers.id AS \"game.publisher.id\", " +
+		"publishers.name AS \"game.publisher.name\", " +
+
+		"markets.id AS \"market.id\", " +
+		"markets.name AS \"market.name\" " +
+
+		"FROM game_market_prices " +
+
+		"LEFT JOIN games " +
+		"ON (game_market_prices.game_id = games.id) " +
+
+		"LEFT JOIN publishers " +
+		"ON (games.publisher_id = publishers.id) " +
+
+		"LEFT JOIN markets " +
+		"ON (game_market_prices.market_id = markets.id) " +
+
+		"WHERE game_market_prices.market_id = $1 AND game_market_prices.market_game_url = ANY($2);"
+
+	if err := gameMarketPriceRepository.store.db.Select(
+		&gameMarketPrices,
+		findQuery,
+		market.ID,
+		pq.Array(marketGameURLs),
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.GameMarketPrice{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return gameMarketPrices, nil
+}
+
+// FindAllByGamesMarket returns offers of the games in the market, games not sold there are skipped
+func (gameMarketPriceRepository *GameMarketPriceRepository) FindAllByGamesMarket(games []*model.Game, market *model.Market) ([]*model.GameMarketPrice, error) {
+	repositoryName := "GameMarketPrice"
+	methodName := "FindAllByGamesMarket"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	gameMarketPrices := []*model.GameMarketPrice{}
+	if len(games) == 0 {
+		return gameMarketPrices, nil
+	}
+
+	gameIDs := []int64{}
+	for _, game := range games {
+		gameIDs = append(gameIDs, int64(game.ID))
+	}
+
+	findQuery := "SELECT " +
+		"game_market_prices.id AS id, " +
+		"game_market_prices.initial_value_formatted AS initial_value_formatted, " +
+		"game_market_prices.final_value_formatted AS final_value_formatted, " +
+		"game_market_prices.final_value AS final_value, " +
+		"game_market_prices.discount_percent AS discount_percent, " +
+		"game_market_prices.sale_starts_at AS sale_starts_at, " +
+		"game_market_prices.sale_ends_at AS sale_ends_at, " +
+		"game_market_prices.market_game_url AS market_game_url, " +
+		"game_market_prices.last_seen_at AS last_seen_at, " +
+		"game_market_prices.missed_syncs AS missed_syncs, " +
+		"game_market_prices.delisted AS delisted, " +
+
+		"games.id AS \"game.id\", " +
+		"games.header_image_url AS \"game.header_image_url\", " +
+		"games.name AS \"game.name\", " +
+		"games.description AS \"game.description\", " +
+
+		"publishers.id AS \"game.publisher.id\", " +
+		"publishers.name AS \"game.publisher.name\", " +
+
+		"markets.id AS \"market.id\", " +
+		"markets.name AS \"market.name\" " +
+
+		"FROM game_market_prices " +
+
+		"LEFT JOIN games " +
+		"ON (game_market_prices.game_id = games.id) " +
+
+		"LEFT JOIN publishers " +
+		"ON (games.publisher_id = publishers.id) " +
+
+		"LEFT JOIN markets " +
+		"ON (game_market_prices.market_id = markets.id) " +
+
+		"WHERE game_market_prices.market_id = $1 AND game_market_prices.game_id = ANY($2);"
+
+	if err := gameMarketPriceRepository.store.db.Select(
+		&gameMarketPrices,
+		findQuery,
+		market.ID,
+		pq.Array(gameIDs),
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.GameMarketPrice{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return gameMarketPrices, nil
+}
+
+// FindAllBestByGames returns the cheapest offer of every game, that is still sold in one of the markets.
+// Empty market names mean every market, games without such offers are skipped.
+func (gameMarketPriceRepository *GameMarketPriceRepository) FindAllBestByGames(games []*model.Game, marketNames []string) ([]*model.GameMarketPrice, error) {
+	repositoryName := "GameMarketPrice"
+	methodName := "FindAllBestByGames"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	gameMarketPrices := []*model.GameMarketPrice{}
+	if len(games) == 0 {
+		return gameMarketPrices, nil
+	}
+
+	gameIDs := []int64{}
+	for _, game := range games {
+		gameIDs = append(gameIDs, int64(game.ID))
+	}
+
+	findQuery := "SELECT DISTINCT ON (game_market_prices.game_id) " +
+		"game_market_prices.id AS id, " +
+		"game_market_prices.initial_value_formatted AS initial_value_formatted, " +
+		"game_market_prices.final_value_formatted AS final_value_formatted, " +
+		"game_market_prices.final_value AS final_value, " +
+		"game_market_prices.discount_percent AS discount_percent, " +
+		"game_market_prices.sale_starts_at AS sale_starts_at, " +
+		"game_market_prices.sale_ends_at AS sale_ends_at, " +
+		"game_market_prices.market_game_url AS market_game_url, " +
+		"game_market_prices.last_seen_at AS last_seen_at, " +
+		"game_market_prices.missed_syncs AS missed_syncs, " +
+		"game_market_prices.delisted AS delisted, " +
+
+		"games.id AS \"game.id\", " +
+		"games.header_image_url AS \"game.header_image_url\", " +
+		"games.name AS \"game.name\", " +
+		"games.description AS \"game.description\", " +
+
+		"publishers.id AS \"game.publisher.id\", " +
+		"publishers.name AS \"game.publisher.name\", " +
+
+		"markets.id AS \"market.id\", " +
+		"markets.name AS \"market.name\" " +
+
+		"FROM game_market_prices " +
+
+		"LEFT JOIN games " +
+		"ON (game_market_prices.game_id = games.id) " +
+
+		"LEFT JOIN publishers " +
+		"ON (games.publisher_id = publishers.id) " +
+
+		"LEFT JOIN markets " +
+		"ON (game_market_prices.market_id = markets.id) " +
+
+		"WHERE game_market_prices.game_id = ANY($1) AND NOT game_market_prices.delisted " +
+		"AND (coalesce(cardinality($2::varchar[]), 0) = 0 OR markets.name = ANY($2)) " +
+		"ORDER BY game_market_prices.game_id, game_market_prices.final_value, game_market_prices.id;"
+
+	if marketNames == nil {
+		marketNames = []string{}
+	}
+
+	if err := gameMarketPriceRepository.store.db.Select(
+		&gameMarketPrices,
+		findQuery,
+		pq.Array(gameIDs),
+		pq.Array(marketNames),
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.GameMarketPrice{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return gameMarketPrices, nil
+}
+
+// FindAllDeals returns discounted offers, that are still sold and whose sale hasn't ended yet.
+// Offers without known sale end are put after the others when sorting by ending soonest.
+// Games owned by filter.ExcludeOwnedBy are skipped, if it isn't nil, and so are offers outside
+// filter.EnabledMarkets and games with any of filter.HiddenTags.
+func (gameMarketPriceRepository *GameMarketPriceRepository) FindAllDeals(sort store.DealsSort, limit int, filter store.DealsFilter) ([]*model.GameMarketPrice, error) {
+	repositoryName := "GameMarketPrice"
+	methodName := "FindAllDeals"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	orderBy := ""
+	switch sort {
+	case store.DealsSortEndingSoon:
+		orderBy = "game_market_prices.sale_ends_at ASC NULLS LAST, game_market_prices.discount_percent DESC"
+	case store.DealsSortPrice:
+		orderBy = "game_market_prices.final_value ASC, game_market_prices.discount_percent DESC"
+	default:
+		orderBy = "game_market_prices.discount_percent DESC, game_market_prices.final_value ASC"
+	}
+
+	var excludeOwnedByID uint64
+	if filter.ExcludeOwnedBy != nil {
+		excludeOwnedByID = filter.ExcludeOwnedBy.ID
+	}
+
+	gameMarketPrices := []*model.GameMarketPrice{}
+	findQuery := "SELECT " +
+		"game_market_prices.id AS id, " +
+		"game_market_prices.initial_value_formatted AS initial_value_formatted, " +
+		"game_market_prices.final_value_formatted AS final_value_formatted, " +
+		"game_market_prices.final_value AS final_value, " +
+		"game_market_prices.discount_percent AS discount_percent, " +
+		"game_market_prices.sale_starts_at AS sale_starts_at, " +
+		"game_market_prices.sale_ends_at AS sale_ends_at, " +
+		"game_market_prices.market_game_url AS market_game_url, " +
+		"game_market_prices.last_seen_at AS last_seen_at, " +
+		"game_market_prices.missed_syncs AS missed_syncs, " +
+		"game_market_prices.delisted AS delisted, " +
+
+		"games.id AS \"game.id\", " +
+		"games.header_image_url AS \"game.header_image_url\", " +
+		"games.name AS \"game.name\", " +
+		"games.description AS \"game.description\", " +
+
+		"publishers.id AS \"game.publisher.id\", " +
+		"publishers.name AS \"game.publisher.name\", " +
+
+		"markets.id AS \"market.id\", " +
+		"markets.name AS \"market.name\" " +
+
+		"FROM game_market_prices " +
+
+		"LEFT JOIN games " +
+		"ON (game_market_prices.game_id = games.id) " +
+
+		"LEFT JOIN publishers " +
+		"ON (games.publisher_id = publishers.id) " +
+
+		"LEFT JOIN markets " +
+		"ON (game_market_prices.market_id = markets.id) " +
+
+		"WHERE game_market_prices.discount_percent > 0 " +
+		"AND NOT game_market_prices.delisted " +
+		"AND (game_market_prices.sale_ends_at IS NULL OR game_market_prices.sale_ends_at > now()) " +
+		"AND ($2 = 0 OR NOT EXISTS (" +
+		"SELECT 1 FROM user_owned_games " +
+		"WHERE user_owned_games.game_id = game_market_prices.game_id AND user_owned_games.user_id = $2)) " +
+		"AND (coalesce(cardinality($3::varchar[]), 0) = 0 OR markets.name = ANY($3)) " +
+		"AND NOT EXISTS (" +
+		"SELECT 1 FROM game_tags " +
+		"JOIN tags ON (game_tags.tag_id = tags.id) " +
+		"WHERE game_tags.game_id = game_market_prices.game_id AND tags.name = ANY($4::varchar[])) " +
+		"ORDER BY " + orderBy + ", game_market_prices.id " +
+		"LIMIT $1;"
+
+	if err := gameMarketPriceRepository.store.db.Select(
+		&gameMarketPrices,
+		findQuery,
+		limit,
+		excludeOwnedByID,
+		pq.Array(filter.EnabledMarkets),
+		pq.Array(filter.HiddenTags),
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.GameMarketPrice{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return gameMarketPrices, nil
+}
+
 func (gameMarketPriceRepository *GameMarketPriceRepository) Update(newGameMarket *model.GameMarketPrice) error {
 	repositoryName := "GameMarketPrice"
 	methodName := "Update"
 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
 
-	updateQuery := "UPDATE game_market_prices " +
-		"SET initial_value_formatted = :initial_value_formatted, " +
-		"final_value_formatted = :final_value_formatted, " +
-		"discount_percent = :discount_percent, " +
-		"market_game_url = :market_game_url, " +
-		"game_id = :game.id, " +
-		"market_id = :market.id " +
-		"WHERE id = :id;"
-
 	countResult, err := gameMarketPriceRepository.store.db.NamedExec(
-		updateQuery,
+		gameMarketPriceUpdateQuery,
 		newGameMarket,
 	)
 
@@ -236,6 +579,137 @@ func (gameMarketPriceRepository *GameMarketPriceRepository) Update(newGameMarket
 	return nil
 }
 
+// SaveAll writes a batch of offers found by a sync in one transaction,
+// offers without ID are created and the rest are updated
+func (gameMarketPriceRepository *GameMarketPriceRepository) SaveAll(gameMarketPrices []*model.GameMarketPrice) error {
+	repositoryName := "GameMarketPrice"
+	methodName := "SaveAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if len(gameMarketPrices) == 0 {
+		return nil
+	}
+
+	tx, err := gameMarketPriceRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	for _, gameMarketPrice := range gameMarketPrices {
+		if gameMarketPrice.LastSeenAt.IsZero() {
+			gameMarketPrice.LastSeenAt = time.Now()
+		}
+
+		if gameMarketPrice.ID == 0 {
+			if err := tx.Get(
+				&gameMarketPrice.ID,
+				gameMarketPriceCreateQuery,
+				gameMarketPriceCreateArgs(gameMarketPrice)...,
+			); err != nil {
+				tx.Rollback()
+				return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+			}
+
+			continue
+		}
+
+		countResult, err := tx.NamedExec(gameMarketPriceUpdateQuery, gameMarketPrice)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+
+		count, err := countResult.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+
+		if count == 0 {
+			tx.Rollback()
+			return errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// MarkSeen resets missed syncs counter of offers found by the last sync
+func (gameMarketPriceRepository *GameMarketPriceRepository) MarkSeen(ids []uint64, seenAt time.Time) error {
+	repositoryName := "GameMarketPrice"
+	methodName := "MarkSeen"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	updateQuery := "UPDATE game_market_prices " +
+		"SET last_seen_at = $1, " +
+		"missed_syncs = 0 " +
+		"WHERE id = ANY($2);"
+
+	if _, err := gameMarketPriceRepository.store.db.Exec(
+		updateQuery,
+		seenAt,
+		pq.Array(ids),
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// MarkMissed increments missed syncs counter of market offers, that weren't found by the last sync,
+// and marks them as delisted after missesToDelist syncs in a row. Returns IDs of just delisted offers.
+func (gameMarketPriceRepository *GameMarketPriceRepository) MarkMissed(market *model.Market, seenIDs []uint64, missesToDelist int) ([]uint64, error) {
+	repositoryName := "GameMarketPrice"
+	methodName := "MarkMissed"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	delistedIDs := []uint64{}
+	updateQuery := "UPDATE game_market_prices " +
+		"SET missed_syncs = missed_syncs + 1, " +
+		"delisted = (missed_syncs + 1 >= $3) " +
+		"WHERE market_id = $1 AND NOT delisted AND NOT (id = ANY($2)) " +
+		"RETURNING id, delisted;"
+
+	rows, err := gameMarketPriceRepository.store.db.Queryx(
+		updateQuery,
+		market.ID,
+		pq.Array(seenIDs),
+		missesToDelist,
+	)
+	if err != nil {
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		var delisted bool
+
+		if err := rows.Scan(&id, &delisted); err != nil {
+			return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+
+		if delisted {
+			delistedIDs = append(delistedIDs, id)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return delistedIDs, nil
+}
+
 func (gameMarketPriceRepository *GameMarketPriceRepository) Delete(id uint64) error {
 	repositoryName := "GameMarketPrice"
 	methodName := "Delete"
@@ -140,7 +140,9 @@ func (gameRepository *GameRepository) FindAllByUser(user *model.User) ([]*model.
 		"ON (games.publisher_id = publishers.id) " +
 
 		"WHERE games.id IN (" +
-		"    SELECT DISTINCT game_id FROM user_game_favourites WHERE user_id = $1" +
+		"    SELECT wishlist_items.game_id FROM wishlist_items " +
+		"    INNER JOIN wishlists ON (wishlist_items.wishlist_id = wishlists.id) " +
+		"    WHERE wishlists.user_id = $1 AND wishlists.is_default" +
 		");"
 
 	if err := gameRepository.store.db.Select(
@@ -226,7 +228,7 @@ func (gameRepository *GameRepository) Update(newGame *model.Game) error {
 		"SET header_image_url = :header_image_url, " +
 		"name = :name, " +
 		"description = :description, " +
-		"release_date = TO_DATE(':release_date', 'dd.MM.YYYY'), " +
+		"release_date = TO_DATE(:release_date, 'dd.MM.YYYY'), " +
 		"publisher_id = :publisher.id " +
 		"WHERE id = :id;"
 
@@ -0,0 +1,112 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type GameSimilarityRepository struct {
+	store *Store
+}
+
+// ReplaceAll drops neighbours of every game and saves the new ones in one transaction,
+// so readers never see a half-refreshed table
+func (gameSimilarityRepository *GameSimilarityRepository) ReplaceAll(gameSimilarities []*model.GameSimilarity) error {
+	repositoryName := "GameSimilarity"
+	methodName := "ReplaceAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	gameIDs := []int64{}
+	similarGameIDs := []int64{}
+	scores := []float64{}
+	for _, gameSimilarity := range gameSimilarities {
+		gameIDs = append(gameIDs, int64(gameSimilarity.Game.ID))
+		similarGameIDs = append(similarGameIDs, int64(gameSimilarity.SimilarGame.ID))
+		scores = append(scores, gameSimilarity.Score)
+	}
+
+	tx, err := gameSimilarityRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if _, err := tx.Exec("DELETE FROM game_similarities;"); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	insertQuery := "INSERT INTO game_similarities (game_id, similar_game_id, score) " +
+		"SELECT * FROM unnest($1::bigint[], $2::bigint[], $3::double precision[]);"
+
+	if _, err := tx.Exec(
+		insertQuery,
+		pq.Array(gameIDs),
+		pq.Array(similarGameIDs),
+		pq.Array(scores),
+	); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// FindAllByGame returns at most limit neighbours of the game, the most similar first
+func (gameSimilarityRepository *GameSimilarityRepository) FindAllByGame(game *model.Game, limit int) ([]*model.GameSimilarity, error) {
+	repositoryName := "GameSimilarity"
+	methodName := "FindAllByGame"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	gameSimilarities := []*model.GameSimilarity{}
+	findQuery := "SELECT " +
+		"game_similarities.id AS id, " +
+		"game_similarities.score AS score, " +
+		"game_similarities.game_id AS \"game.id\", " +
+
+		"games.id AS \"similar_game.id\", " +
+		"games.header_image_url AS \"similar_game.header_image_url\", " +
+		"games.name AS \"similar_game.name\", " +
+		"games.description AS \"similar_game.description\", " +
+		"TO_CHAR(games.release_date, 'dd.MM.YYYY') AS \"similar_game.release_date\", " +
+
+		"publishers.id AS \"similar_game.publisher.id\", " +
+		"publishers.name AS \"similar_game.publisher.name\" " +
+
+		"FROM game_similarities " +
+
+		"LEFT JOIN games " +
+		"ON (game_similarities.similar_game_id = games.id) " +
+
+		"LEFT JOIN publishers " +
+		"ON (games.publisher_id = publishers.id) " +
+
+		"WHERE game_similarities.game_id = $1 " +
+		"ORDER BY game_similarities.score DESC, game_similarities.similar_game_id " +
+		"LIMIT $2;"
+
+	if err := gameSimilarityRepository.store.db.Select(
+		&gameSimilarities,
+		findQuery,
+		game.ID,
+		limit,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.GameSimilarity{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return gameSimilarities, nil
+}
@@ -79,6 +79,39 @@ func (gameTagRepository *GameTagRepository) FindBy(columnName string, value inte
 	return gameTag, nil
 }
 
+// FindAll returns tags of the whole catalogue, games have only their IDs set
+func (gameTagRepository *GameTagRepository) FindAll() ([]*model.GameTag, error) {
+	repositoryName := "GameTag"
+	methodName := "FindAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	gameTags := []*model.GameTag{}
+	findQuery := "SELECT " +
+		"game_tags.id AS id, " +
+		"game_tags.game_id AS \"game.id\", " +
+
+		"tags.id AS \"tag.id\", " +
+		"tags.name AS \"tag.name\" " +
+
+		"FROM game_tags " +
+
+		"LEFT JOIN tags " +
+		"ON (game_tags.tag_id = tags.id);"
+
+	if err := gameTagRepository.store.db.Select(
+		&gameTags,
+		findQuery,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.GameTag{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return gameTags, nil
+}
+
 func (gameTagRepository *GameTagRepository) Update(newGameTag *model.GameTag) error {
 	repositoryName := "GameTag"
 	methodName := "Update"
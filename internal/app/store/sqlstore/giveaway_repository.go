@@ -0,0 +1,214 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type GiveawayRepository struct {
+	store *Store
+}
+
+// Giveaway may be not matched with a game, so game columns are coalesced and
+// the game is dropped after scanning if its ID is zero
+const giveawaySelectColumns = "giveaways.id AS id, " +
+	"giveaways.title AS title, " +
+	"giveaways.market_game_url AS market_game_url, " +
+	"giveaways.starts_at AS starts_at, " +
+	"giveaways.ends_at AS ends_at, " +
+
+	"markets.id AS \"market.id\", " +
+	"markets.name AS \"market.name\", " +
+
+	"COALESCE(games.id, 0) AS \"game.id\", " +
+	"COALESCE(games.header_image_url, '') AS \"game.header_image_url\", " +
+	"COALESCE(games.name, '') AS \"game.name\", " +
+	"COALESCE(games.description, '') AS \"game.description\", " +
+	"COALESCE(TO_CHAR(games.release_date, 'dd.MM.YYYY'), '') AS \"game.release_date\", " +
+
+	"COALESCE(publishers.id, 0) AS \"game.publisher.id\", " +
+	"COALESCE(publishers.name, '') AS \"game.publisher.name\" " +
+
+	"FROM giveaways " +
+
+	"LEFT JOIN markets " +
+	"ON (giveaways.market_id = markets.id) " +
+
+	"LEFT JOIN games " +
+	"ON (giveaways.game_id = games.id) " +
+
+	"LEFT JOIN publishers " +
+	"ON (games.publisher_id = publishers.id) "
+
+func (giveawayRepository *GiveawayRepository) Create(giveaway *model.Giveaway) error {
+	repositoryName := "Giveaway"
+	methodName := "Create"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	var gameID interface{}
+	if giveaway.Game != nil {
+		gameID = giveaway.Game.ID
+	}
+
+	createQuery := "INSERT INTO giveaways (title, market_game_url, starts_at, ends_at, market_id, game_id) " +
+		"VALUES ($1, $2, $3, $4, $5, $6) " +
+		"ON CONFLICT(market_id, market_game_url, starts_at) DO UPDATE SET " +
+		"title = EXCLUDED.title, ends_at = EXCLUDED.ends_at, game_id = EXCLUDED.game_id RETURNING id;"
+
+	if err := giveawayRepository.store.db.Get(
+		&giveaway.ID,
+		createQuery,
+		giveaway.Title,
+		giveaway.MarketGameURL,
+		giveaway.StartsAt,
+		giveaway.EndsAt,
+		giveaway.Market.ID,
+		gameID,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (giveawayRepository *GiveawayRepository) Find(id uint64) (*model.Giveaway, error) {
+	repositoryName := "Giveaway"
+	methodName := "Find"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	giveaway := &model.Giveaway{}
+	findQuery := "SELECT " + giveawaySelectColumns + "WHERE giveaways.id = $1 LIMIT 1;"
+
+	if err := giveawayRepository.store.db.Get(
+		giveaway,
+		findQuery,
+		id,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if giveaway.Game.ID == 0 {
+		giveaway.Game = nil
+	}
+
+	return giveaway, nil
+}
+
+// FindAllNotEnded returns active and upcoming giveaways ordered by start time, giveaways without dates go first
+func (giveawayRepository *GiveawayRepository) FindAllNotEnded(now time.Time) ([]*model.Giveaway, error) {
+	repositoryName := "Giveaway"
+	methodName := "FindAllNotEnded"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	giveaways := []*model.Giveaway{}
+	findQuery := "SELECT " + giveawaySelectColumns +
+		"WHERE giveaways.ends_at IS NULL OR giveaways.ends_at > $1 " +
+		"ORDER BY giveaways.starts_at NULLS FIRST, giveaways.ends_at, giveaways.id;"
+
+	if err := giveawayRepository.store.db.Select(
+		&giveaways,
+		findQuery,
+		now,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.Giveaway{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	for _, giveaway := range giveaways {
+		if giveaway.Game.ID == 0 {
+			giveaway.Game = nil
+		}
+	}
+
+	return giveaways, nil
+}
+
+// ReplaceUndated replaces giveaways without dates of the market with the ones found by the last sync,
+// so giveaways, that weren't found, are considered ended
+func (giveawayRepository *GiveawayRepository) ReplaceUndated(market *model.Market, giveaways []*model.Giveaway) error {
+	repositoryName := "Giveaway"
+	methodName := "ReplaceUndated"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tx, err := giveawayRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	deleteQuery := "DELETE FROM giveaways WHERE market_id = $1 AND starts_at IS NULL;"
+	if _, err := tx.Exec(deleteQuery, market.ID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	createQuery := "INSERT INTO giveaways (title, market_game_url, market_id, game_id) " +
+		"VALUES ($1, $2, $3, $4) RETURNING id;"
+
+	for _, giveaway := range giveaways {
+		var gameID interface{}
+		if giveaway.Game != nil {
+			gameID = giveaway.Game.ID
+		}
+
+		if err := tx.Get(
+			&giveaway.ID,
+			createQuery,
+			giveaway.Title,
+			giveaway.MarketGameURL,
+			market.ID,
+			gameID,
+		); err != nil {
+			tx.Rollback()
+			return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (giveawayRepository *GiveawayRepository) Delete(id uint64) error {
+	repositoryName := "Giveaway"
+	methodName := "Delete"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	deleteQuery := "DELETE FROM giveaways WHERE id = $1;"
+
+	countResult, err := giveawayRepository.store.db.Exec(
+		deleteQuery,
+		id,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
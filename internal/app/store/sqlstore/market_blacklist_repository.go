@@ -68,6 +68,40 @@ func (marketBlacklistItemRepository *MarketBlacklistItemRepository) CheckByURL(m
 	return result, nil
 }
 
+func (marketBlacklistItemRepository *MarketBlacklistItemRepository) FindAll() ([]*model.MarketBlacklistItem, error) {
+	repositoryName := "MarketBlacklistItem"
+	methodName := "FindAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	marketBlacklistItems := []*model.MarketBlacklistItem{}
+	findQuery := "SELECT " +
+		"market_blacklist.id AS id, " +
+		"market_blacklist.market_game_url AS market_game_url, " +
+
+		"markets.id AS \"market.id\", " +
+		"markets.name AS \"market.name\" " +
+
+		"FROM market_blacklist " +
+
+		"LEFT JOIN markets " +
+		"ON (market_blacklist.market_id = markets.id) " +
+
+		"ORDER BY market_blacklist.id;"
+
+	if err := marketBlacklistItemRepository.store.db.Select(
+		&marketBlacklistItems,
+		findQuery,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.MarketBlacklistItem{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return marketBlacklistItems, nil
+}
+
 func (marketBlacklistItemRepository *MarketBlacklistItemRepository) Delete(id uint64) error {
 	repositoryName := "MarketBlacklistItem"
 	methodName := "Delete"
@@ -60,6 +60,28 @@ func (marketRepository *MarketRepository) FindBy(columnName string, value interf
 	return market, nil
 }
 
+func (marketRepository *MarketRepository) FindAll() ([]*model.Market, error) {
+	repositoryName := "Market"
+	methodName := "FindAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	markets := []*model.Market{}
+	findQuery := "SELECT * FROM markets ORDER BY id;"
+
+	if err := marketRepository.store.db.Select(
+		&markets,
+		findQuery,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.Market{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return markets, nil
+}
+
 func (marketRepository *MarketRepository) Update(newMarket *model.Market) error {
 	repositoryName := "Market"
 	methodName := "Update"
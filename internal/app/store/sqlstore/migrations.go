@@ -5,6 +5,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
@@ -43,22 +44,112 @@ func (s *Store) createTables() error {
 		return errWrapped
 	}
 
-	if err := createTableUserGameFavourites(tx); err != nil {
+	if err := createTableGameTags(tx); err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return errWrapped
 	}
 
-	if err := createTableGameTags(tx); err != nil {
+	if err := createTableGameMarketPrices(tx); err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return errWrapped
 	}
 
-	if err := createTableGameMarketPrices(tx); err != nil {
+	if err := createTableMarketBlacklist(tx); err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return errWrapped
 	}
 
-	if err := createTableMarketBlacklist(tx); err != nil {
+	if err := alterTableGameMarketPricesAvailability(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableGiveaways(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := alterTableGameMarketPricesSales(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTablePriceHistory(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTablePriceStats(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := alterTableUsersEmailVerified(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableSecurityEvents(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := alterTableUsersRoles(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableUserTOTP(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableUserRecoveryCodes(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableUserIdentities(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableUserOwnedGames(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableWishlists(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableWishlistItems(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := alterTableWishlistsSharing(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableUserPreferences(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := createTableGameSimilarities(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := alterTableGiveawaysUndated(tx); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := migrateUserGameFavourites(tx); err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return errWrapped
 	}
@@ -164,24 +255,6 @@ func createTableMarkets(tx *sqlx.Tx) error {
 	return nil
 }
 
-func createTableUserGameFavourites(tx *sqlx.Tx) error {
-	tableName := "UserGameFavourites"
-	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
-
-	createTableGameFavouritesQuery := "CREATE TABLE IF NOT EXISTS user_game_favourites (" +
-		"id bigserial NOT NULL PRIMARY KEY," +
-		"user_id bigserial NOT NULL REFERENCES users (id) ON DELETE CASCADE," +
-		"game_id bigserial NOT NULL REFERENCES games (id) ON DELETE CASCADE );"
-
-	if _, err := tx.Exec(createTableGameFavouritesQuery); err != nil {
-		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
-		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
-		return errWrapped
-	}
-
-	return nil
-}
-
 func createTableGameTags(tx *sqlx.Tx) error {
 	tableName := "GameTags"
 	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
@@ -239,3 +312,461 @@ func createTableMarketBlacklist(tx *sqlx.Tx) error {
 
 	return nil
 }
+
+func alterTableGameMarketPricesAvailability(tx *sqlx.Tx) error {
+	tableName := "GameMarketPrices"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	alterTableGameMarketPricesQuery := "ALTER TABLE game_market_prices " +
+		"ADD COLUMN IF NOT EXISTS last_seen_at timestamptz NOT NULL DEFAULT now()," +
+		"ADD COLUMN IF NOT EXISTS missed_syncs integer NOT NULL DEFAULT 0," +
+		"ADD COLUMN IF NOT EXISTS delisted boolean NOT NULL DEFAULT false;"
+
+	if _, err := tx.Exec(alterTableGameMarketPricesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func alterTableGameMarketPricesSales(tx *sqlx.Tx) error {
+	tableName := "GameMarketPrices"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	alterTableGameMarketPricesQuery := "ALTER TABLE game_market_prices " +
+		"ADD COLUMN IF NOT EXISTS final_value integer NOT NULL DEFAULT 0," +
+		"ADD COLUMN IF NOT EXISTS sale_starts_at timestamptz," +
+		"ADD COLUMN IF NOT EXISTS sale_ends_at timestamptz;"
+
+	if _, err := tx.Exec(alterTableGameMarketPricesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableGiveaways(tx *sqlx.Tx) error {
+	tableName := "Giveaways"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableGiveawaysQuery := "CREATE TABLE IF NOT EXISTS giveaways (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"title varchar NOT NULL," +
+		"market_game_url varchar NOT NULL," +
+		"starts_at timestamptz NOT NULL," +
+		"ends_at timestamptz NOT NULL," +
+		"market_id bigint NOT NULL REFERENCES markets (id) ON DELETE CASCADE," +
+		"game_id bigint REFERENCES games (id) ON DELETE SET NULL," +
+		"UNIQUE (market_id, market_game_url, starts_at) );"
+
+	if _, err := tx.Exec(createTableGiveawaysQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTablePriceHistory(tx *sqlx.Tx) error {
+	tableName := "PriceHistory"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTablePriceHistoryQuery := "CREATE TABLE IF NOT EXISTS price_history (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"final_value integer NOT NULL," +
+		"discount_percent integer NOT NULL," +
+		"recorded_at timestamptz NOT NULL," +
+		"game_market_price_id bigint NOT NULL REFERENCES game_market_prices (id) ON DELETE CASCADE );"
+
+	if _, err := tx.Exec(createTablePriceHistoryQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	createIndexQuery := "CREATE INDEX IF NOT EXISTS price_history_game_market_price_id_recorded_at_idx " +
+		"ON price_history (game_market_price_id, recorded_at);"
+
+	if _, err := tx.Exec(createIndexQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	// Offers, that existed before the history was tracked, start with their current price.
+	// Zero final_value is the column default until the first sync fills it in, so such offers
+	// aren't seeded, they are recorded by the sync itself.
+	seedQuery := "INSERT INTO price_history (final_value, discount_percent, recorded_at, game_market_price_id) " +
+		"SELECT final_value, discount_percent, last_seen_at, id FROM game_market_prices " +
+		"WHERE final_value > 0 " +
+		"AND NOT EXISTS (SELECT 1 FROM price_history WHERE price_history.game_market_price_id = game_market_prices.id);"
+
+	if _, err := tx.Exec(seedQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTablePriceStats(tx *sqlx.Tx) error {
+	tableName := "PriceStats"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTablePriceStatsQuery := "CREATE TABLE IF NOT EXISTS price_stats (" +
+		"game_market_price_id bigint NOT NULL PRIMARY KEY REFERENCES game_market_prices (id) ON DELETE CASCADE," +
+		"all_time_low integer NOT NULL," +
+		"all_time_low_at timestamptz NOT NULL," +
+		"price_sum bigint NOT NULL DEFAULT 0," +
+		"samples_count integer NOT NULL DEFAULT 0," +
+		"sales_count integer NOT NULL DEFAULT 0," +
+		"first_seen_at timestamptz NOT NULL," +
+		"updated_at timestamptz NOT NULL );"
+
+	if _, err := tx.Exec(createTablePriceStatsQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	seedQuery := "INSERT INTO price_stats (game_market_price_id, all_time_low, all_time_low_at, price_sum, samples_count, sales_count, first_seen_at, updated_at) " +
+		"SELECT id, final_value, last_seen_at, final_value, 1, 0, last_seen_at, last_seen_at FROM game_market_prices " +
+		"WHERE final_value > 0 " +
+		"ON CONFLICT (game_market_price_id) DO NOTHING;"
+
+	if _, err := tx.Exec(seedQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func alterTableUsersEmailVerified(tx *sqlx.Tx) error {
+	tableName := "Users"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	alterTableUsersQuery := "ALTER TABLE users " +
+		"ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false;"
+
+	if _, err := tx.Exec(alterTableUsersQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableSecurityEvents(tx *sqlx.Tx) error {
+	tableName := "SecurityEvents"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableSecurityEventsQuery := "CREATE TABLE IF NOT EXISTS security_events (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"type varchar NOT NULL," +
+		"session_id varchar NOT NULL," +
+		"ip varchar NOT NULL," +
+		"user_agent varchar NOT NULL," +
+		"created_at timestamptz NOT NULL," +
+		"user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE );"
+
+	if _, err := tx.Exec(createTableSecurityEventsQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func alterTableUsersRoles(tx *sqlx.Tx) error {
+	tableName := "Users"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	alterTableUsersQuery := "ALTER TABLE users " +
+		"ADD COLUMN IF NOT EXISTS role varchar NOT NULL DEFAULT 'user', " +
+		"ADD COLUMN IF NOT EXISTS banned boolean NOT NULL DEFAULT false;"
+
+	if _, err := tx.Exec(alterTableUsersQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableUserTOTP(tx *sqlx.Tx) error {
+	tableName := "UserTOTP"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableUserTOTPQuery := "CREATE TABLE IF NOT EXISTS user_totp (" +
+		"user_id bigint NOT NULL PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE," +
+		"secret varchar NOT NULL," +
+		"enabled boolean NOT NULL DEFAULT false," +
+		"last_used_step bigint NOT NULL DEFAULT 0," +
+		"created_at timestamptz NOT NULL );"
+
+	if _, err := tx.Exec(createTableUserTOTPQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableUserRecoveryCodes(tx *sqlx.Tx) error {
+	tableName := "UserRecoveryCodes"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableUserRecoveryCodesQuery := "CREATE TABLE IF NOT EXISTS user_recovery_codes (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"code_hash varchar NOT NULL," +
+		"used_at timestamptz," +
+		"user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE," +
+		"UNIQUE (user_id, code_hash) );"
+
+	if _, err := tx.Exec(createTableUserRecoveryCodesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableUserIdentities(tx *sqlx.Tx) error {
+	tableName := "UserIdentities"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableUserIdentitiesQuery := "CREATE TABLE IF NOT EXISTS user_identities (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"provider varchar NOT NULL," +
+		"subject varchar NOT NULL," +
+		"email varchar NOT NULL," +
+		"created_at timestamptz NOT NULL," +
+		"user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE," +
+		"UNIQUE (provider, subject)," +
+		"UNIQUE (user_id, provider) );"
+
+	if _, err := tx.Exec(createTableUserIdentitiesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableUserOwnedGames(tx *sqlx.Tx) error {
+	tableName := "UserOwnedGames"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableUserOwnedGamesQuery := "CREATE TABLE IF NOT EXISTS user_owned_games (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"source varchar NOT NULL," +
+		"created_at timestamptz NOT NULL," +
+		"user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE," +
+		"game_id bigint NOT NULL REFERENCES games (id) ON DELETE CASCADE," +
+		"market_id bigint REFERENCES markets (id) ON DELETE SET NULL," +
+		"UNIQUE (user_id, game_id) );"
+
+	if _, err := tx.Exec(createTableUserOwnedGamesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableWishlists(tx *sqlx.Tx) error {
+	tableName := "Wishlists"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableWishlistsQuery := "CREATE TABLE IF NOT EXISTS wishlists (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"name varchar NOT NULL," +
+		"position integer NOT NULL," +
+		"is_default boolean NOT NULL DEFAULT false," +
+		"created_at timestamptz NOT NULL," +
+		"user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE," +
+		"UNIQUE (user_id, name) );"
+
+	if _, err := tx.Exec(createTableWishlistsQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	// Every user has only one default list
+	createIndexWishlistsDefaultQuery := "CREATE UNIQUE INDEX IF NOT EXISTS wishlists_default_idx " +
+		"ON wishlists (user_id) WHERE is_default;"
+
+	if _, err := tx.Exec(createIndexWishlistsDefaultQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableWishlistItems(tx *sqlx.Tx) error {
+	tableName := "WishlistItems"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableWishlistItemsQuery := "CREATE TABLE IF NOT EXISTS wishlist_items (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"priority integer NOT NULL DEFAULT 0," +
+		"note varchar NOT NULL DEFAULT ''," +
+		"target_price integer," +
+		"created_at timestamptz NOT NULL," +
+		"wishlist_id bigint NOT NULL REFERENCES wishlists (id) ON DELETE CASCADE," +
+		"game_id bigint NOT NULL REFERENCES games (id) ON DELETE CASCADE," +
+		"UNIQUE (wishlist_id, game_id) );"
+
+	if _, err := tx.Exec(createTableWishlistItemsQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func alterTableWishlistsSharing(tx *sqlx.Tx) error {
+	tableName := "Wishlists"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	alterTableWishlistsQuery := "ALTER TABLE wishlists " +
+		"ADD COLUMN IF NOT EXISTS is_public boolean NOT NULL DEFAULT false," +
+		"ADD COLUMN IF NOT EXISTS public_slug varchar UNIQUE;"
+
+	if _, err := tx.Exec(alterTableWishlistsQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+// alterTableGiveawaysUndated lets giveaways have no dates. GOG doesn't publish them,
+// so its giveaways saved with made-up dates are deleted, the next sync saves them again.
+func alterTableGiveawaysUndated(tx *sqlx.Tx) error {
+	tableName := "Giveaways"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	alterTableGiveawaysQuery := "ALTER TABLE giveaways " +
+		"ALTER COLUMN starts_at DROP NOT NULL," +
+		"ALTER COLUMN ends_at DROP NOT NULL;"
+
+	if _, err := tx.Exec(alterTableGiveawaysQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	deleteGiveawaysQuery := "DELETE FROM giveaways " +
+		"WHERE starts_at IS NOT NULL " +
+		"AND market_id IN (SELECT id FROM markets WHERE name = 'GOG.com');"
+
+	if _, err := tx.Exec(deleteGiveawaysQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+// migrateUserGameFavourites moves favourites into default wishlists and drops their old table,
+// duplicated favourites are merged
+func migrateUserGameFavourites(tx *sqlx.Tx) error {
+	tableName := "UserGameFavourites"
+	errWrapMessage := fmt.Sprintf(store.ErrAlterTablesMessageFormat, tableName)
+
+	var tableExists bool
+	if err := tx.Get(&tableExists, "SELECT to_regclass('user_game_favourites') IS NOT NULL;"); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	if !tableExists {
+		return nil
+	}
+
+	migrateQueries := []string{
+		"INSERT INTO wishlists (name, position, is_default, created_at, user_id) " +
+			"SELECT DISTINCT '" + model.DefaultWishlistName + "', 0, true, now(), user_id FROM user_game_favourites " +
+			"ON CONFLICT DO NOTHING;",
+		"INSERT INTO wishlist_items (created_at, wishlist_id, game_id) " +
+			"SELECT now(), wishlists.id, user_game_favourites.game_id FROM user_game_favourites " +
+			"INNER JOIN wishlists ON (user_game_favourites.user_id = wishlists.user_id AND wishlists.is_default) " +
+			"ON CONFLICT DO NOTHING;",
+		"DROP TABLE user_game_favourites;",
+	}
+
+	for _, migrateQuery := range migrateQueries {
+		if _, err := tx.Exec(migrateQuery); err != nil {
+			errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+			errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+			return errWrapped
+		}
+	}
+
+	return nil
+}
+
+func createTableUserPreferences(tx *sqlx.Tx) error {
+	tableName := "UserPreferences"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableUserPreferencesQuery := "CREATE TABLE IF NOT EXISTS user_preferences (" +
+		"user_id bigint NOT NULL PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE," +
+		"region varchar NOT NULL," +
+		"currency varchar NOT NULL," +
+		"language varchar NOT NULL," +
+		"enabled_markets varchar[] NOT NULL DEFAULT '{}'," +
+		"hidden_tags varchar[] NOT NULL DEFAULT '{}'," +
+		"updated_at timestamptz NOT NULL );"
+
+	if _, err := tx.Exec(createTableUserPreferencesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+func createTableGameSimilarities(tx *sqlx.Tx) error {
+	tableName := "GameSimilarities"
+	errWrapMessage := fmt.Sprintf(store.ErrCreateTablesMessageFormat, tableName)
+
+	createTableGameSimilaritiesQuery := "CREATE TABLE IF NOT EXISTS game_similarities (" +
+		"id bigserial NOT NULL PRIMARY KEY," +
+		"score double precision NOT NULL," +
+		"game_id bigint NOT NULL REFERENCES games (id) ON DELETE CASCADE," +
+		"similar_game_id bigint NOT NULL REFERENCES games (id) ON DELETE CASCADE," +
+		"UNIQUE (game_id, similar_game_id) );"
+
+	if _, err := tx.Exec(createTableGameSimilaritiesQuery); err != nil {
+		errWrapped := errors.Wrap(store.ErrUnknownSQL, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
@@ -0,0 +1,328 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type PriceStatsRepository struct {
+	store *Store
+}
+
+// priceStatsWindowLow selects the lowest price, that was active at any moment since the time passed
+// in the parameter. The price recorded before the window start counts too, as it was still active then.
+func priceStatsWindowLow(sinceParam string, alias string) string {
+	return "COALESCE((SELECT MIN(price_history.final_value) FROM price_history " +
+		"WHERE price_history.game_market_price_id = price_stats.game_market_price_id " +
+		"AND price_history.recorded_at >= COALESCE((" +
+		"SELECT MAX(price_history_start.recorded_at) FROM price_history AS price_history_start " +
+		"WHERE price_history_start.game_market_price_id = price_stats.game_market_price_id " +
+		"AND price_history_start.recorded_at <= " + sinceParam + "), '-infinity'::timestamptz)), " +
+		"price_stats.all_time_low) AS " + alias + ", "
+}
+
+// priceStatsAverage selects the average price weighted by time every price was active,
+// the last price is active until the time passed in the parameter. Offers without history
+// fall back to the average of recorded prices.
+func priceStatsAverage(nowParam string, alias string) string {
+	return "COALESCE((SELECT ROUND(SUM(price_periods.final_value * price_periods.seconds) / NULLIF(SUM(price_periods.seconds), 0)) " +
+		"FROM (SELECT price_history.final_value AS final_value, " +
+		"EXTRACT(EPOCH FROM (COALESCE(LEAD(price_history.recorded_at) OVER (ORDER BY price_history.recorded_at, price_history.id), " +
+		nowParam + ") - price_history.recorded_at)) AS seconds " +
+		"FROM price_history " +
+		"WHERE price_history.game_market_price_id = price_stats.game_market_price_id) AS price_periods), " +
+		"price_stats.price_sum / NULLIF(price_stats.samples_count, 0), 0)::integer AS " + alias + ", "
+}
+
+// Window lows use $2, $3 and $4 parameters as starts of 30, 90 and 365 days windows,
+// the average uses $5 as current time
+var priceStatsSelectColumns = "price_stats.all_time_low AS all_time_low, " +
+	"price_stats.all_time_low_at AS all_time_low_at, " +
+	priceStatsWindowLow("$2", "low_30_days") +
+	priceStatsWindowLow("$3", "low_90_days") +
+	priceStatsWindowLow("$4", "low_365_days") +
+	priceStatsAverage("$5", "average_price") +
+	"price_stats.price_sum AS price_sum, " +
+	"price_stats.samples_count AS samples_count, " +
+	"price_stats.sales_count AS sales_count, " +
+	"price_stats.first_seen_at AS first_seen_at, " +
+	"price_stats.updated_at AS updated_at, " +
+
+	"game_market_prices.id AS \"game_market_price.id\", " +
+	"game_market_prices.game_id AS \"game_market_price.game.id\", " +
+	"game_market_prices.final_value AS \"game_market_price.final_value\", " +
+	"game_market_prices.discount_percent AS \"game_market_price.discount_percent\", " +
+	"game_market_prices.delisted AS \"game_market_price.delisted\", " +
+
+	"markets.id AS \"game_market_price.market.id\", " +
+	"markets.name AS \"game_market_price.market.name\" " +
+
+	"FROM price_stats " +
+
+	"LEFT JOIN game_market_prices " +
+	"ON (price_stats.game_market_price_id = game_market_prices.id) " +
+
+	"LEFT JOIN markets " +
+	"ON (game_market_prices.market_id = markets.id) "
+
+const priceRecordSelectColumns = "price_history.id AS id, " +
+	"price_history.final_value AS final_value, " +
+	"price_history.discount_percent AS discount_percent, " +
+	"price_history.recorded_at AS recorded_at, " +
+
+	"game_market_prices.id AS \"game_market_price.id\", " +
+	"game_market_prices.game_id AS \"game_market_price.game.id\" " +
+
+	"FROM price_history " +
+
+	"LEFT JOIN game_market_prices " +
+	"ON (price_history.game_market_price_id = game_market_prices.id) " +
+
+	"LEFT JOIN games " +
+	"ON (game_market_prices.game_id = games.id) "
+
+func priceStatsWindowStarts(now time.Time) (time.Time, time.Time, time.Time) {
+	return now.AddDate(0, 0, -30), now.AddDate(0, 0, -90), now.AddDate(0, 0, -365)
+}
+
+// AddRecord saves current price of the offer to its history and updates aggregated statistics
+func (priceStatsRepository *PriceStatsRepository) AddRecord(gameMarketPrice *model.GameMarketPrice, recordedAt time.Time) error {
+	repositoryName := "PriceStats"
+	methodName := "AddRecord"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tx, err := priceStatsRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	insertHistoryQuery := "INSERT INTO price_history (final_value, discount_percent, recorded_at, game_market_price_id) " +
+		"VALUES ($1, $2, $3, $4);"
+
+	if _, err := tx.Exec(
+		insertHistoryQuery,
+		gameMarketPrice.FinalValue,
+		gameMarketPrice.DiscountPercent,
+		recordedAt,
+		gameMarketPrice.ID,
+	); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	upsertStatsQuery := "INSERT INTO price_stats (game_market_price_id, all_time_low, all_time_low_at, price_sum, samples_count, sales_count, first_seen_at, updated_at) " +
+		"VALUES ($1, $2, $3, $2, 1, 0, $3, $3) " +
+		"ON CONFLICT (game_market_price_id) DO UPDATE SET " +
+		"all_time_low_at = CASE WHEN EXCLUDED.all_time_low < price_stats.all_time_low " +
+		"THEN EXCLUDED.all_time_low_at ELSE price_stats.all_time_low_at END, " +
+		"all_time_low = LEAST(price_stats.all_time_low, EXCLUDED.all_time_low), " +
+		"price_sum = price_stats.price_sum + EXCLUDED.price_sum, " +
+		"samples_count = price_stats.samples_count + 1, " +
+		"updated_at = EXCLUDED.updated_at;"
+
+	if _, err := tx.Exec(
+		upsertStatsQuery,
+		gameMarketPrice.ID,
+		gameMarketPrice.FinalValue,
+		recordedAt,
+	); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// AddSale counts one more sale of the offer. Statistics must already exist, so AddRecord goes first.
+func (priceStatsRepository *PriceStatsRepository) AddSale(gameMarketPrice *model.GameMarketPrice) error {
+	repositoryName := "PriceStats"
+	methodName := "AddSale"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	updateQuery := "UPDATE price_stats SET sales_count = sales_count + 1 WHERE game_market_price_id = $1;"
+
+	countResult, err := priceStatsRepository.store.db.Exec(
+		updateQuery,
+		gameMarketPrice.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+func (priceStatsRepository *PriceStatsRepository) FindByGameMarketPrice(gameMarketPrice *model.GameMarketPrice, now time.Time) (*model.PriceStats, error) {
+	repositoryName := "PriceStats"
+	methodName := "FindByGameMarketPrice"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	since30Days, since90Days, since365Days := priceStatsWindowStarts(now)
+
+	priceStats := &model.PriceStats{}
+	findQuery := "SELECT " + priceStatsSelectColumns +
+		"WHERE price_stats.game_market_price_id = $1 LIMIT 1;"
+
+	if err := priceStatsRepository.store.db.Get(
+		priceStats,
+		findQuery,
+		gameMarketPrice.ID,
+		since30Days,
+		since90Days,
+		since365Days,
+		now,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return priceStats, nil
+}
+
+func (priceStatsRepository *PriceStatsRepository) FindAllByGame(game *model.Game, now time.Time) ([]*model.PriceStats, error) {
+	repositoryName := "PriceStats"
+	methodName := "FindAllByGame"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	since30Days, since90Days, since365Days := priceStatsWindowStarts(now)
+
+	priceStatsAll := []*model.PriceStats{}
+	findQuery := "SELECT " + priceStatsSelectColumns +
+		"WHERE game_market_prices.game_id = $1;"
+
+	if err := priceStatsRepository.store.db.Select(
+		&priceStatsAll,
+		findQuery,
+		game.ID,
+		since30Days,
+		since90Days,
+		since365Days,
+		now,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.PriceStats{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return priceStatsAll, nil
+}
+
+// FindAllByGames returns statistics of all offers of the games in one query
+func (priceStatsRepository *PriceStatsRepository) FindAllByGames(games []*model.Game, now time.Time) ([]*model.PriceStats, error) {
+	repositoryName := "PriceStats"
+	methodName := "FindAllByGames"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	priceStatsAll := []*model.PriceStats{}
+	if len(games) == 0 {
+		return priceStatsAll, nil
+	}
+
+	gameIDs := []int64{}
+	for _, game := range games {
+		gameIDs = append(gameIDs, int64(game.ID))
+	}
+
+	since30Days, since90Days, since365Days := priceStatsWindowStarts(now)
+
+	findQuery := "SELECT " + priceStatsSelectColumns +
+		"WHERE game_market_prices.game_id = ANY($1);"
+
+	if err := priceStatsRepository.store.db.Select(
+		&priceStatsAll,
+		findQuery,
+		pq.Array(gameIDs),
+		since30Days,
+		since90Days,
+		since365Days,
+		now,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.PriceStats{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return priceStatsAll, nil
+}
+
+// FindAllRecordsByGame returns price history of all offers of the game ordered by time
+func (priceStatsRepository *PriceStatsRepository) FindAllRecordsByGame(game *model.Game) ([]*model.PriceRecord, error) {
+	repositoryName := "PriceStats"
+	methodName := "FindAllRecordsByGame"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	priceRecords := []*model.PriceRecord{}
+	findQuery := "SELECT " + priceRecordSelectColumns +
+		"WHERE games.id = $1 " +
+		"ORDER BY price_history.recorded_at, price_history.id;"
+
+	if err := priceStatsRepository.store.db.Select(
+		&priceRecords,
+		findQuery,
+		game.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.PriceRecord{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return priceRecords, nil
+}
+
+// FindAllRecordsByPublisher returns price history of all offers of the publisher's games ordered by time
+func (priceStatsRepository *PriceStatsRepository) FindAllRecordsByPublisher(publisher *model.Publisher) ([]*model.PriceRecord, error) {
+	repositoryName := "PriceStats"
+	methodName := "FindAllRecordsByPublisher"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	priceRecords := []*model.PriceRecord{}
+	findQuery := "SELECT " + priceRecordSelectColumns +
+		"WHERE games.publisher_id = $1 " +
+		"ORDER BY price_history.recorded_at, price_history.id;"
+
+	if err := priceStatsRepository.store.db.Select(
+		&priceRecords,
+		findQuery,
+		publisher.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.PriceRecord{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return priceRecords, nil
+}
@@ -60,6 +60,28 @@ func (publisherRepository *PublisherRepository) FindBy(columnName string, value
 	return publisher, nil
 }
 
+func (publisherRepository *PublisherRepository) FindAll() ([]*model.Publisher, error) {
+	repositoryName := "Publisher"
+	methodName := "FindAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	publishers := []*model.Publisher{}
+	findQuery := "SELECT * FROM publishers ORDER BY id;"
+
+	if err := publisherRepository.store.db.Select(
+		&publishers,
+		findQuery,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.Publisher{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return publishers, nil
+}
+
 func (publisherRepository *PublisherRepository) Update(newPublisher *model.Publisher) error {
 	repositoryName := "Publisher"
 	methodName := "Update"
@@ -0,0 +1,111 @@
+package sqlstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+// RecoveryCodeRepository keeps only hashes of the codes
+type RecoveryCodeRepository struct {
+	store *Store
+}
+
+// ReplaceAll deletes all codes of the user, used ones too, and saves new ones
+func (recoveryCodeRepository *RecoveryCodeRepository) ReplaceAll(user *model.User, codeHashes []string) error {
+	repositoryName := "RecoveryCode"
+	methodName := "ReplaceAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tx, err := recoveryCodeRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if err := replaceRecoveryCodes(tx, user, codeHashes); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes is shared with enabling TOTP, so the codes are saved in the same transaction
+func replaceRecoveryCodes(tx *sqlx.Tx, user *model.User, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1;", user.ID); err != nil {
+		return errors.Wrap(store.ErrUnknownSQL, err.Error())
+	}
+
+	insertQuery := "INSERT INTO user_recovery_codes (code_hash, user_id) VALUES ($1, $2);"
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec(insertQuery, codeHash, user.ID); err != nil {
+			return errors.Wrap(store.ErrUnknownSQL, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Use marks the code as used, returns ErrNotFound if there is no such unused code
+func (recoveryCodeRepository *RecoveryCodeRepository) Use(user *model.User, codeHash string, usedAt time.Time) error {
+	repositoryName := "RecoveryCode"
+	methodName := "Use"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	useQuery := "UPDATE user_recovery_codes " +
+		"SET used_at = $1 " +
+		"WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL;"
+
+	countResult, err := recoveryCodeRepository.store.db.Exec(
+		useQuery,
+		usedAt,
+		user.ID,
+		codeHash,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+func (recoveryCodeRepository *RecoveryCodeRepository) CountUnused(user *model.User) (int, error) {
+	repositoryName := "RecoveryCode"
+	methodName := "CountUnused"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	var count int
+	countQuery := "SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL;"
+
+	if err := recoveryCodeRepository.store.db.Get(
+		&count,
+		countQuery,
+		user.ID,
+	); err != nil {
+		return 0, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return count, nil
+}
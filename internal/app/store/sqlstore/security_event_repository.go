@@ -0,0 +1,85 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type SecurityEventRepository struct {
+	store *Store
+}
+
+func (securityEventRepository *SecurityEventRepository) Create(securityEvent *model.SecurityEvent) error {
+	repositoryName := "SecurityEvent"
+	methodName := "Create"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	createQuery := "INSERT INTO security_events (type, session_id, ip, user_agent, created_at, user_id) " +
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;"
+
+	if err := securityEventRepository.store.db.Get(
+		&securityEvent.ID,
+		createQuery,
+		securityEvent.Type,
+		securityEvent.SessionID,
+		securityEvent.IP,
+		securityEvent.UserAgent,
+		securityEvent.CreatedAt,
+		securityEvent.User.ID,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// FindAllByUser returns the latest events of the user, newest first
+func (securityEventRepository *SecurityEventRepository) FindAllByUser(user *model.User, limit int) ([]*model.SecurityEvent, error) {
+	repositoryName := "SecurityEvent"
+	methodName := "FindAllByUser"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	securityEvents := []*model.SecurityEvent{}
+
+	findQuery := "SELECT " +
+		"security_events.id AS id, " +
+		"security_events.type AS type, " +
+		"security_events.session_id AS session_id, " +
+		"security_events.ip AS ip, " +
+		"security_events.user_agent AS user_agent, " +
+		"security_events.created_at AS created_at, " +
+
+		"users.id AS \"user.id\", " +
+		"users.username AS \"user.username\", " +
+		"users.email AS \"user.email\", " +
+		"users.email_verified AS \"user.email_verified\" " +
+
+		"FROM security_events " +
+
+		"LEFT JOIN users " +
+		"ON (security_events.user_id = users.id) " +
+
+		"WHERE security_events.user_id = $1 " +
+		"ORDER BY security_events.created_at DESC, security_events.id DESC " +
+		"LIMIT $2;"
+
+	if err := securityEventRepository.store.db.Select(
+		&securityEvents,
+		findQuery,
+		user.ID,
+		limit,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.SecurityEvent{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return securityEvents, nil
+}
@@ -2,9 +2,13 @@ package sqlstore
 
 import (
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
+// pqUniqueViolation is the Postgres error code of broken UNIQUE constraint
+const pqUniqueViolation = "23505"
+
 var (
 	pointsByPlace = map[int]int{
 		1:  25,
@@ -31,6 +35,17 @@ type Store struct {
 	gameTagRepository             *GameTagRepository
 	gameMarketPriceRepository     *GameMarketPriceRepository
 	marketBlacklistItemRepository *MarketBlacklistItemRepository
+	giveawayRepository            *GiveawayRepository
+	priceStatsRepository          *PriceStatsRepository
+	securityEventRepository       *SecurityEventRepository
+	userTOTPRepository            *UserTOTPRepository
+	recoveryCodeRepository        *RecoveryCodeRepository
+	userIdentityRepository        *UserIdentityRepository
+	userOwnedGameRepository       *UserOwnedGameRepository
+	wishlistRepository            *WishlistRepository
+	wishlistItemRepository        *WishlistItemRepository
+	userPreferencesRepository     *UserPreferencesRepository
+	gameSimilarityRepository      *GameSimilarityRepository
 }
 
 func New(db *sqlx.DB) (*Store, error) {
@@ -156,3 +171,140 @@ func (st *Store) MarketBlacklist() store.MarketBlacklistItemRepository {
 
 	return st.marketBlacklistItemRepository
 }
+
+func (st *Store) Giveaways() store.GiveawayRepository {
+	if st.giveawayRepository != nil {
+		return st.giveawayRepository
+	}
+
+	st.giveawayRepository = &GiveawayRepository{
+		store: st,
+	}
+
+	return st.giveawayRepository
+}
+
+func (st *Store) PriceStats() store.PriceStatsRepository {
+	if st.priceStatsRepository != nil {
+		return st.priceStatsRepository
+	}
+
+	st.priceStatsRepository = &PriceStatsRepository{
+		store: st,
+	}
+
+	return st.priceStatsRepository
+}
+
+func (st *Store) SecurityEvents() store.SecurityEventRepository {
+	if st.securityEventRepository != nil {
+		return st.securityEventRepository
+	}
+
+	st.securityEventRepository = &SecurityEventRepository{
+		store: st,
+	}
+
+	return st.securityEventRepository
+}
+
+func (st *Store) UserTOTP() store.UserTOTPRepository {
+	if st.userTOTPRepository != nil {
+		return st.userTOTPRepository
+	}
+
+	st.userTOTPRepository = &UserTOTPRepository{
+		store: st,
+	}
+
+	return st.userTOTPRepository
+}
+
+func (st *Store) RecoveryCodes() store.RecoveryCodeRepository {
+	if st.recoveryCodeRepository != nil {
+		return st.recoveryCodeRepository
+	}
+
+	st.recoveryCodeRepository = &RecoveryCodeRepository{
+		store: st,
+	}
+
+	return st.recoveryCodeRepository
+}
+
+func (st *Store) UserIdentities() store.UserIdentityRepository {
+	if st.userIdentityRepository != nil {
+		return st.userIdentityRepository
+	}
+
+	st.userIdentityRepository = &UserIdentityRepository{
+		store: st,
+	}
+
+	return st.userIdentityRepository
+}
+
+func (st *Store) UserOwnedGames() store.UserOwnedGameRepository {
+	if st.userOwnedGameRepository != nil {
+		return st.userOwnedGameRepository
+	}
+
+	st.userOwnedGameRepository = &UserOwnedGameRepository{
+		store: st,
+	}
+
+	return st.userOwnedGameRepository
+}
+
+func (st *Store) Wishlists() store.WishlistRepository {
+	if st.wishlistRepository != nil {
+		return st.wishlistRepository
+	}
+
+	st.wishlistRepository = &WishlistRepository{
+		store: st,
+	}
+
+	return st.wishlistRepository
+}
+
+func (st *Store) WishlistItems() store.WishlistItemRepository {
+	if st.wishlistItemRepository != nil {
+		return st.wishlistItemRepository
+	}
+
+	st.wishlistItemRepository = &WishlistItemRepository{
+		store: st,
+	}
+
+	return st.wishlistItemRepository
+}
+
+func (st *Store) UserPreferences() store.UserPreferencesRepository {
+	if st.userPreferencesRepository != nil {
+		return st.userPreferencesRepository
+	}
+
+	st.userPreferencesRepository = &UserPreferencesRepository{
+		store: st,
+	}
+
+	return st.userPreferencesRepository
+}
+
+func (st *Store) GameSimilarities() store.GameSimilarityRepository {
+	if st.gameSimilarityRepository != nil {
+		return st.gameSimilarityRepository
+	}
+
+	st.gameSimilarityRepository = &GameSimilarityRepository{
+		store: st,
+	}
+
+	return st.gameSimilarityRepository
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == pqUniqueViolation
+}
@@ -60,6 +60,28 @@ func (tagRepository *TagRepository) FindBy(columnName string, value interface{})
 	return tag, nil
 }
 
+func (tagRepository *TagRepository) FindAll() ([]*model.Tag, error) {
+	repositoryName := "Tag"
+	methodName := "FindAll"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tags := []*model.Tag{}
+	findQuery := "SELECT * FROM tags ORDER BY id;"
+
+	if err := tagRepository.store.db.Select(
+		&tags,
+		findQuery,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.Tag{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return tags, nil
+}
+
 func (tagRepository *TagRepository) FindAllByGame(game *model.Game) ([]*model.Tag, error) {
 	repositoryName := "Tag"
 	methodName := "FindAllByGame"
@@ -3,6 +3,7 @@ package sqlstore
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -10,71 +11,76 @@ import (
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
+// UserGameFavouriteRepository keeps favourites as items of the user's default wishlist
 type UserGameFavouriteRepository struct {
 	store *Store
 }
 
+const userGameFavouriteSelectColumns = "wishlist_items.id AS id, " +
+
+	"games.id AS \"game.id\", " +
+	"games.header_image_url AS \"game.header_image_url\", " +
+	"games.name AS \"game.name\", " +
+	"games.description AS \"game.description\", " +
+
+	"publishers.id AS \"game.publisher.id\", " +
+	"publishers.name AS \"game.publisher.name\", " +
+
+	"users.id AS \"user.id\", " +
+	"users.username AS \"user.username\", " +
+	"users.email AS \"user.email\" " +
+
+	"FROM wishlist_items " +
+
+	"INNER JOIN wishlists " +
+	"ON (wishlist_items.wishlist_id = wishlists.id AND wishlists.is_default) " +
+
+	"LEFT JOIN games " +
+	"ON (wishlist_items.game_id = games.id) " +
+
+	"LEFT JOIN publishers " +
+	"ON (games.publisher_id = publishers.id) " +
+
+	"LEFT JOIN users " +
+	"ON (wishlists.user_id = users.id) "
+
 func (userGameFavouriteRepository *UserGameFavouriteRepository) Create(userGameFavourite *model.UserGameFavourite) error {
 	repositoryName := "UserGameFavourite"
 	methodName := "Create"
 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
 
-	createQuery := "INSERT INTO user_game_favourites (game_id, user_id) VALUES ($1, $2) RETURNING id;"
+	wishlist, err := userGameFavouriteRepository.store.Wishlists().FindOrCreateDefault(userGameFavourite.User)
+	if err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
 
-	if err := userGameFavouriteRepository.store.db.Get(
-		&userGameFavourite.ID,
-		createQuery,
-		userGameFavourite.Game.ID,
-		userGameFavourite.User.ID,
-	); err != nil {
-		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	wishlistItem := &model.WishlistItem{
+		CreatedAt: time.Now(),
+		Wishlist:  wishlist,
+		Game:      userGameFavourite.Game,
 	}
 
+	if err := userGameFavouriteRepository.store.WishlistItems().Create(wishlistItem); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	userGameFavourite.ID = wishlistItem.ID
+
 	return nil
 }
 
 func (userGameFavouriteRepository *UserGameFavouriteRepository) Find(id uint64) (*model.UserGameFavourite, error) {
-	return userGameFavouriteRepository.FindBy("id", id)
-}
-
-func (userGameFavouriteRepository *UserGameFavouriteRepository) FindBy(columnName string, value interface{}) (*model.UserGameFavourite, error) {
 	repositoryName := "UserGameFavourite"
-	methodName := "FindBy"
+	methodName := "Find"
 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
 
 	userGameFavourite := &model.UserGameFavourite{}
-	findQuery := fmt.Sprintf("SELECT "+
-		"user_game_favourites.id AS id, "+
-
-		"games.id AS \"game.id\", "+
-		"games.header_image_url AS \"game.header_image_url\", "+
-		"games.name AS \"game.name\", "+
-		"games.description AS \"game.description\", "+
-
-		"publishers.id AS \"game.publisher.id\", "+
-		"publishers.name AS \"game.publisher.name\", "+
-
-		"users.id AS \"user.id\", "+
-		"users.username AS \"user.username\", "+
-		"users.email AS \"user.email\" "+
-
-		"FROM user_game_favourites "+
-
-		"LEFT JOIN games "+
-		"ON (user_game_favourites.game_id = games.id) "+
-
-		"LEFT JOIN publishers "+
-		"ON (games.publisher_id = publishers.id) "+
-
-		"LEFT JOIN users "+
-		"ON (user_game_favourites.user_id = users.id) "+
-
-		"WHERE user_game_favourites.%s = $1 LIMIT 1;", columnName)
+	findQuery := "SELECT " + userGameFavouriteSelectColumns + "WHERE wishlist_items.id = $1 LIMIT 1;"
 
 	if err := userGameFavouriteRepository.store.db.Get(
 		userGameFavourite,
 		findQuery,
-		value,
+		id,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
@@ -92,34 +98,9 @@ func (userGameFavouriteRepository *UserGameFavouriteRepository) FindByUserGame(u
 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
 
 	userGameFavourite := &model.UserGameFavourite{}
-	findQuery := "SELECT " +
-		"user_game_favourites.id AS id, " +
-
-		"games.id AS \"game.id\", " +
-		"games.header_image_url AS \"game.header_image_url\", " +
-		"games.name AS \"game.name\", " +
-		"games.description AS \"game.description\", " +
-
-		"publishers.id AS \"game.publisher.id\", " +
-		"publishers.name AS \"game.publisher.name\", " +
-
-		"users.id AS \"user.id\", " +
-		"users.username AS \"user.username\", " +
-		"users.email AS \"user.email\" " +
-
-		"FROM user_game_favourites " +
-
-		"LEFT JOIN games " +
-		"ON (user_game_favourites.game_id = games.id) " +
-
-		"LEFT JOIN publishers " +
-		"ON (games.publisher_id = publishers.id) " +
-
-		"LEFT JOIN users " +
-		"ON (user_game_favourites.user_id = users.id) " +
-
-		"WHERE user_game_favourites.user_id = $1 " +
-		"AND user_game_favourites.game_id = $2 LIMIT 1;"
+	findQuery := "SELECT " + userGameFavouriteSelectColumns +
+		"WHERE wishlists.user_id = $1 " +
+		"AND wishlist_items.game_id = $2 LIMIT 1;"
 
 	if err := userGameFavouriteRepository.store.db.Get(
 		userGameFavourite,
@@ -137,44 +118,15 @@ func (userGameFavouriteRepository *UserGameFavouriteRepository) FindByUserGame(u
 	return userGameFavourite, nil
 }
 
-// func (userGameFavouriteRepository *UserGameFavouriteRepository) Update(newUserGameFavourite *model.UserGameFavourite) error {
-// 	repositoryName := "UserGameFavourite"
-// 	methodName := "Update"
-// 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
-//
-// 	updateQuery := "UPDATE user_game_favourites " +
-// 		"SET game_id = :game.id, " +
-// 		"user_id = :user.id " +
-// 		"WHERE id = :id;"
-//
-// 	countResult, err := userGameFavouriteRepository.store.db.NamedExec(
-// 		updateQuery,
-// 		newUserGameFavourite,
-// 	)
-//
-// 	if err != nil {
-// 		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
-// 	}
-//
-// 	count, err := countResult.RowsAffected()
-//
-// 	if err != nil {
-// 		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
-// 	}
-//
-// 	if count == 0 {
-// 		return errors.Wrap(store.ErrNotFound, errWrapMessage)
-// 	}
-//
-// 	return nil
-// }
-
 func (userGameFavouriteRepository *UserGameFavouriteRepository) Delete(id uint64) error {
 	repositoryName := "UserGameFavourite"
 	methodName := "Delete"
 	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
 
-	deleteQuery := "DELETE FROM user_game_favourites WHERE id = $1;"
+	deleteQuery := "DELETE FROM wishlist_items " +
+		"USING wishlists " +
+		"WHERE wishlist_items.wishlist_id = wishlists.id AND wishlists.is_default " +
+		"AND wishlist_items.id = $1;"
 
 	countResult, err := userGameFavouriteRepository.store.db.Exec(
 		deleteQuery,
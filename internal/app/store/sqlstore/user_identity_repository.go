@@ -0,0 +1,136 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type UserIdentityRepository struct {
+	store *Store
+}
+
+const userIdentitySelectColumns = "user_identities.id AS id, " +
+	"user_identities.provider AS provider, " +
+	"user_identities.subject AS subject, " +
+	"user_identities.email AS email, " +
+	"user_identities.created_at AS created_at, " +
+
+	"users.id AS \"user.id\", " +
+	"users.username AS \"user.username\", " +
+	"users.email AS \"user.email\", " +
+	"users.email_verified AS \"user.email_verified\", " +
+	"users.role AS \"user.role\", " +
+	"users.banned AS \"user.banned\" " +
+
+	"FROM user_identities " +
+
+	"LEFT JOIN users " +
+	"ON (user_identities.user_id = users.id) "
+
+func (userIdentityRepository *UserIdentityRepository) Create(userIdentity *model.UserIdentity) error {
+	repositoryName := "UserIdentity"
+	methodName := "Create"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	createQuery := "INSERT INTO user_identities (provider, subject, email, created_at, user_id) " +
+		"VALUES ($1, $2, $3, $4, $5) RETURNING id;"
+
+	if err := userIdentityRepository.store.db.Get(
+		&userIdentity.ID,
+		createQuery,
+		userIdentity.Provider,
+		userIdentity.Subject,
+		userIdentity.Email,
+		userIdentity.CreatedAt,
+		userIdentity.User.ID,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (userIdentityRepository *UserIdentityRepository) FindByProviderSubject(provider string, subject string) (*model.UserIdentity, error) {
+	repositoryName := "UserIdentity"
+	methodName := "FindByProviderSubject"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	userIdentity := &model.UserIdentity{}
+	findQuery := "SELECT " + userIdentitySelectColumns +
+		"WHERE user_identities.provider = $1 AND user_identities.subject = $2 LIMIT 1;"
+
+	if err := userIdentityRepository.store.db.Get(
+		userIdentity,
+		findQuery,
+		provider,
+		subject,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return userIdentity, nil
+}
+
+func (userIdentityRepository *UserIdentityRepository) FindAllByUser(user *model.User) ([]*model.UserIdentity, error) {
+	repositoryName := "UserIdentity"
+	methodName := "FindAllByUser"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	userIdentities := []*model.UserIdentity{}
+	findQuery := "SELECT " + userIdentitySelectColumns +
+		"WHERE user_identities.user_id = $1 " +
+		"ORDER BY user_identities.provider;"
+
+	if err := userIdentityRepository.store.db.Select(
+		&userIdentities,
+		findQuery,
+		user.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.UserIdentity{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return userIdentities, nil
+}
+
+func (userIdentityRepository *UserIdentityRepository) DeleteByUserProvider(user *model.User, provider string) error {
+	repositoryName := "UserIdentity"
+	methodName := "DeleteByUserProvider"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	deleteQuery := "DELETE FROM user_identities WHERE user_id = $1 AND provider = $2;"
+
+	countResult, err := userIdentityRepository.store.db.Exec(
+		deleteQuery,
+		user.ID,
+		provider,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type UserOwnedGameRepository struct {
+	store *Store
+}
+
+// Market of owned game may be unknown, so market columns are coalesced and
+// the market is dropped after scanning if its ID is zero
+const userOwnedGameSelectColumns = "user_owned_games.id AS id, " +
+	"user_owned_games.source AS source, " +
+	"user_owned_games.created_at AS created_at, " +
+
+	"games.id AS \"game.id\", " +
+	"games.header_image_url AS \"game.header_image_url\", " +
+	"games.name AS \"game.name\", " +
+	"games.description AS \"game.description\", " +
+	"TO_CHAR(games.release_date, 'dd.MM.YYYY') AS \"game.release_date\", " +
+
+	"publishers.id AS \"game.publisher.id\", " +
+	"publishers.name AS \"game.publisher.name\", " +
+
+	"COALESCE(markets.id, 0) AS \"market.id\", " +
+	"COALESCE(markets.name, '') AS \"market.name\", " +
+
+	"users.id AS \"user.id\", " +
+	"users.username AS \"user.username\", " +
+	"users.email AS \"user.email\" " +
+
+	"FROM user_owned_games " +
+
+	"LEFT JOIN games " +
+	"ON (user_owned_games.game_id = games.id) " +
+
+	"LEFT JOIN publishers " +
+	"ON (games.publisher_id = publishers.id) " +
+
+	"LEFT JOIN markets " +
+	"ON (user_owned_games.market_id = markets.id) " +
+
+	"LEFT JOIN users " +
+	"ON (user_owned_games.user_id = users.id) "
+
+// Create keeps the existing record if the user already owns the game, so imports can be repeated
+func (userOwnedGameRepository *UserOwnedGameRepository) Create(userOwnedGame *model.UserOwnedGame) error {
+	repositoryName := "UserOwnedGame"
+	methodName := "Create"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	var marketID interface{}
+	if userOwnedGame.Market != nil {
+		marketID = userOwnedGame.Market.ID
+	}
+
+	createQuery := "INSERT INTO user_owned_games (source, created_at, user_id, game_id, market_id) " +
+		"VALUES ($1, $2, $3, $4, $5) " +
+		"ON CONFLICT(user_id, game_id) DO UPDATE SET " +
+		"source = user_owned_games.source RETURNING id;"
+
+	if err := userOwnedGameRepository.store.db.Get(
+		&userOwnedGame.ID,
+		createQuery,
+		userOwnedGame.Source,
+		userOwnedGame.CreatedAt,
+		userOwnedGame.User.ID,
+		userOwnedGame.Game.ID,
+		marketID,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (userOwnedGameRepository *UserOwnedGameRepository) Find(id uint64) (*model.UserOwnedGame, error) {
+	repositoryName := "UserOwnedGame"
+	methodName := "Find"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	userOwnedGame := &model.UserOwnedGame{}
+	findQuery := "SELECT " + userOwnedGameSelectColumns + "WHERE user_owned_games.id = $1 LIMIT 1;"
+
+	if err := userOwnedGameRepository.store.db.Get(
+		userOwnedGame,
+		findQuery,
+		id,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if userOwnedGame.Market.ID == 0 {
+		userOwnedGame.Market = nil
+	}
+
+	return userOwnedGame, nil
+}
+
+func (userOwnedGameRepository *UserOwnedGameRepository) FindAllByUser(user *model.User) ([]*model.UserOwnedGame, error) {
+	repositoryName := "UserOwnedGame"
+	methodName := "FindAllByUser"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	userOwnedGames := []*model.UserOwnedGame{}
+	findQuery := "SELECT " + userOwnedGameSelectColumns +
+		"WHERE user_owned_games.user_id = $1 " +
+		"ORDER BY games.name;"
+
+	if err := userOwnedGameRepository.store.db.Select(
+		&userOwnedGames,
+		findQuery,
+		user.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.UserOwnedGame{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	for _, userOwnedGame := range userOwnedGames {
+		if userOwnedGame.Market.ID == 0 {
+			userOwnedGame.Market = nil
+		}
+	}
+
+	return userOwnedGames, nil
+}
+
+// Update changes source and market, the game of the record stays the same
+func (userOwnedGameRepository *UserOwnedGameRepository) Update(userOwnedGame *model.UserOwnedGame) error {
+	repositoryName := "UserOwnedGame"
+	methodName := "Update"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	var marketID interface{}
+	if userOwnedGame.Market != nil {
+		marketID = userOwnedGame.Market.ID
+	}
+
+	updateQuery := "UPDATE user_owned_games SET source = $1, market_id = $2 WHERE id = $3;"
+
+	countResult, err := userOwnedGameRepository.store.db.Exec(
+		updateQuery,
+		userOwnedGame.Source,
+		marketID,
+		userOwnedGame.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+func (userOwnedGameRepository *UserOwnedGameRepository) Delete(id uint64) error {
+	repositoryName := "UserOwnedGame"
+	methodName := "Delete"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	deleteQuery := "DELETE FROM user_owned_games WHERE id = $1;"
+
+	countResult, err := userOwnedGameRepository.store.db.Exec(
+		deleteQuery,
+		id,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type UserPreferencesRepository struct {
+	store *Store
+}
+
+// FindByUser returns ErrNotFound if the user hasn't saved preferences yet
+func (userPreferencesRepository *UserPreferencesRepository) FindByUser(user *model.User) (*model.UserPreferences, error) {
+	repositoryName := "UserPreferences"
+	methodName := "FindByUser"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	userPreferences := &model.UserPreferences{
+		User: user,
+	}
+	findQuery := "SELECT region, currency, language, enabled_markets, hidden_tags, updated_at " +
+		"FROM user_preferences WHERE user_id = $1 LIMIT 1;"
+
+	// Arrays can't be scanned into struct fields by name
+	if err := userPreferencesRepository.store.db.QueryRowx(
+		findQuery,
+		user.ID,
+	).Scan(
+		&userPreferences.Region,
+		&userPreferences.Currency,
+		&userPreferences.Language,
+		pq.Array(&userPreferences.EnabledMarkets),
+		pq.Array(&userPreferences.HiddenTags),
+		&userPreferences.UpdatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return userPreferences, nil
+}
+
+// Save creates preferences of the user or replaces the existing ones
+func (userPreferencesRepository *UserPreferencesRepository) Save(userPreferences *model.UserPreferences) error {
+	repositoryName := "UserPreferences"
+	methodName := "Save"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := userPreferences.Validate(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	saveQuery := "INSERT INTO user_preferences " +
+		"(user_id, region, currency, language, enabled_markets, hidden_tags, updated_at) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) " +
+		"ON CONFLICT (user_id) DO UPDATE SET " +
+		"region = EXCLUDED.region, " +
+		"currency = EXCLUDED.currency, " +
+		"language = EXCLUDED.language, " +
+		"enabled_markets = EXCLUDED.enabled_markets, " +
+		"hidden_tags = EXCLUDED.hidden_tags, " +
+		"updated_at = EXCLUDED.updated_at;"
+
+	if _, err := userPreferencesRepository.store.db.Exec(
+		saveQuery,
+		userPreferences.User.ID,
+		userPreferences.Region,
+		userPreferences.Currency,
+		userPreferences.Language,
+		pq.Array(userPreferences.EnabledMarkets),
+		pq.Array(userPreferences.HiddenTags),
+		userPreferences.UpdatedAt,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
@@ -28,13 +28,41 @@ func (userRepository *UserRepository) Create(user *model.User) error {
 		return errors.Wrap(err, errWrapMessage)
 	}
 
-	createQuery := "INSERT INTO users (username, email, encrypted_password) VALUES ($1, $2, $3) " +
+	createQuery := "INSERT INTO users (username, email, encrypted_password, role) VALUES ($1, $2, $3, $4) " +
 		"ON CONFLICT(username) DO UPDATE SET username = EXCLUDED.username RETURNING id;"
 
 	if err := userRepository.store.db.Get(
 		&user.ID,
 		createQuery,
-		user.Username, user.Email, user.EncryptedPassword,
+		user.Username, user.Email, user.EncryptedPassword, user.Role,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// CreateWithoutPassword creates user, that logs in only through external identity providers
+func (userRepository *UserRepository) CreateWithoutPassword(user *model.User) error {
+	repositoryName := "User"
+	methodName := "CreateWithoutPassword"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := user.ValidateWithoutPassword(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	user.Password = ""
+	if err := user.BeforeCreate(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	createQuery := "INSERT INTO users (username, email, encrypted_password, role) VALUES ($1, $2, '', $3) RETURNING id;"
+
+	if err := userRepository.store.db.Get(
+		&user.ID,
+		createQuery,
+		user.Username, user.Email, user.Role,
 	); err != nil {
 		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
 	}
@@ -78,8 +106,9 @@ func (userRepository *UserRepository) UpdateEmail(newEmail string, userId uint64
 		return errors.Wrap(errors.Wrap(model.ErrValidationFailed, err.Error()), errWrapMessage)
 	}
 
+	// New email isn't verified yet
 	updateEmailQuery := "UPDATE users " +
-		"SET email = $1 " +
+		"SET email = $1, email_verified = false " +
 		"WHERE id = $2;"
 
 	countResult, err := userRepository.store.db.Exec(
@@ -105,6 +134,38 @@ func (userRepository *UserRepository) UpdateEmail(newEmail string, userId uint64
 	return nil
 }
 
+func (userRepository *UserRepository) UpdateEmailVerified(emailVerified bool, userId uint64) error {
+	repositoryName := "User"
+	methodName := "UpdateEmailVerified"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	updateEmailVerifiedQuery := "UPDATE users " +
+		"SET email_verified = $1 " +
+		"WHERE id = $2;"
+
+	countResult, err := userRepository.store.db.Exec(
+		updateEmailVerifiedQuery,
+		emailVerified,
+		userId,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
 func (userRepository *UserRepository) UpdatePassword(newPassword string, userId uint64) error {
 	repositoryName := "User"
 	methodName := "UpdatePassword"
@@ -145,6 +206,74 @@ func (userRepository *UserRepository) UpdatePassword(newPassword string, userId
 	return nil
 }
 
+func (userRepository *UserRepository) UpdateRole(newRole string, userId uint64) error {
+	repositoryName := "User"
+	methodName := "UpdateRole"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := validation.Validate(&newRole, model.ValidationRulesRole...); err != nil {
+		return errors.Wrap(errors.Wrap(model.ErrValidationFailed, err.Error()), errWrapMessage)
+	}
+
+	updateRoleQuery := "UPDATE users " +
+		"SET role = $1 " +
+		"WHERE id = $2;"
+
+	countResult, err := userRepository.store.db.Exec(
+		updateRoleQuery,
+		newRole,
+		userId,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+func (userRepository *UserRepository) UpdateBanned(banned bool, userId uint64) error {
+	repositoryName := "User"
+	methodName := "UpdateBanned"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	updateBannedQuery := "UPDATE users " +
+		"SET banned = $1 " +
+		"WHERE id = $2;"
+
+	countResult, err := userRepository.store.db.Exec(
+		updateBannedQuery,
+		banned,
+		userId,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
 func (userRepository *UserRepository) Delete(id uint64) error {
 	repositoryName := "User"
 	methodName := "Delete"
@@ -0,0 +1,186 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type UserTOTPRepository struct {
+	store *Store
+}
+
+// Save starts enrolment again: previous secret of the user is replaced and TOTP is disabled
+func (userTOTPRepository *UserTOTPRepository) Save(userTOTP *model.UserTOTP) error {
+	repositoryName := "UserTOTP"
+	methodName := "Save"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	saveQuery := "INSERT INTO user_totp (user_id, secret, enabled, last_used_step, created_at) " +
+		"VALUES ($1, $2, false, 0, $3) " +
+		"ON CONFLICT (user_id) DO UPDATE SET " +
+		"secret = EXCLUDED.secret, " +
+		"enabled = false, " +
+		"last_used_step = 0, " +
+		"created_at = EXCLUDED.created_at;"
+
+	if _, err := userTOTPRepository.store.db.Exec(
+		saveQuery,
+		userTOTP.User.ID,
+		userTOTP.Secret,
+		userTOTP.CreatedAt,
+	); err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	userTOTP.Enabled = false
+	userTOTP.LastUsedStep = 0
+
+	return nil
+}
+
+func (userTOTPRepository *UserTOTPRepository) FindByUser(user *model.User) (*model.UserTOTP, error) {
+	repositoryName := "UserTOTP"
+	methodName := "FindByUser"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	userTOTP := &model.UserTOTP{}
+	findQuery := "SELECT secret, enabled, last_used_step, created_at FROM user_totp WHERE user_id = $1 LIMIT 1;"
+
+	if err := userTOTPRepository.store.db.Get(
+		userTOTP,
+		findQuery,
+		user.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	userTOTP.User = user
+
+	return userTOTP, nil
+}
+
+// UseStep remembers the time step of accepted code.
+// Returns ErrNotFound if the step isn't newer than the last used one, so one code can't be used twice.
+func (userTOTPRepository *UserTOTPRepository) UseStep(user *model.User, step int64) error {
+	repositoryName := "UserTOTP"
+	methodName := "UseStep"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	useStepQuery := "UPDATE user_totp " +
+		"SET last_used_step = $1 " +
+		"WHERE user_id = $2 AND last_used_step < $1;"
+
+	countResult, err := userTOTPRepository.store.db.Exec(
+		useStepQuery,
+		step,
+		user.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+// Enable finishes enrolment together with saving recovery codes, so TOTP can't be enabled without them.
+// Returns ErrNotFound if TOTP of the user isn't set up or is already enabled.
+func (userTOTPRepository *UserTOTPRepository) Enable(user *model.User, recoveryCodeHashes []string) error {
+	repositoryName := "UserTOTP"
+	methodName := "Enable"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tx, err := userTOTPRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	countResult, err := tx.Exec("UPDATE user_totp SET enabled = true WHERE user_id = $1 AND NOT enabled;", user.ID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		tx.Rollback()
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	if err := replaceRecoveryCodes(tx, user, recoveryCodeHashes); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// Delete disables TOTP of the user together with the recovery codes
+func (userTOTPRepository *UserTOTPRepository) Delete(user *model.User) error {
+	repositoryName := "UserTOTP"
+	methodName := "Delete"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tx, err := userTOTPRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	countResult, err := tx.Exec("DELETE FROM user_totp WHERE user_id = $1;", user.ID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		tx.Rollback()
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1;", user.ID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
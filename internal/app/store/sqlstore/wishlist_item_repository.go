@@ -0,0 +1,202 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type WishlistItemRepository struct {
+	store *Store
+}
+
+const wishlistItemSelectColumns = "wishlist_items.id AS id, " +
+	"wishlist_items.priority AS priority, " +
+	"wishlist_items.note AS note, " +
+	"wishlist_items.target_price AS target_price, " +
+	"wishlist_items.created_at AS created_at, " +
+
+	"wishlists.id AS \"wishlist.id\", " +
+	"wishlists.name AS \"wishlist.name\", " +
+	"wishlists.position AS \"wishlist.position\", " +
+	"wishlists.is_default AS \"wishlist.is_default\", " +
+	"wishlists.is_public AS \"wishlist.is_public\", " +
+	"wishlists.public_slug AS \"wishlist.public_slug\", " +
+	"wishlists.created_at AS \"wishlist.created_at\", " +
+	"wishlists.user_id AS \"wishlist.user.id\", " +
+
+	"games.id AS \"game.id\", " +
+	"games.header_image_url AS \"game.header_image_url\", " +
+	"games.name AS \"game.name\", " +
+	"games.description AS \"game.description\", " +
+	"TO_CHAR(games.release_date, 'dd.MM.YYYY') AS \"game.release_date\", " +
+
+	"publishers.id AS \"game.publisher.id\", " +
+	"publishers.name AS \"game.publisher.name\" " +
+
+	"FROM wishlist_items " +
+
+	"LEFT JOIN wishlists " +
+	"ON (wishlist_items.wishlist_id = wishlists.id) " +
+
+	"LEFT JOIN games " +
+	"ON (wishlist_items.game_id = games.id) " +
+
+	"LEFT JOIN publishers " +
+	"ON (games.publisher_id = publishers.id) "
+
+// Create adds the game to the list, ErrConflict means the game is already in it
+func (wishlistItemRepository *WishlistItemRepository) Create(wishlistItem *model.WishlistItem) error {
+	repositoryName := "WishlistItem"
+	methodName := "Create"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := wishlistItem.Validate(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	createQuery := "INSERT INTO wishlist_items (priority, note, target_price, created_at, wishlist_id, game_id) " +
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;"
+
+	if err := wishlistItemRepository.store.db.Get(
+		&wishlistItem.ID,
+		createQuery,
+		wishlistItem.Priority,
+		wishlistItem.Note,
+		wishlistItem.TargetPrice,
+		wishlistItem.CreatedAt,
+		wishlistItem.Wishlist.ID,
+		wishlistItem.Game.ID,
+	); err != nil {
+		if isUniqueViolation(err) {
+			return errors.Wrap(store.ErrConflict, errWrapMessage)
+		}
+
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (wishlistItemRepository *WishlistItemRepository) Find(id uint64) (*model.WishlistItem, error) {
+	repositoryName := "WishlistItem"
+	methodName := "Find"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	wishlistItem := &model.WishlistItem{}
+	findQuery := "SELECT " + wishlistItemSelectColumns + "WHERE wishlist_items.id = $1 LIMIT 1;"
+
+	if err := wishlistItemRepository.store.db.Get(
+		wishlistItem,
+		findQuery,
+		id,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return wishlistItem, nil
+}
+
+// FindAllByWishlist returns the most wanted games first
+func (wishlistItemRepository *WishlistItemRepository) FindAllByWishlist(wishlist *model.Wishlist) ([]*model.WishlistItem, error) {
+	repositoryName := "WishlistItem"
+	methodName := "FindAllByWishlist"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	wishlistItems := []*model.WishlistItem{}
+	findQuery := "SELECT " + wishlistItemSelectColumns +
+		"WHERE wishlist_items.wishlist_id = $1 " +
+		"ORDER BY wishlist_items.priority DESC, wishlist_items.created_at, wishlist_items.id;"
+
+	if err := wishlistItemRepository.store.db.Select(
+		&wishlistItems,
+		findQuery,
+		wishlist.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.WishlistItem{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return wishlistItems, nil
+}
+
+// Update changes priority, note and target price, the game and the list stay the same
+func (wishlistItemRepository *WishlistItemRepository) Update(wishlistItem *model.WishlistItem) error {
+	repositoryName := "WishlistItem"
+	methodName := "Update"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := wishlistItem.Validate(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	updateQuery := "UPDATE wishlist_items " +
+		"SET priority = $1, " +
+		"note = $2, " +
+		"target_price = $3 " +
+		"WHERE id = $4;"
+
+	countResult, err := wishlistItemRepository.store.db.Exec(
+		updateQuery,
+		wishlistItem.Priority,
+		wishlistItem.Note,
+		wishlistItem.TargetPrice,
+		wishlistItem.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+func (wishlistItemRepository *WishlistItemRepository) Delete(id uint64) error {
+	repositoryName := "WishlistItem"
+	methodName := "Delete"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	deleteQuery := "DELETE FROM wishlist_items WHERE id = $1;"
+
+	countResult, err := wishlistItemRepository.store.db.Exec(
+		deleteQuery,
+		id,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
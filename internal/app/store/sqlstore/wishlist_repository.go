@@ -0,0 +1,337 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+type WishlistRepository struct {
+	store *Store
+}
+
+const wishlistSelectColumns = "wishlists.id AS id, " +
+	"wishlists.name AS name, " +
+	"wishlists.position AS position, " +
+	"wishlists.is_default AS is_default, " +
+	"wishlists.is_public AS is_public, " +
+	"wishlists.public_slug AS public_slug, " +
+	"wishlists.created_at AS created_at, " +
+
+	"users.id AS \"user.id\", " +
+	"users.username AS \"user.username\" " +
+
+	"FROM wishlists " +
+
+	"LEFT JOIN users " +
+	"ON (wishlists.user_id = users.id) "
+
+// Create puts the list after the other lists of the user, ErrConflict means the user has a list with this name
+func (wishlistRepository *WishlistRepository) Create(wishlist *model.Wishlist) error {
+	repositoryName := "Wishlist"
+	methodName := "Create"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := wishlist.Validate(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	createQuery := "INSERT INTO wishlists (name, position, is_default, created_at, user_id) " +
+		"VALUES ($1, (SELECT COALESCE(MAX(position) + 1, 0) FROM wishlists WHERE user_id = $4), $2, $3, $4) " +
+		"RETURNING id, position;"
+
+	if err := wishlistRepository.store.db.QueryRowx(
+		createQuery,
+		wishlist.Name,
+		wishlist.IsDefault,
+		wishlist.CreatedAt,
+		wishlist.User.ID,
+	).Scan(&wishlist.ID, &wishlist.Position); err != nil {
+		if isUniqueViolation(err) {
+			return errors.Wrap(store.ErrConflict, errWrapMessage)
+		}
+
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+func (wishlistRepository *WishlistRepository) Find(id uint64) (*model.Wishlist, error) {
+	repositoryName := "Wishlist"
+	methodName := "Find"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	wishlist := &model.Wishlist{}
+	findQuery := "SELECT " + wishlistSelectColumns + "WHERE wishlists.id = $1 LIMIT 1;"
+
+	if err := wishlistRepository.store.db.Get(
+		wishlist,
+		findQuery,
+		id,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return wishlist, nil
+}
+
+func (wishlistRepository *WishlistRepository) FindAllByUser(user *model.User) ([]*model.Wishlist, error) {
+	repositoryName := "Wishlist"
+	methodName := "FindAllByUser"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	wishlists := []*model.Wishlist{}
+	findQuery := "SELECT " + wishlistSelectColumns +
+		"WHERE wishlists.user_id = $1 " +
+		"ORDER BY wishlists.position, wishlists.id;"
+
+	if err := wishlistRepository.store.db.Select(
+		&wishlists,
+		findQuery,
+		user.ID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return []*model.Wishlist{}, nil
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return wishlists, nil
+}
+
+// FindByPublicSlug returns the list only while it is public
+func (wishlistRepository *WishlistRepository) FindByPublicSlug(slug string) (*model.Wishlist, error) {
+	repositoryName := "Wishlist"
+	methodName := "FindByPublicSlug"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	wishlist := &model.Wishlist{}
+	findQuery := "SELECT " + wishlistSelectColumns +
+		"WHERE wishlists.public_slug = $1 AND wishlists.is_public LIMIT 1;"
+
+	if err := wishlistRepository.store.db.Get(
+		wishlist,
+		findQuery,
+		slug,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+
+		return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return wishlist, nil
+}
+
+// FindOrCreateDefault returns the list favourites are kept in, it is created on first use
+func (wishlistRepository *WishlistRepository) FindOrCreateDefault(user *model.User) (*model.Wishlist, error) {
+	repositoryName := "Wishlist"
+	methodName := "FindOrCreateDefault"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	// Update doesn't change anything, it lets the existing list be returned
+	createQuery := "INSERT INTO wishlists (name, position, is_default, created_at, user_id) " +
+		"VALUES ($1, 0, true, now(), $2) " +
+		"ON CONFLICT (user_id) WHERE is_default DO UPDATE SET is_default = true " +
+		"RETURNING id;"
+
+	var id uint64
+	if err := wishlistRepository.store.db.Get(
+		&id,
+		createQuery,
+		model.DefaultWishlistName,
+		user.ID,
+	); err != nil {
+		if !isUniqueViolation(err) {
+			return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+
+		// The user has another list named as the default one, it was created before
+		// such names were rejected, so it becomes the default list
+		promoteQuery := "UPDATE wishlists SET is_default = true " +
+			"WHERE user_id = $1 AND name = $2 " +
+			"AND NOT EXISTS (SELECT 1 FROM wishlists WHERE user_id = $1 AND is_default) " +
+			"RETURNING id;"
+
+		if err := wishlistRepository.store.db.Get(
+			&id,
+			promoteQuery,
+			user.ID,
+			model.DefaultWishlistName,
+		); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.Wrap(store.ErrConflict, errWrapMessage)
+			}
+
+			return nil, errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+	}
+
+	wishlist, err := wishlistRepository.Find(id)
+	if err != nil {
+		return nil, errors.Wrap(err, errWrapMessage)
+	}
+
+	return wishlist, nil
+}
+
+// Update renames the list, ErrConflict means the user has another list with this name
+func (wishlistRepository *WishlistRepository) Update(wishlist *model.Wishlist) error {
+	repositoryName := "Wishlist"
+	methodName := "Update"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	if err := wishlist.Validate(); err != nil {
+		return errors.Wrap(err, errWrapMessage)
+	}
+
+	updateQuery := "UPDATE wishlists SET name = $1 WHERE id = $2;"
+
+	countResult, err := wishlistRepository.store.db.Exec(
+		updateQuery,
+		wishlist.Name,
+		wishlist.ID,
+	)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.Wrap(store.ErrConflict, errWrapMessage)
+		}
+
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+// UpdateSharing changes visibility and slug of the list, ErrConflict means the slug is taken
+func (wishlistRepository *WishlistRepository) UpdateSharing(wishlist *model.Wishlist) error {
+	repositoryName := "Wishlist"
+	methodName := "UpdateSharing"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	updateQuery := "UPDATE wishlists SET is_public = $1, public_slug = $2 WHERE id = $3;"
+
+	countResult, err := wishlistRepository.store.db.Exec(
+		updateQuery,
+		wishlist.IsPublic,
+		wishlist.PublicSlug,
+		wishlist.ID,
+	)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.Wrap(store.ErrConflict, errWrapMessage)
+		}
+
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
+
+// Reorder puts the user's lists in the order of ids. ErrNotFound means one of ids isn't a list of the user.
+func (wishlistRepository *WishlistRepository) Reorder(user *model.User, ids []uint64) error {
+	repositoryName := "Wishlist"
+	methodName := "Reorder"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	tx, err := wishlistRepository.store.db.Beginx()
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	updateQuery := "UPDATE wishlists SET position = $1 WHERE id = $2 AND user_id = $3;"
+
+	for position, id := range ids {
+		countResult, err := tx.Exec(
+			updateQuery,
+			position,
+			id,
+			user.ID,
+		)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+
+		count, err := countResult.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+		}
+
+		if count == 0 {
+			tx.Rollback()
+			return errors.Wrap(store.ErrNotFound, errWrapMessage)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	return nil
+}
+
+// Delete deletes the list with its items
+func (wishlistRepository *WishlistRepository) Delete(id uint64) error {
+	repositoryName := "Wishlist"
+	methodName := "Delete"
+	errWrapMessage := fmt.Sprintf(store.ErrRepositoryMessageFormat, repositoryName, methodName)
+
+	deleteQuery := "DELETE FROM wishlists WHERE id = $1;"
+
+	countResult, err := wishlistRepository.store.db.Exec(
+		deleteQuery,
+		id,
+	)
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	count, err := countResult.RowsAffected()
+
+	if err != nil {
+		return errors.Wrap(errors.Wrap(store.ErrUnknownSQL, err.Error()), errWrapMessage)
+	}
+
+	if count == 0 {
+		return errors.Wrap(store.ErrNotFound, errWrapMessage)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package sqlstore_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
@@ -364,9 +365,12 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	tableName := "GameMarketPrices"
 	errWrapMessage := fmt.Sprintf(store.ErrTestDataInsertionMessageFormat, tableName)
 
+	saleEndsAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "Free To Play",
 		FinalValueFormatted:   "Free To Play",
+		FinalValue:            0,
 		DiscountPercent:       0,
 		MarketGameURL:         "730",
 		Game:                  games[0],
@@ -376,6 +380,7 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "610 руб.",
 		FinalValueFormatted:   "247 руб.",
+		FinalValue:            24700,
 		DiscountPercent:       60,
 		MarketGameURL:         "305620",
 		Game:                  games[1],
@@ -385,6 +390,7 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "749 руб.",
 		FinalValueFormatted:   "749 руб.",
+		FinalValue:            74900,
 		DiscountPercent:       0,
 		MarketGameURL:         "the-long-dark",
 		Game:                  games[1],
@@ -394,6 +400,7 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "520 руб.",
 		FinalValueFormatted:   "520 руб.",
+		FinalValue:            52000,
 		DiscountPercent:       0,
 		MarketGameURL:         "427520",
 		Game:                  games[2],
@@ -403,6 +410,7 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "3 619 руб.",
 		FinalValueFormatted:   "3 619 руб.",
+		FinalValue:            361900,
 		DiscountPercent:       0,
 		MarketGameURL:         "factorio",
 		Game:                  games[2],
@@ -412,6 +420,7 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "3 999 руб.",
 		FinalValueFormatted:   "3 999 руб.",
+		FinalValue:            399900,
 		DiscountPercent:       0,
 		MarketGameURL:         "1245620",
 		Game:                  games[3],
@@ -421,7 +430,9 @@ func insertTestDataGameMarketPrices(st *sqlstore.Store) error {
 	gameMarketPrices = append(gameMarketPrices, &model.GameMarketPrice{
 		InitialValueFormatted: "1 199 руб.",
 		FinalValueFormatted:   "719 руб.",
+		FinalValue:            71900,
 		DiscountPercent:       40,
+		SaleEndsAt:            &saleEndsAt,
 		MarketGameURL:         "221100",
 		Game:                  games[4],
 		Market:                markets[0],
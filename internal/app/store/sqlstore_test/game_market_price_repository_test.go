@@ -1 +1,256 @@
 package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+func TestGameMarketPriceRepositoryMarkMissed(t *testing.T) {
+	gameMarketPriceWant := gameMarketPrices[4]
+	missesToDelist := 2
+
+	for i := 1; i <= missesToDelist; i++ {
+		delistedIDs, err := st.GameMarketPrices().MarkMissed(gameMarketPriceWant.Market, []uint64{}, missesToDelist)
+		if err != nil {
+			t.Errorf("Couldn't mark offers missed in market (%s):\n\t%s", gameMarketPriceWant.Market.Name, err.Error())
+			return
+		}
+
+		if i < missesToDelist && len(delistedIDs) != 0 {
+			t.Errorf("Offers were delisted too early (miss %d of %d):\n\tGot: %v", i, missesToDelist, delistedIDs)
+		}
+		if i == missesToDelist && (len(delistedIDs) != 1 || delistedIDs[0] != gameMarketPriceWant.ID) {
+			t.Errorf("Wrong offers were delisted:\n\tWanted: [%d], Got: %v", gameMarketPriceWant.ID, delistedIDs)
+		}
+	}
+
+	gameMarketPriceFound, err := st.GameMarketPrices().Find(gameMarketPriceWant.ID)
+	if err != nil {
+		t.Errorf("Couldn't find gameMarketPrice with ID (%d):\n\t%s", gameMarketPriceWant.ID, err.Error())
+		return
+	}
+	if !gameMarketPriceFound.Delisted || gameMarketPriceFound.MissedSyncs != missesToDelist {
+		t.Errorf("GameMarketPrice wasn't delisted:\n\tGot: %+v", gameMarketPriceFound)
+	}
+
+	if err := st.GameMarketPrices().MarkSeen([]uint64{gameMarketPriceWant.ID}, time.Now()); err != nil {
+		t.Errorf("Couldn't mark gameMarketPrice with ID (%d) seen:\n\t%s", gameMarketPriceWant.ID, err.Error())
+		return
+	}
+
+	gameMarketPriceFound, err = st.GameMarketPrices().Find(gameMarketPriceWant.ID)
+	if err != nil {
+		t.Errorf("Couldn't find gameMarketPrice with ID (%d):\n\t%s", gameMarketPriceWant.ID, err.Error())
+		return
+	}
+	if gameMarketPriceFound.MissedSyncs != 0 {
+		t.Errorf("Missed syncs weren't reset:\n\tGot: %+v", gameMarketPriceFound)
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllDeals(t *testing.T) {
+	// gameMarketPrices[1] has bigger discount and lower price, gameMarketPrices[6] has known sale end
+	testCases := []struct {
+		sort store.DealsSort
+		want []uint64
+	}{
+		{sort: store.DealsSortDiscount, want: []uint64{gameMarketPrices[1].ID, gameMarketPrices[6].ID}},
+		{sort: store.DealsSortPrice, want: []uint64{gameMarketPrices[1].ID, gameMarketPrices[6].ID}},
+		{sort: store.DealsSortEndingSoon, want: []uint64{gameMarketPrices[6].ID, gameMarketPrices[1].ID}},
+	}
+
+	for _, testCase := range testCases {
+		gameMarketPricesFound, err := st.GameMarketPrices().FindAllDeals(testCase.sort, 10, store.DealsFilter{})
+		if err != nil {
+			t.Errorf("Couldn't find deals sorted by (%s):\n\t%s", testCase.sort, err.Error())
+			return
+		}
+
+		got := []uint64{}
+		for _, gameMarketPrice := range gameMarketPricesFound {
+			got = append(got, gameMarketPrice.ID)
+		}
+
+		if len(got) != len(testCase.want) {
+			t.Errorf("Wrong deals sorted by (%s):\n\tWanted: %v, Got: %v", testCase.sort, testCase.want, got)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != testCase.want[i] {
+				t.Errorf("Wrong deals sorted by (%s):\n\tWanted: %v, Got: %v", testCase.sort, testCase.want, got)
+				break
+			}
+		}
+	}
+
+	gameMarketPriceFound, err := st.GameMarketPrices().Find(gameMarketPrices[6].ID)
+	if err != nil {
+		t.Errorf("Couldn't find gameMarketPrice with ID (%d):\n\t%s", gameMarketPrices[6].ID, err.Error())
+		return
+	}
+	if gameMarketPriceFound.SaleEndsAt == nil || !gameMarketPriceFound.SaleEndsAt.Equal(*gameMarketPrices[6].SaleEndsAt) {
+		t.Errorf("Wrong sale end:\n\tWanted: %v, Got: %v", gameMarketPrices[6].SaleEndsAt, gameMarketPriceFound.SaleEndsAt)
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllDealsFiltered(t *testing.T) {
+	// tags[4] is a tag of games[1] only, there are no deals in markets[1]
+	testCases := []struct {
+		filter store.DealsFilter
+		want   []uint64
+	}{
+		{filter: store.DealsFilter{HiddenTags: []string{tags[4].Name}}, want: []uint64{gameMarketPrices[6].ID}},
+		{filter: store.DealsFilter{EnabledMarkets: []string{markets[1].Name}}, want: []uint64{}},
+		{filter: store.DealsFilter{EnabledMarkets: []string{markets[0].Name}}, want: []uint64{gameMarketPrices[1].ID, gameMarketPrices[6].ID}},
+	}
+
+	for _, testCase := range testCases {
+		gameMarketPricesFound, err := st.GameMarketPrices().FindAllDeals(store.DealsSortDiscount, 10, testCase.filter)
+		if err != nil {
+			t.Errorf("Couldn't find deals filtered by (%+v):\n\t%s", testCase.filter, err.Error())
+			return
+		}
+
+		got := []uint64{}
+		for _, gameMarketPrice := range gameMarketPricesFound {
+			got = append(got, gameMarketPrice.ID)
+		}
+
+		if len(got) != len(testCase.want) {
+			t.Errorf("Wrong deals filtered by (%+v):\n\tWanted: %v, Got: %v", testCase.filter, testCase.want, got)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != testCase.want[i] {
+				t.Errorf("Wrong deals filtered by (%+v):\n\tWanted: %v, Got: %v", testCase.filter, testCase.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllByMarketURLs(t *testing.T) {
+	market := markets[0]
+
+	// "the-long-dark" is URL of other market, "1" isn't in the catalogue
+	gameMarketPricesFound, err := st.GameMarketPrices().FindAllByMarketURLs(market, []string{"730", "305620", "the-long-dark", "1"})
+	if err != nil {
+		t.Errorf("Couldn't find gameMarketPrices of market (%s) by URLs:\n\t%s", market.Name, err.Error())
+		return
+	}
+
+	if len(gameMarketPricesFound) != 2 {
+		t.Errorf("Wrong number of gameMarketPrices:\n\tWanted: 2, Got: %d", len(gameMarketPricesFound))
+		return
+	}
+
+	for _, gameMarketPriceFound := range gameMarketPricesFound {
+		if gameMarketPriceFound.Market.ID != market.ID ||
+			(gameMarketPriceFound.Game.ID != gameMarketPrices[0].Game.ID && gameMarketPriceFound.Game.ID != gameMarketPrices[1].Game.ID) {
+			t.Errorf("Wrong gameMarketPrice found:\n\tGot: %+v", gameMarketPriceFound)
+		}
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllByGamesMarket(t *testing.T) {
+	market := markets[0]
+
+	// games[1] has no offer in the market
+	gameMarketPricesFound, err := st.GameMarketPrices().FindAllByGamesMarket([]*model.Game{games[0], games[2], games[1]}, market)
+	if err != nil {
+		t.Errorf("Couldn't find gameMarketPrices of market (%s) by games:\n\t%s", market.Name, err.Error())
+		return
+	}
+
+	if len(gameMarketPricesFound) != 2 {
+		t.Errorf("Wrong number of gameMarketPrices:\n\tWanted: 2, Got: %d", len(gameMarketPricesFound))
+		return
+	}
+
+	for _, gameMarketPriceFound := range gameMarketPricesFound {
+		if gameMarketPriceFound.Market.ID != market.ID ||
+			(gameMarketPriceFound.Game.ID != games[0].ID && gameMarketPriceFound.Game.ID != games[2].ID) {
+			t.Errorf("Wrong gameMarketPrice found:\n\tGot: %+v", gameMarketPriceFound)
+		}
+	}
+}
+
+func TestGameMarketPriceRepositorySaveAll(t *testing.T) {
+	gameMarketPriceWant := *gameMarketPrices[0]
+	gameMarketPriceWant.LastSeenAt = time.Now()
+
+	if err := st.GameMarketPrices().SaveAll([]*model.GameMarketPrice{&gameMarketPriceWant}); err != nil {
+		t.Errorf("Couldn't save gameMarketPrice with ID (%d):\n\t%s", gameMarketPriceWant.ID, err.Error())
+		return
+	}
+
+	gameMarketPriceFound, err := st.GameMarketPrices().Find(gameMarketPriceWant.ID)
+	if err != nil {
+		t.Errorf("Couldn't find gameMarketPrice with ID (%d):\n\t%s", gameMarketPriceWant.ID, err.Error())
+		return
+	}
+	if gameMarketPriceFound.FinalValueFormatted != gameMarketPriceWant.FinalValueFormatted ||
+		gameMarketPriceFound.Game.ID != gameMarketPriceWant.Game.ID {
+		t.Errorf("Wrong gameMarketPrice saved:\n\tWanted: %+v, Got: %+v", gameMarketPriceWant, gameMarketPriceFound)
+	}
+
+	missingGameMarketPrice := gameMarketPriceWant
+	missingGameMarketPrice.ID = 1 << 40
+	if err := st.GameMarketPrices().SaveAll([]*model.GameMarketPrice{&missingGameMarketPrice}); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Offer that doesn't exist was saved:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllBestByGames(t *testing.T) {
+	// games[1] is cheaper in the first market, games[2] is sold only in the first market for this price
+	wantByGame := map[uint64]uint64{
+		games[1].ID: gameMarketPrices[1].ID,
+		games[2].ID: gameMarketPrices[3].ID,
+	}
+
+	gameMarketPricesFound, err := st.GameMarketPrices().FindAllBestByGames([]*model.Game{games[1], games[2]}, nil)
+	if err != nil {
+		t.Errorf("Couldn't find best prices:\n\t%s", err.Error())
+		return
+	}
+
+	if len(gameMarketPricesFound) != len(wantByGame) {
+		t.Errorf("Wrong number of best prices:\n\tWanted: %d, Got: %d", len(wantByGame), len(gameMarketPricesFound))
+		return
+	}
+	for _, gameMarketPriceFound := range gameMarketPricesFound {
+		if wantByGame[gameMarketPriceFound.Game.ID] != gameMarketPriceFound.ID {
+			t.Errorf("Wrong best price of game (%d):\n\tWanted ID: %d, Got: %d", gameMarketPriceFound.Game.ID, wantByGame[gameMarketPriceFound.Game.ID], gameMarketPriceFound.ID)
+		}
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllBestByGamesInMarkets(t *testing.T) {
+	// The cheapest offers of both games are in the first market, that isn't enabled
+	wantByGame := map[uint64]uint64{
+		games[1].ID: gameMarketPrices[2].ID,
+		games[2].ID: gameMarketPrices[4].ID,
+	}
+
+	gameMarketPricesFound, err := st.GameMarketPrices().FindAllBestByGames([]*model.Game{games[1], games[2]}, []string{markets[1].Name, markets[2].Name})
+	if err != nil {
+		t.Errorf("Couldn't find best prices in markets:\n\t%s", err.Error())
+		return
+	}
+
+	if len(gameMarketPricesFound) != len(wantByGame) {
+		t.Errorf("Wrong number of best prices in markets:\n\tWanted: %d, Got: %d", len(wantByGame), len(gameMarketPricesFound))
+		return
+	}
+	for _, gameMarketPriceFound := range gameMarketPricesFound {
+		if wantByGame[gameMarketPriceFound.Game.ID] != gameMarketPriceFound.ID {
+			t.Errorf("Wrong best price of game (%d) in markets:\n\tWanted ID: %d, Got: %d", gameMarketPriceFound.Game.ID, wantByGame[gameMarketPriceFound.Game.ID], gameMarketPriceFound.ID)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestGameSimilarityRepository(t *testing.T) {
+	gameSimilarities := []*model.GameSimilarity{
+		{Score: 0.4, Game: games[0], SimilarGame: games[1]},
+		{Score: 0.9, Game: games[0], SimilarGame: games[2]},
+		{Score: 0.5, Game: games[1], SimilarGame: games[0]},
+	}
+
+	if err := st.GameSimilarities().ReplaceAll(gameSimilarities); err != nil {
+		t.Errorf("Couldn't save game similarities:\n\t%s", err.Error())
+		return
+	}
+
+	gameSimilaritiesFound, err := st.GameSimilarities().FindAllByGame(games[0], 10)
+	if err != nil {
+		t.Errorf("Couldn't find similar games of game (%d):\n\t%s", games[0].ID, err.Error())
+		return
+	}
+	if len(gameSimilaritiesFound) != 2 || gameSimilaritiesFound[0].SimilarGame.ID != games[2].ID ||
+		gameSimilaritiesFound[1].SimilarGame.ID != games[1].ID || gameSimilaritiesFound[0].SimilarGame.Name != games[2].Name {
+		t.Errorf("Wrong similar games of game (%d):\n\tGot: %+v", games[0].ID, gameSimilaritiesFound)
+	}
+
+	if gameSimilaritiesLimited, err := st.GameSimilarities().FindAllByGame(games[0], 1); err != nil || len(gameSimilaritiesLimited) != 1 {
+		t.Errorf("Limit of similar games wasn't applied:\n\tGot: %+v (%v)", gameSimilaritiesLimited, err)
+	}
+
+	// Refresh replaces neighbours of every game
+	if err := st.GameSimilarities().ReplaceAll(gameSimilarities[2:]); err != nil {
+		t.Errorf("Couldn't replace game similarities:\n\t%s", err.Error())
+		return
+	}
+	if gameSimilaritiesFound, err := st.GameSimilarities().FindAllByGame(games[0], 10); err != nil || len(gameSimilaritiesFound) != 0 {
+		t.Errorf("Old similar games of game (%d) were kept:\n\tGot: %+v (%v)", games[0].ID, gameSimilaritiesFound, err)
+	}
+}
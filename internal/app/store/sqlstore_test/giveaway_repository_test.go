@@ -0,0 +1,128 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func giveawayTime(now time.Time, hours int) *time.Time {
+	giveawayTime := now.Add(time.Duration(hours) * time.Hour)
+	return &giveawayTime
+}
+
+func TestGiveawayRepository(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	giveawayActive := &model.Giveaway{
+		Title:         games[0].Name,
+		MarketGameURL: "giveaway-active",
+		StartsAt:      giveawayTime(now, -24),
+		EndsAt:        giveawayTime(now, 24),
+		Market:        markets[1],
+		Game:          games[0],
+	}
+	giveawayUpcoming := &model.Giveaway{
+		Title:         "Unknown game",
+		MarketGameURL: "giveaway-upcoming",
+		StartsAt:      giveawayTime(now, 48),
+		EndsAt:        giveawayTime(now, 72),
+		Market:        markets[2],
+	}
+	giveawayEnded := &model.Giveaway{
+		Title:         "Ended game",
+		MarketGameURL: "giveaway-ended",
+		StartsAt:      giveawayTime(now, -72),
+		EndsAt:        giveawayTime(now, -48),
+		Market:        markets[2],
+	}
+
+	for _, giveaway := range []*model.Giveaway{giveawayActive, giveawayUpcoming, giveawayEnded} {
+		if err := st.Giveaways().Create(giveaway); err != nil {
+			t.Errorf("Couldn't create giveaway (%s):\n\t%s", giveaway.MarketGameURL, err.Error())
+			return
+		}
+	}
+
+	// Creating the same giveaway again updates it instead of adding a duplicate
+	giveawayActiveID := giveawayActive.ID
+	giveawayActive.EndsAt = giveawayTime(now, 36)
+	if err := st.Giveaways().Create(giveawayActive); err != nil {
+		t.Errorf("Couldn't update giveaway (%s):\n\t%s", giveawayActive.MarketGameURL, err.Error())
+		return
+	}
+	if giveawayActive.ID != giveawayActiveID {
+		t.Errorf("Giveaway was duplicated:\n\tWanted: %d, Got: %d", giveawayActiveID, giveawayActive.ID)
+	}
+
+	giveawayFound, err := st.Giveaways().Find(giveawayActive.ID)
+	if err != nil {
+		t.Errorf("Couldn't find giveaway with ID (%d):\n\t%s", giveawayActive.ID, err.Error())
+		return
+	}
+	if giveawayFound.Game == nil || giveawayFound.Game.ID != games[0].ID || giveawayFound.EndsAt == nil || !giveawayFound.EndsAt.Equal(*giveawayActive.EndsAt) {
+		t.Errorf("Wrong giveaway found:\n\tWanted: %+v, Got: %+v", giveawayActive, giveawayFound)
+	}
+
+	giveawaysFound, err := st.Giveaways().FindAllNotEnded(now)
+	if err != nil {
+		t.Errorf("Couldn't find not ended giveaways:\n\t%s", err.Error())
+		return
+	}
+
+	if len(giveawaysFound) != 2 ||
+		giveawaysFound[0].ID != giveawayActive.ID ||
+		giveawaysFound[1].ID != giveawayUpcoming.ID {
+		t.Errorf("Wrong not ended giveaways:\n\tWanted: [%d %d], Got: %+v", giveawayActive.ID, giveawayUpcoming.ID, giveawaysFound)
+		return
+	}
+	if giveawaysFound[1].Game != nil {
+		t.Errorf("Unmatched giveaway has a game:\n\tGot: %+v", giveawaysFound[1].Game)
+	}
+
+	for _, giveaway := range []*model.Giveaway{giveawayActive, giveawayUpcoming, giveawayEnded} {
+		if err := st.Giveaways().Delete(giveaway.ID); err != nil {
+			t.Errorf("Couldn't delete giveaway with ID (%d):\n\t%s", giveaway.ID, err.Error())
+		}
+	}
+}
+
+func TestGiveawayRepositoryReplaceUndated(t *testing.T) {
+	now := time.Now()
+	market := markets[2]
+
+	giveawaysFirstSync := []*model.Giveaway{
+		{Title: games[0].Name, MarketGameURL: "undated-first", Market: market, Game: games[0]},
+		{Title: "Unknown game", MarketGameURL: "undated-second", Market: market},
+	}
+	if err := st.Giveaways().ReplaceUndated(market, giveawaysFirstSync); err != nil {
+		t.Errorf("Couldn't save giveaways without dates:\n\t%s", err.Error())
+		return
+	}
+
+	// Giveaway, that isn't found by the next sync, has ended
+	giveawaysSecondSync := giveawaysFirstSync[1:]
+	if err := st.Giveaways().ReplaceUndated(market, giveawaysSecondSync); err != nil {
+		t.Errorf("Couldn't replace giveaways without dates:\n\t%s", err.Error())
+		return
+	}
+
+	giveawaysFound, err := st.Giveaways().FindAllNotEnded(now)
+	if err != nil {
+		t.Errorf("Couldn't find not ended giveaways:\n\t%s", err.Error())
+		return
+	}
+
+	if len(giveawaysFound) != 1 || giveawaysFound[0].MarketGameURL != "undated-second" {
+		t.Errorf("Wrong not ended giveaways:\n\tWanted: [undated-second], Got: %+v", giveawaysFound)
+		return
+	}
+	if giveawaysFound[0].StartsAt != nil || giveawaysFound[0].EndsAt != nil {
+		t.Errorf("Giveaway without dates got them:\n\tGot: %+v", giveawaysFound[0])
+	}
+
+	if err := st.Giveaways().ReplaceUndated(market, []*model.Giveaway{}); err != nil {
+		t.Errorf("Couldn't delete giveaways without dates:\n\t%s", err.Error())
+	}
+}
@@ -0,0 +1,83 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestPriceStatsRepository(t *testing.T) {
+	gameMarketPrice := gameMarketPrices[3]
+	now := time.Now().Truncate(time.Second)
+
+	records := []struct {
+		finalValue int
+		recordedAt time.Time
+	}{
+		{finalValue: 52000, recordedAt: now.AddDate(0, 0, -200)},
+		{finalValue: 26000, recordedAt: now.AddDate(0, 0, -100)},
+		{finalValue: 39000, recordedAt: now.AddDate(0, 0, -60)},
+		{finalValue: 52000, recordedAt: now.AddDate(0, 0, -10)},
+	}
+
+	for _, record := range records {
+		offer := &model.GameMarketPrice{
+			ID:         gameMarketPrice.ID,
+			FinalValue: record.finalValue,
+		}
+
+		if err := st.PriceStats().AddRecord(offer, record.recordedAt); err != nil {
+			t.Errorf("Couldn't add price record for gameMarketPrice with ID (%d):\n\t%s", gameMarketPrice.ID, err.Error())
+			return
+		}
+	}
+
+	if err := st.PriceStats().AddSale(gameMarketPrice); err != nil {
+		t.Errorf("Couldn't add sale for gameMarketPrice with ID (%d):\n\t%s", gameMarketPrice.ID, err.Error())
+		return
+	}
+
+	priceStats, err := st.PriceStats().FindByGameMarketPrice(gameMarketPrice, now)
+	if err != nil {
+		t.Errorf("Couldn't find price stats for gameMarketPrice with ID (%d):\n\t%s", gameMarketPrice.ID, err.Error())
+		return
+	}
+
+	if priceStats.AllTimeLow != 26000 || !priceStats.AllTimeLowAt.Equal(records[1].recordedAt) {
+		t.Errorf("Wrong all-time low:\n\tWanted: %d at %v, Got: %d at %v", 26000, records[1].recordedAt, priceStats.AllTimeLow, priceStats.AllTimeLowAt)
+	}
+
+	// 39000 was set 60 days ago and was still active 30 days ago
+	if priceStats.Low30Days != 39000 || priceStats.Low90Days != 39000 || priceStats.Low365Days != 26000 {
+		t.Errorf("Wrong window lows:\n\tWanted: [39000 39000 26000], Got: [%d %d %d]", priceStats.Low30Days, priceStats.Low90Days, priceStats.Low365Days)
+	}
+
+	// Prices were active for 100, 40, 50 and 10 days
+	if priceStats.SamplesCount != len(records) || priceStats.AveragePrice != 43550 || priceStats.SalesCount != 1 {
+		t.Errorf("Wrong price stats:\n\tGot: %+v", priceStats)
+	}
+
+	if priceStats.IsHistoricalLow() {
+		t.Errorf("Current price (%d) is marked as historical low", priceStats.GameMarketPrice.FinalValue)
+	}
+
+	priceStatsAll, err := st.PriceStats().FindAllByGames([]*model.Game{gameMarketPrice.Game}, now)
+	if err != nil {
+		t.Errorf("Couldn't find price stats for game with ID (%d):\n\t%s", gameMarketPrice.Game.ID, err.Error())
+		return
+	}
+
+	found := false
+	for _, priceStatsFound := range priceStatsAll {
+		if priceStatsFound.GameMarketPrice.Game.ID != gameMarketPrice.Game.ID {
+			t.Errorf("Price stats of another game found:\n\tWanted game ID: %d, Got: %d", gameMarketPrice.Game.ID, priceStatsFound.GameMarketPrice.Game.ID)
+		}
+		if priceStatsFound.GameMarketPrice.ID == gameMarketPrice.ID {
+			found = priceStatsFound.AveragePrice == priceStats.AveragePrice
+		}
+	}
+	if !found {
+		t.Errorf("Price stats for gameMarketPrice with ID (%d) weren't found by game:\n\tGot: %+v", gameMarketPrice.ID, priceStatsAll)
+	}
+}
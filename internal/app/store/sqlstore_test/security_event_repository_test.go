@@ -0,0 +1,65 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+)
+
+func TestSecurityEventRepository(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	securityEventOld := &model.SecurityEvent{
+		Type:      model.SecurityEventRefreshTokenReuse,
+		SessionID: "session-old",
+		IP:        "127.0.0.1",
+		UserAgent: "test-agent",
+		CreatedAt: now.Add(-time.Hour),
+		User:      users[0],
+	}
+	securityEventNew := &model.SecurityEvent{
+		Type:      model.SecurityEventRefreshTokenReuse,
+		SessionID: "session-new",
+		IP:        "127.0.0.2",
+		UserAgent: "test-agent",
+		CreatedAt: now,
+		User:      users[0],
+	}
+	securityEventOtherUser := &model.SecurityEvent{
+		Type:      model.SecurityEventRefreshTokenReuse,
+		SessionID: "session-other",
+		IP:        "127.0.0.3",
+		UserAgent: "test-agent",
+		CreatedAt: now,
+		User:      users[1],
+	}
+
+	for _, securityEvent := range []*model.SecurityEvent{securityEventOld, securityEventNew, securityEventOtherUser} {
+		if err := st.SecurityEvents().Create(securityEvent); err != nil {
+			t.Errorf("Couldn't create security event (%s):\n\t%s", securityEvent.SessionID, err.Error())
+			return
+		}
+	}
+
+	securityEventsFound, err := st.SecurityEvents().FindAllByUser(users[0], 10)
+	if err != nil {
+		t.Errorf("Couldn't find security events of user (%d):\n\t%s", users[0].ID, err.Error())
+		return
+	}
+	if len(securityEventsFound) != 2 ||
+		securityEventsFound[0].ID != securityEventNew.ID ||
+		securityEventsFound[1].ID != securityEventOld.ID {
+		t.Errorf("Wrong security events:\n\tWanted: [%d %d], Got: %+v", securityEventNew.ID, securityEventOld.ID, securityEventsFound)
+		return
+	}
+
+	securityEventsFound, err = st.SecurityEvents().FindAllByUser(users[0], 1)
+	if err != nil {
+		t.Errorf("Couldn't find security events of user (%d):\n\t%s", users[0].ID, err.Error())
+		return
+	}
+	if len(securityEventsFound) != 1 || securityEventsFound[0].ID != securityEventNew.ID {
+		t.Errorf("Security events limit isn't applied:\n\tWanted: [%d], Got: %+v", securityEventNew.ID, securityEventsFound)
+	}
+}
@@ -0,0 +1,79 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+func TestUserIdentityRepository(t *testing.T) {
+	user := &model.User{
+		Username: "external_user",
+		Email:    "external_user@example.com",
+	}
+
+	if err := st.Users().CreateWithoutPassword(user); err != nil {
+		t.Errorf("Couldn't create user without password:\n\t%s", err.Error())
+		return
+	}
+	defer st.Users().Delete(user.ID)
+
+	userFound, err := st.Users().Find(user.ID)
+	if err != nil {
+		t.Errorf("Couldn't find user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if userFound.HasPassword() {
+		t.Errorf("User (%d) created without password has one", user.ID)
+	}
+
+	userIdentity := &model.UserIdentity{
+		Provider:  "steam",
+		Subject:   "76561197960287930",
+		CreatedAt: time.Now().Truncate(time.Second),
+		User:      user,
+	}
+
+	if err := st.UserIdentities().Create(userIdentity); err != nil {
+		t.Errorf("Couldn't create identity of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	userIdentityFound, err := st.UserIdentities().FindByProviderSubject("steam", "76561197960287930")
+	if err != nil {
+		t.Errorf("Couldn't find identity:\n\t%s", err.Error())
+		return
+	}
+	if userIdentityFound.ID != userIdentity.ID || userIdentityFound.User.ID != user.ID {
+		t.Errorf("Wrong identity:\n\tWanted: %+v, Got: %+v", userIdentity, userIdentityFound)
+	}
+
+	// Identity can't be linked to two users
+	userIdentityOther := &model.UserIdentity{
+		Provider:  "steam",
+		Subject:   "76561197960287930",
+		CreatedAt: time.Now(),
+		User:      users[3],
+	}
+	if err := st.UserIdentities().Create(userIdentityOther); err == nil {
+		t.Errorf("Identity has been linked to second user (%d)", users[3].ID)
+	}
+
+	userIdentities, err := st.UserIdentities().FindAllByUser(user)
+	if err != nil || len(userIdentities) != 1 {
+		t.Errorf("Wrong identities of user (%d):\n\tWanted: 1, Got: %d (%v)", user.ID, len(userIdentities), err)
+	}
+
+	if err := st.UserIdentities().DeleteByUserProvider(user, "steam"); err != nil {
+		t.Errorf("Couldn't delete identity:\n\t%s", err.Error())
+	}
+	if err := st.UserIdentities().DeleteByUserProvider(user, "steam"); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Deleted identity was deleted again:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+	if _, err := st.UserIdentities().FindByProviderSubject("steam", "76561197960287930"); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Deleted identity was found:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,100 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+func TestUserOwnedGameRepository(t *testing.T) {
+	user := users[3]
+
+	userOwnedGame := &model.UserOwnedGame{
+		Source:    model.OwnedGameSourceImported,
+		CreatedAt: time.Now().Truncate(time.Second),
+		User:      user,
+		Game:      games[0],
+		Market:    markets[0],
+	}
+
+	if err := st.UserOwnedGames().Create(userOwnedGame); err != nil {
+		t.Errorf("Couldn't create owned game of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	// Repeated import keeps the existing record
+	userOwnedGameAgain := &model.UserOwnedGame{
+		Source:    model.OwnedGameSourceManual,
+		CreatedAt: time.Now(),
+		User:      user,
+		Game:      games[0],
+	}
+	if err := st.UserOwnedGames().Create(userOwnedGameAgain); err != nil {
+		t.Errorf("Couldn't create owned game of user (%d) again:\n\t%s", user.ID, err.Error())
+		return
+	}
+	if userOwnedGameAgain.ID != userOwnedGame.ID {
+		t.Errorf("Owned game has been duplicated:\n\tWanted ID: %d, Got: %d", userOwnedGame.ID, userOwnedGameAgain.ID)
+	}
+
+	userOwnedGames, err := st.UserOwnedGames().FindAllByUser(user)
+	if err != nil {
+		t.Errorf("Couldn't find owned games of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if len(userOwnedGames) != 1 {
+		t.Errorf("Wrong number of owned games of user (%d):\n\tWanted: 1, Got: %d", user.ID, len(userOwnedGames))
+		return
+	}
+	if userOwnedGames[0].Game.ID != games[0].ID || userOwnedGames[0].Source != model.OwnedGameSourceImported ||
+		userOwnedGames[0].Market == nil || userOwnedGames[0].Market.ID != markets[0].ID {
+		t.Errorf("Wrong owned game of user (%d):\n\tWanted: %+v, Got: %+v", user.ID, userOwnedGame, userOwnedGames[0])
+	}
+
+	userOwnedGame.Market = nil
+	if err := st.UserOwnedGames().Update(userOwnedGame); err != nil {
+		t.Errorf("Couldn't update owned game (%d):\n\t%s", userOwnedGame.ID, err.Error())
+		return
+	}
+	if userOwnedGameFound, err := st.UserOwnedGames().Find(userOwnedGame.ID); err != nil || userOwnedGameFound.Market != nil {
+		t.Errorf("Market of owned game (%d) hasn't been removed:\n\tGot: %+v (%v)", userOwnedGame.ID, userOwnedGameFound, err)
+	}
+
+	if err := st.UserOwnedGames().Delete(userOwnedGame.ID); err != nil {
+		t.Errorf("Couldn't delete owned game (%d):\n\t%s", userOwnedGame.ID, err.Error())
+		return
+	}
+	if _, err := st.UserOwnedGames().Find(userOwnedGame.ID); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Deleted owned game (%d) was found:\n\tWanted: %v, Got: %v", userOwnedGame.ID, store.ErrNotFound, err)
+	}
+}
+
+func TestGameMarketPriceRepositoryFindAllDealsExcludeOwned(t *testing.T) {
+	user := users[1]
+
+	// gameMarketPrices[1] and gameMarketPrices[6] are the only deals
+	userOwnedGame := &model.UserOwnedGame{
+		Source:    model.OwnedGameSourceManual,
+		CreatedAt: time.Now(),
+		User:      user,
+		Game:      gameMarketPrices[1].Game,
+	}
+	if err := st.UserOwnedGames().Create(userOwnedGame); err != nil {
+		t.Errorf("Couldn't create owned game of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	defer st.UserOwnedGames().Delete(userOwnedGame.ID)
+
+	gameMarketPricesFound, err := st.GameMarketPrices().FindAllDeals(store.DealsSortDiscount, 10, store.DealsFilter{ExcludeOwnedBy: user})
+	if err != nil {
+		t.Errorf("Couldn't find deals without games owned by user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	if len(gameMarketPricesFound) != 1 || gameMarketPricesFound[0].ID != gameMarketPrices[6].ID {
+		t.Errorf("Owned game wasn't excluded from deals:\n\tWanted: [%d], Got: %d deals", gameMarketPrices[6].ID, len(gameMarketPricesFound))
+	}
+}
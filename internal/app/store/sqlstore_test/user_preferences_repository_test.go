@@ -0,0 +1,51 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+func TestUserPreferencesRepository(t *testing.T) {
+	user := users[3]
+
+	if _, err := st.UserPreferences().FindByUser(user); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Wrong error when finding preferences, that weren't saved:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+
+	userPreferences := model.NewUserPreferences(user)
+	userPreferences.Language = "ru"
+	userPreferences.EnabledMarkets = []string{markets[0].Name}
+	userPreferences.HiddenTags = []string{tags[0].Name}
+	userPreferences.UpdatedAt = time.Now().Truncate(time.Second)
+
+	if err := st.UserPreferences().Save(userPreferences); err != nil {
+		t.Errorf("Couldn't save preferences of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	// Saving again replaces the preferences
+	userPreferences.HiddenTags = []string{}
+	if err := st.UserPreferences().Save(userPreferences); err != nil {
+		t.Errorf("Couldn't save preferences of user (%d) again:\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	userPreferencesFound, err := st.UserPreferences().FindByUser(user)
+	if err != nil {
+		t.Errorf("Couldn't find preferences of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if userPreferencesFound.Language != "ru" || len(userPreferencesFound.EnabledMarkets) != 1 ||
+		userPreferencesFound.EnabledMarkets[0] != markets[0].Name || len(userPreferencesFound.HiddenTags) != 0 {
+		t.Errorf("Wrong preferences of user (%d):\n\tWanted: %+v, Got: %+v", user.ID, userPreferences, userPreferencesFound)
+	}
+
+	userPreferences.Currency = "rubles"
+	if err := st.UserPreferences().Save(userPreferences); errors.Cause(err) != model.ErrValidationFailed {
+		t.Errorf("Wrong error when saving invalid preferences:\n\tWanted: %v, Got: %v", model.ErrValidationFailed, err)
+	}
+}
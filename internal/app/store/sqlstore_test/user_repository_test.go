@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
 )
 
@@ -65,6 +66,83 @@ func TestUserRepositoryUpdateEmail(t *testing.T) {
 	}
 }
 
+func TestUserRepositoryUpdateEmailVerified(t *testing.T) {
+	userWant := users[1]
+
+	if err := st.Users().UpdateEmailVerified(true, userWant.ID); err != nil {
+		t.Errorf("Couldn't verify email for user by ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+
+	userFound, err := st.Users().Find(userWant.ID)
+	if err != nil {
+		t.Errorf("Couldn't find user with ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+	if !userFound.EmailVerified {
+		t.Errorf("Email wasn't verified for user:\n\t%+v", userFound)
+	}
+
+	// Changed email must be verified again
+	if err := st.Users().UpdateEmail(userFound.Email, userWant.ID); err != nil {
+		t.Errorf("Couldn't update email for user by ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+
+	userFound, err = st.Users().Find(userWant.ID)
+	if err != nil {
+		t.Errorf("Couldn't find user with ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+	if userFound.EmailVerified {
+		t.Errorf("Email stayed verified after update for user:\n\t%+v", userFound)
+	}
+
+	var userIDNotExist uint64 = 100
+
+	if err := st.Users().UpdateEmailVerified(true, userIDNotExist); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Wrong error when verifying email for user with non-existent ID (%d):\n\t%v", userIDNotExist, err)
+	}
+}
+
+func TestUserRepositoryUpdateRoleBanned(t *testing.T) {
+	userWant := users[4]
+
+	if err := st.Users().UpdateRole(model.RoleModerator, userWant.ID); err != nil {
+		t.Errorf("Couldn't update role for user by ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+	if err := st.Users().UpdateRole("superuser", userWant.ID); errors.Cause(err) != model.ErrValidationFailed {
+		t.Errorf("Wrong error when setting unknown role for user by ID (%d):\n\t%v", userWant.ID, err)
+	}
+	if err := st.Users().UpdateBanned(true, userWant.ID); err != nil {
+		t.Errorf("Couldn't ban user by ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+
+	userFound, err := st.Users().Find(userWant.ID)
+	if err != nil {
+		t.Errorf("Couldn't find user with ID (%d):\n\t%s", userWant.ID, err.Error())
+		return
+	}
+	if userFound.Role != model.RoleModerator || !userFound.Banned {
+		t.Errorf("Role or ban wasn't updated for user:\n\t%+v", userFound)
+	}
+
+	if err := st.Users().UpdateBanned(false, userWant.ID); err != nil {
+		t.Errorf("Couldn't unban user by ID (%d):\n\t%s", userWant.ID, err.Error())
+	}
+	if err := st.Users().UpdateRole(model.RoleUser, userWant.ID); err != nil {
+		t.Errorf("Couldn't update role for user by ID (%d):\n\t%s", userWant.ID, err.Error())
+	}
+
+	var userIDNotExist uint64 = 100
+
+	if err := st.Users().UpdateBanned(true, userIDNotExist); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Wrong error when banning user with non-existent ID (%d):\n\t%v", userIDNotExist, err)
+	}
+}
+
 func TestUserRepositoryUpdatePassword(t *testing.T) {
 	userWant := users[3]
 	newPassword := "new_Password_4"
@@ -0,0 +1,99 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+func TestUserTOTPRepository(t *testing.T) {
+	user := users[2]
+
+	userTOTP := &model.UserTOTP{
+		Secret:    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		CreatedAt: time.Now().Truncate(time.Second),
+		User:      user,
+	}
+
+	if err := st.UserTOTP().Save(userTOTP); err != nil {
+		t.Errorf("Couldn't save TOTP of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	userTOTPFound, err := st.UserTOTP().FindByUser(user)
+	if err != nil {
+		t.Errorf("Couldn't find TOTP of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if userTOTPFound.Secret != userTOTP.Secret || userTOTPFound.Enabled {
+		t.Errorf("Wrong TOTP of user (%d):\n\tWanted: %+v, Got: %+v", user.ID, userTOTP, userTOTPFound)
+	}
+
+	if err := st.UserTOTP().UseStep(user, 100); err != nil {
+		t.Errorf("Couldn't use TOTP step:\n\t%s", err.Error())
+		return
+	}
+	if err := st.UserTOTP().UseStep(user, 100); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Step was used twice:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+	if userTOTPFound, err := st.UserTOTP().FindByUser(user); err != nil || userTOTPFound.Enabled || userTOTPFound.LastUsedStep != 100 {
+		t.Errorf("TOTP of user (%d) has been enabled by using a step:\n\tGot: %+v (%v)", user.ID, userTOTPFound, err)
+	}
+
+	if err := st.UserTOTP().Enable(user, []string{"hash-1", "hash-2"}); err != nil {
+		t.Errorf("Couldn't enable TOTP of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if err := st.UserTOTP().Enable(user, []string{"hash-3"}); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("TOTP was enabled twice:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+	if userTOTPFound, err := st.UserTOTP().FindByUser(user); err != nil || !userTOTPFound.Enabled {
+		t.Errorf("TOTP of user (%d) hasn't been enabled:\n\tGot: %+v (%v)", user.ID, userTOTPFound, err)
+	}
+	if count, err := st.RecoveryCodes().CountUnused(user); err != nil || count != 2 {
+		t.Errorf("Wrong number of recovery codes after enabling:\n\tWanted: 2, Got: %d (%v)", count, err)
+	}
+
+	// Regenerating codes of enabled TOTP replaces the codes saved on enabling
+	if err := st.RecoveryCodes().ReplaceAll(user, []string{"hash-3", "hash-4"}); err != nil {
+		t.Errorf("Couldn't regenerate recovery codes:\n\t%s", err.Error())
+		return
+	}
+	if err := st.RecoveryCodes().Use(user, "hash-1", time.Now()); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Replaced recovery code was accepted:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+	if err := st.RecoveryCodes().Use(user, "hash-3", time.Now()); err != nil {
+		t.Errorf("Regenerated recovery code wasn't accepted:\n\t%s", err.Error())
+	}
+	if userTOTPFound, err := st.UserTOTP().FindByUser(user); err != nil || !userTOTPFound.Enabled {
+		t.Errorf("TOTP of user (%d) has been disabled by regenerating codes:\n\tGot: %+v (%v)", user.ID, userTOTPFound, err)
+	}
+
+	if err := st.RecoveryCodes().ReplaceAll(user, []string{"hash-1", "hash-2"}); err != nil {
+		t.Errorf("Couldn't save recovery codes:\n\t%s", err.Error())
+		return
+	}
+	if err := st.RecoveryCodes().Use(user, "hash-1", time.Now()); err != nil {
+		t.Errorf("Couldn't use recovery code:\n\t%s", err.Error())
+	}
+	if err := st.RecoveryCodes().Use(user, "hash-1", time.Now()); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Recovery code was used twice:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+	if count, err := st.RecoveryCodes().CountUnused(user); err != nil || count != 1 {
+		t.Errorf("Wrong number of unused recovery codes:\n\tWanted: 1, Got: %d (%v)", count, err)
+	}
+
+	if err := st.UserTOTP().Delete(user); err != nil {
+		t.Errorf("Couldn't delete TOTP of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if _, err := st.UserTOTP().FindByUser(user); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("TOTP of user (%d) hasn't been deleted:\n\tWanted: %v, Got: %v", user.ID, store.ErrNotFound, err)
+	}
+	if count, err := st.RecoveryCodes().CountUnused(user); err != nil || count != 0 {
+		t.Errorf("Recovery codes haven't been deleted:\n\tWanted: 0, Got: %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,182 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/model"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/store"
+)
+
+func TestWishlistRepository(t *testing.T) {
+	user := users[4]
+
+	wishlistDefault, err := st.Wishlists().FindOrCreateDefault(user)
+	if err != nil {
+		t.Errorf("Couldn't create default wishlist of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if !wishlistDefault.IsDefault || wishlistDefault.Name != model.DefaultWishlistName {
+		t.Errorf("Wrong default wishlist of user (%d):\n\tGot: %+v", user.ID, wishlistDefault)
+	}
+
+	if wishlistDefaultAgain, err := st.Wishlists().FindOrCreateDefault(user); err != nil || wishlistDefaultAgain.ID != wishlistDefault.ID {
+		t.Errorf("Default wishlist of user (%d) has been duplicated:\n\tWanted ID: %d, Got: %+v (%v)", user.ID, wishlistDefault.ID, wishlistDefaultAgain, err)
+	}
+
+	wishlist := &model.Wishlist{
+		Name:      "Buy on sale",
+		CreatedAt: time.Now().Truncate(time.Second),
+		User:      user,
+	}
+	if err := st.Wishlists().Create(wishlist); err != nil {
+		t.Errorf("Couldn't create wishlist of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if wishlist.Position <= wishlistDefault.Position {
+		t.Errorf("New wishlist isn't after the default one:\n\tDefault: %d, Got: %d", wishlistDefault.Position, wishlist.Position)
+	}
+
+	wishlistDuplicate := &model.Wishlist{
+		Name:      wishlist.Name,
+		CreatedAt: time.Now(),
+		User:      user,
+	}
+	if err := st.Wishlists().Create(wishlistDuplicate); errors.Cause(err) != store.ErrConflict {
+		t.Errorf("Wrong error when creating wishlist with existing name:\n\tWanted: %v, Got: %v", store.ErrConflict, err)
+	}
+
+	wishlist.Name = "Co-op with friends"
+	if err := st.Wishlists().Update(wishlist); err != nil {
+		t.Errorf("Couldn't rename wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+	}
+
+	if err := st.Wishlists().Reorder(user, []uint64{wishlist.ID, wishlistDefault.ID}); err != nil {
+		t.Errorf("Couldn't reorder wishlists of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	wishlists, err := st.Wishlists().FindAllByUser(user)
+	if err != nil {
+		t.Errorf("Couldn't find wishlists of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+	if len(wishlists) != 2 || wishlists[0].ID != wishlist.ID || wishlists[0].Name != wishlist.Name || wishlists[1].ID != wishlistDefault.ID {
+		t.Errorf("Wrong wishlists of user (%d) after reordering:\n\tGot: %+v", user.ID, wishlists)
+	}
+
+	if err := st.Wishlists().Reorder(users[0], []uint64{wishlist.ID}); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Wrong error when reordering wishlist of another user:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+
+	if err := st.Wishlists().Delete(wishlist.ID); err != nil {
+		t.Errorf("Couldn't delete wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+		return
+	}
+	if _, err := st.Wishlists().Find(wishlist.ID); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Deleted wishlist (%d) was found:\n\tWanted: %v, Got: %v", wishlist.ID, store.ErrNotFound, err)
+	}
+}
+
+func TestWishlistItemRepository(t *testing.T) {
+	user := users[4]
+
+	wishlist, err := st.Wishlists().FindOrCreateDefault(user)
+	if err != nil {
+		t.Errorf("Couldn't find default wishlist of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	targetPrice := 499
+	wishlistItem := &model.WishlistItem{
+		Priority:    3,
+		Note:        "Wait for the bundle",
+		TargetPrice: &targetPrice,
+		CreatedAt:   time.Now().Truncate(time.Second),
+		Wishlist:    wishlist,
+		Game:        games[2],
+	}
+	if err := st.WishlistItems().Create(wishlistItem); err != nil {
+		t.Errorf("Couldn't create item of wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+		return
+	}
+
+	wishlistItemDuplicate := &model.WishlistItem{
+		CreatedAt: time.Now(),
+		Wishlist:  wishlist,
+		Game:      games[2],
+	}
+	if err := st.WishlistItems().Create(wishlistItemDuplicate); errors.Cause(err) != store.ErrConflict {
+		t.Errorf("Wrong error when adding game to wishlist twice:\n\tWanted: %v, Got: %v", store.ErrConflict, err)
+	}
+
+	// Items of the default list are favourites
+	if _, err := st.UserGameFavourites().FindByUserGame(user, games[2]); err != nil {
+		t.Errorf("Item of default wishlist isn't a favourite:\n\t%s", err.Error())
+	}
+
+	wishlistItem.Priority = 5
+	wishlistItem.TargetPrice = nil
+	if err := st.WishlistItems().Update(wishlistItem); err != nil {
+		t.Errorf("Couldn't update wishlist item (%d):\n\t%s", wishlistItem.ID, err.Error())
+		return
+	}
+
+	wishlistItems, err := st.WishlistItems().FindAllByWishlist(wishlist)
+	if err != nil {
+		t.Errorf("Couldn't find items of wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+		return
+	}
+	if len(wishlistItems) != 1 || wishlistItems[0].Priority != 5 || wishlistItems[0].TargetPrice != nil ||
+		wishlistItems[0].Note != wishlistItem.Note || wishlistItems[0].Game.ID != games[2].ID {
+		t.Errorf("Wrong items of wishlist (%d):\n\tWanted: %+v, Got: %+v", wishlist.ID, wishlistItem, wishlistItems)
+	}
+
+	if err := st.WishlistItems().Delete(wishlistItem.ID); err != nil {
+		t.Errorf("Couldn't delete wishlist item (%d):\n\t%s", wishlistItem.ID, err.Error())
+		return
+	}
+	if _, err := st.WishlistItems().Find(wishlistItem.ID); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Deleted wishlist item (%d) was found:\n\tWanted: %v, Got: %v", wishlistItem.ID, store.ErrNotFound, err)
+	}
+}
+
+func TestWishlistRepositorySharing(t *testing.T) {
+	user := users[4]
+
+	slug := "test-public-slug"
+	wishlist := &model.Wishlist{
+		Name:      "Birthday",
+		CreatedAt: time.Now().Truncate(time.Second),
+		User:      user,
+	}
+	if err := st.Wishlists().Create(wishlist); err != nil {
+		t.Errorf("Couldn't create wishlist of user (%d):\n\t%s", user.ID, err.Error())
+		return
+	}
+
+	wishlist.IsPublic = true
+	wishlist.PublicSlug = &slug
+	if err := st.Wishlists().UpdateSharing(wishlist); err != nil {
+		t.Errorf("Couldn't publish wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+		return
+	}
+	if wishlistFound, err := st.Wishlists().FindByPublicSlug(slug); err != nil || wishlistFound.ID != wishlist.ID {
+		t.Errorf("Couldn't find public wishlist by slug (%s):\n\tWanted ID: %d, Got: %+v (%v)", slug, wishlist.ID, wishlistFound, err)
+	}
+
+	// Hidden list keeps its slug, but can't be found by it
+	wishlist.IsPublic = false
+	if err := st.Wishlists().UpdateSharing(wishlist); err != nil {
+		t.Errorf("Couldn't hide wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+		return
+	}
+	if _, err := st.Wishlists().FindByPublicSlug(slug); errors.Cause(err) != store.ErrNotFound {
+		t.Errorf("Wrong error when finding hidden wishlist by slug:\n\tWanted: %v, Got: %v", store.ErrNotFound, err)
+	}
+
+	if err := st.Wishlists().Delete(wishlist.ID); err != nil {
+		t.Errorf("Couldn't delete wishlist (%d):\n\t%s", wishlist.ID, err.Error())
+	}
+}
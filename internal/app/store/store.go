@@ -10,4 +10,15 @@ type Store interface {
 	GameTags() GameTagRepository
 	GameMarketPrices() GameMarketPriceRepository
 	MarketBlacklist() MarketBlacklistItemRepository
+	Giveaways() GiveawayRepository
+	PriceStats() PriceStatsRepository
+	SecurityEvents() SecurityEventRepository
+	UserTOTP() UserTOTPRepository
+	RecoveryCodes() RecoveryCodeRepository
+	UserIdentities() UserIdentityRepository
+	UserOwnedGames() UserOwnedGameRepository
+	Wishlists() WishlistRepository
+	WishlistItems() WishlistItemRepository
+	UserPreferences() UserPreferencesRepository
+	GameSimilarities() GameSimilarityRepository
 }
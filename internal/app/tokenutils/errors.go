@@ -7,6 +7,7 @@ var (
 	ErrTokenNotProvided      = errors.New("Authentication token wasn't provided")
 	ErrTokenExpiredOrDeleted = errors.New("Authentication token expired or has been deleted")
 	ErrTokenDamaged          = errors.New("Authentication token has been damaged")
+	ErrSessionNotFound       = errors.New("Session not found")
 	ErrInternal              = errors.New("Internal error")
 )
 
@@ -14,7 +15,7 @@ const (
 	errTokenUtilsMessageFormat   = "TokenUtils %s error"
 	errTokenDeleteMessage        = "Couldn't delete token"
 	errTokenCreateMessage        = "Couldn't create token"
-	errTokenSaveMessage          = "Couldn't save token to redis db"
+	errTokenSaveMessage          = "Couldn't save token to token store"
 	errTokenValidationMessage    = "Something wrong with token validation"
 	errTokenUUIDMessage          = "Something wrong with uuid"
 	errTokenClaimsMessage        = "Something wrong with claims"
@@ -22,6 +23,9 @@ const (
 	errTokenSigningMethodMessage = "Unexpected signing method"
 	errTokenExpiredMessage       = "Token is expired"
 	errUintParseMessage          = "Couldn't parse uint"
-	errRedisMessage              = "Something wrong in redis"
-	errRedisNilMessage           = "redis: nil"
+	errKeyLoadMessage            = "Couldn't load signing key"
+	errKeyNoneMessage            = "There is no active signing key"
+	errKeyIDMessage              = "Signing key ID is empty or duplicated"
+	errKeyUnknownMessage         = "Unknown or retired signing key"
+	errStoreMessage              = "Something wrong in token store"
 )
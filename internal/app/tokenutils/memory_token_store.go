@@ -0,0 +1,249 @@
+package tokenutils
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryTokenStore keeps everything in the process memory, it is meant for tests and local development
+type MemoryTokenStore struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]*memoryTokenStoreEntry
+}
+
+// Only one of value, hash and set is used by an entry
+type memoryTokenStoreEntry struct {
+	value     string
+	hash      map[string]string
+	set       map[string]struct{}
+	expiresAt time.Time
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return NewMemoryTokenStoreWithClock(time.Now)
+}
+
+// NewMemoryTokenStoreWithClock lets tests move time forward to check expiration
+func NewMemoryTokenStoreWithClock(now func() time.Time) *MemoryTokenStore {
+	return &MemoryTokenStore{
+		now:     now,
+		entries: map[string]*memoryTokenStoreEntry{},
+	}
+}
+
+// entry must be called with the lock held, expired entries are deleted on access
+func (memoryTokenStore *MemoryTokenStore) entry(key string) *memoryTokenStoreEntry {
+	entry, ok := memoryTokenStore.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if !entry.expiresAt.IsZero() && !memoryTokenStore.now().Before(entry.expiresAt) {
+		delete(memoryTokenStore.entries, key)
+		return nil
+	}
+
+	return entry
+}
+
+func (memoryTokenStore *MemoryTokenStore) expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return memoryTokenStore.now().Add(ttl)
+}
+
+func (memoryTokenStore *MemoryTokenStore) Set(key string, value string, ttl time.Duration) error {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	memoryTokenStore.entries[key] = &memoryTokenStoreEntry{
+		value:     value,
+		expiresAt: memoryTokenStore.expiresAt(ttl),
+	}
+
+	return nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) SetIfNotExists(key string, value string, ttl time.Duration) (bool, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	if memoryTokenStore.entry(key) != nil {
+		return false, nil
+	}
+
+	memoryTokenStore.entries[key] = &memoryTokenStoreEntry{
+		value:     value,
+		expiresAt: memoryTokenStore.expiresAt(ttl),
+	}
+
+	return true, nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) Get(key string) (string, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	entry := memoryTokenStore.entry(key)
+	if entry == nil {
+		return "", ErrStoreKeyNotFound
+	}
+
+	return entry.value, nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) GetDelete(key string) (string, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	entry := memoryTokenStore.entry(key)
+	if entry == nil {
+		return "", ErrStoreKeyNotFound
+	}
+
+	delete(memoryTokenStore.entries, key)
+
+	return entry.value, nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) Exists(key string) (bool, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	return memoryTokenStore.entry(key) != nil, nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) Delete(keys ...string) error {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	for _, key := range keys {
+		delete(memoryTokenStore.entries, key)
+	}
+
+	return nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) Expire(key string, ttl time.Duration) error {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	if entry := memoryTokenStore.entry(key); entry != nil {
+		entry.expiresAt = memoryTokenStore.expiresAt(ttl)
+	}
+
+	return nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) HashSet(key string, fields map[string]string) error {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	entry := memoryTokenStore.entry(key)
+	if entry == nil {
+		entry = &memoryTokenStoreEntry{}
+		memoryTokenStore.entries[key] = entry
+	}
+	if entry.hash == nil {
+		entry.hash = map[string]string{}
+	}
+
+	for field, value := range fields {
+		entry.hash[field] = value
+	}
+
+	return nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) HashUpdate(key string, fields map[string]string) (bool, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	entry := memoryTokenStore.entry(key)
+	if entry == nil {
+		return false, nil
+	}
+	if entry.hash == nil {
+		entry.hash = map[string]string{}
+	}
+
+	for field, value := range fields {
+		entry.hash[field] = value
+	}
+
+	return true, nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) HashGetAll(key string) (map[string]string, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	fields := map[string]string{}
+
+	if entry := memoryTokenStore.entry(key); entry != nil {
+		for field, value := range entry.hash {
+			fields[field] = value
+		}
+	}
+
+	return fields, nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) SetAdd(key string, members ...string) error {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	entry := memoryTokenStore.entry(key)
+	if entry == nil {
+		entry = &memoryTokenStoreEntry{}
+		memoryTokenStore.entries[key] = entry
+	}
+	if entry.set == nil {
+		entry.set = map[string]struct{}{}
+	}
+
+	for _, member := range members {
+		entry.set[member] = struct{}{}
+	}
+
+	return nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) SetRemove(key string, members ...string) error {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	entry := memoryTokenStore.entry(key)
+	if entry == nil {
+		return nil
+	}
+
+	for _, member := range members {
+		delete(entry.set, member)
+	}
+
+	// Like in Redis, empty set doesn't exist
+	if len(entry.set) == 0 {
+		delete(memoryTokenStore.entries, key)
+	}
+
+	return nil
+}
+
+func (memoryTokenStore *MemoryTokenStore) SetMembers(key string) ([]string, error) {
+	memoryTokenStore.mu.Lock()
+	defer memoryTokenStore.mu.Unlock()
+
+	members := []string{}
+
+	if entry := memoryTokenStore.entry(key); entry != nil {
+		for member := range entry.set {
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
@@ -0,0 +1,156 @@
+package tokenutils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type OneTimeTokenPurpose string
+
+const (
+	PurposeEmailVerification OneTimeTokenPurpose = "email_verification"
+	PurposePasswordReset     OneTimeTokenPurpose = "password_reset"
+	// PurposeLoginChallenge is the first step of login for users with second factor
+	PurposeLoginChallenge OneTimeTokenPurpose = "login_challenge"
+	// PurposeExternalLogin and PurposeExternalLink are states of external provider flows,
+	// PurposeExternalRegistration lets the user finish registration after unknown identity logged in
+	PurposeExternalLogin        OneTimeTokenPurpose = "external_login"
+	PurposeExternalLink         OneTimeTokenPurpose = "external_link"
+	PurposeExternalRegistration OneTimeTokenPurpose = "external_registration"
+)
+
+// OneTimeTokenDetails is what the token was issued for. Email is saved too,
+// so verification token stops working after the user changes the email.
+// Tokens of external provider flows also keep the provider and its nonce or the identity found.
+type OneTimeTokenDetails struct {
+	UserID   uint64 `json:"user_id"`
+	Email    string `json:"email"`
+	Provider string `json:"provider,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+// Only hash of the token is saved, so tokens can't be taken from the store
+func oneTimeTokenKey(purpose OneTimeTokenPurpose, token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("one_time:%s:%s", purpose, hex.EncodeToString(hash[:]))
+}
+
+// CreateOneTimeToken creates random token for the purpose, that expires after ttl
+func (service *Service) CreateOneTimeToken(purpose OneTimeTokenPurpose, details *OneTimeTokenDetails, ttl time.Duration) (string, error) {
+	methodName := "CreateOneTimeToken"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenCreateMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	detailsRaw, err := json.Marshal(details)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenCreateMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	if err := service.store.Set(oneTimeTokenKey(purpose, token), string(detailsRaw), ttl); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenSaveMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	return token, nil
+}
+
+// ConsumeOneTimeToken returns details of the token and deletes it atomically,
+// so the token can't be used twice even by parallel requests
+func (service *Service) ConsumeOneTimeToken(purpose OneTimeTokenPurpose, token string) (*OneTimeTokenDetails, error) {
+	methodName := "ConsumeOneTimeToken"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	key := oneTimeTokenKey(purpose, token)
+
+	detailsRaw, err := service.store.GetDelete(key)
+	if err != nil {
+		if err == ErrStoreKeyNotFound {
+			errWrapped := errors.Wrap(ErrTokenExpiredOrDeleted, errWrapMessage)
+			return nil, errWrapped
+		}
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	details := &OneTimeTokenDetails{}
+	if err := json.Unmarshal([]byte(detailsRaw), details); err != nil {
+		errWrapped := errors.Wrap(ErrTokenDamaged, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return details, nil
+}
+
+// PeekOneTimeToken returns details of the token without deleting it, so a wrong second factor
+// doesn't waste the challenge. ConsumeOneTimeToken must be called once the token has done its job.
+func (service *Service) PeekOneTimeToken(purpose OneTimeTokenPurpose, token string) (*OneTimeTokenDetails, error) {
+	methodName := "PeekOneTimeToken"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	detailsRaw, err := service.store.Get(oneTimeTokenKey(purpose, token))
+	if err != nil {
+		if err == ErrStoreKeyNotFound {
+			errWrapped := errors.Wrap(ErrTokenExpiredOrDeleted, errWrapMessage)
+			return nil, errWrapped
+		}
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	details := &OneTimeTokenDetails{}
+	if err := json.Unmarshal([]byte(detailsRaw), details); err != nil {
+		errWrapped := errors.Wrap(ErrTokenDamaged, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return details, nil
+}
+
+func usedNonceKey(nonce string) string {
+	hash := sha256.Sum256([]byte(nonce))
+	return "used_nonce:" + hex.EncodeToString(hash[:])
+}
+
+// UseNonce remembers the nonce of external provider's answer for ttl,
+// false means the nonce has already been used and the answer is replayed
+func (service *Service) UseNonce(nonce string, ttl time.Duration) (bool, error) {
+	methodName := "UseNonce"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	used, err := service.store.SetIfNotExists(usedNonceKey(nonce), "1", ttl)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return false, errWrapped
+	}
+
+	return used, nil
+}
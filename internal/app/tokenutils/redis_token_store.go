@@ -0,0 +1,137 @@
+package tokenutils
+
+import (
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// hashUpdateScript sets fields of the hash only if it exists, so a key deleted concurrently isn't recreated without TTL
+var hashUpdateScript = redis.NewScript(
+	"if redis.call('EXISTS', KEYS[1]) == 0 then return 0 end " +
+		"redis.call('HMSET', KEYS[1], unpack(ARGV)) " +
+		"return 1",
+)
+
+type RedisTokenStore struct {
+	client *redis.Client
+}
+
+// NewRedisClient connects to redis and checks the connection
+func NewRedisClient(redisAddr string) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	if _, err := client.Ping().Result(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func NewRedisTokenStore(client *redis.Client) *RedisTokenStore {
+	return &RedisTokenStore{
+		client: client,
+	}
+}
+
+func (redisTokenStore *RedisTokenStore) Set(key string, value string, ttl time.Duration) error {
+	return redisTokenStore.client.Set(key, value, ttl).Err()
+}
+
+func (redisTokenStore *RedisTokenStore) SetIfNotExists(key string, value string, ttl time.Duration) (bool, error) {
+	return redisTokenStore.client.SetNX(key, value, ttl).Result()
+}
+
+func (redisTokenStore *RedisTokenStore) Get(key string) (string, error) {
+	value, err := redisTokenStore.client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrStoreKeyNotFound
+	}
+
+	return value, err
+}
+
+func (redisTokenStore *RedisTokenStore) GetDelete(key string) (string, error) {
+	var getResult *redis.StringCmd
+	if _, err := redisTokenStore.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		getResult = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	}); err != nil && err != redis.Nil {
+		return "", err
+	}
+
+	value, err := getResult.Result()
+	if err == redis.Nil {
+		return "", ErrStoreKeyNotFound
+	}
+
+	return value, err
+}
+
+func (redisTokenStore *RedisTokenStore) Exists(key string) (bool, error) {
+	count, err := redisTokenStore.client.Exists(key).Result()
+	return count > 0, err
+}
+
+func (redisTokenStore *RedisTokenStore) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return redisTokenStore.client.Del(keys...).Err()
+}
+
+func (redisTokenStore *RedisTokenStore) Expire(key string, ttl time.Duration) error {
+	return redisTokenStore.client.Expire(key, ttl).Err()
+}
+
+func (redisTokenStore *RedisTokenStore) HashSet(key string, fields map[string]string) error {
+	values := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		values[field] = value
+	}
+
+	return redisTokenStore.client.HMSet(key, values).Err()
+}
+
+func (redisTokenStore *RedisTokenStore) HashUpdate(key string, fields map[string]string) (bool, error) {
+	if len(fields) == 0 {
+		return redisTokenStore.Exists(key)
+	}
+
+	args := make([]interface{}, 0, 2*len(fields))
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+
+	updated, err := hashUpdateScript.Run(redisTokenStore.client, []string{key}, args...).Int64()
+	return updated == 1, err
+}
+
+func (redisTokenStore *RedisTokenStore) HashGetAll(key string) (map[string]string, error) {
+	return redisTokenStore.client.HGetAll(key).Result()
+}
+
+func (redisTokenStore *RedisTokenStore) SetAdd(key string, members ...string) error {
+	return redisTokenStore.client.SAdd(key, stringsToInterfaces(members)...).Err()
+}
+
+func (redisTokenStore *RedisTokenStore) SetRemove(key string, members ...string) error {
+	return redisTokenStore.client.SRem(key, stringsToInterfaces(members)...).Err()
+}
+
+func (redisTokenStore *RedisTokenStore) SetMembers(key string) ([]string, error) {
+	return redisTokenStore.client.SMembers(key).Result()
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+
+	return result
+}
@@ -0,0 +1,52 @@
+package tokenutils
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Every refresh creates new pair of tokens for the same session, so all refresh tokens
+// of a session form a family. Rotated refresh tokens are remembered until they would have expired:
+// if one of them comes again, it has been stolen, and the whole family is revoked.
+
+func rotatedRefreshKey(refreshUuid string) string {
+	return "rotated_refresh:" + refreshUuid
+}
+
+// RevokeReusedRefreshToken must be called for refresh token, that turned out to be expired or deleted.
+// If it has been rotated already, the session it belongs to is deleted with all its tokens
+// and details of the reused token are returned.
+// Returns ErrTokenExpiredOrDeleted if the token hasn't been rotated, e.g. the session was just closed.
+func (service *Service) RevokeReusedRefreshToken(tokenString string) (*TokenDetails, error) {
+	methodName := "RevokeReusedRefreshToken"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	tokenDetails, err := service.parseToken(tokenString)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	sessionID, err := service.store.Get(rotatedRefreshKey(tokenDetails.Uuid))
+	if err != nil {
+		if err == ErrStoreKeyNotFound {
+			errWrapped := errors.Wrap(ErrTokenExpiredOrDeleted, errWrapMessage)
+			return nil, errWrapped
+		}
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	// Family may have been revoked already, it is still a reuse
+	if err := service.DeleteSession(tokenDetails.UserId, sessionID); err != nil && errors.Cause(err) != ErrSessionNotFound {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	tokenDetails.SessionID = sessionID
+
+	return tokenDetails, nil
+}
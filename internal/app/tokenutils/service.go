@@ -0,0 +1,33 @@
+package tokenutils
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Service issues and checks tokens and keeps sessions of users in the token store
+type Service struct {
+	store TokenStore
+	// signingKeys are sorted by ActiveFrom
+	signingKeys []*SigningKey
+	// legacySecret verifies HS256 tokens issued before signing keys were introduced, empty disables them
+	legacySecret string
+}
+
+func NewService(store TokenStore, signingKeys []*SigningKey, legacySecret string) (*Service, error) {
+	methodName := "NewService"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	keysSorted, err := sortSigningKeys(signingKeys)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return &Service{
+		store:        store,
+		signingKeys:  keysSorted,
+		legacySecret: legacySecret,
+	}, nil
+}
@@ -0,0 +1,227 @@
+package tokenutils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Session is one login of the user on some device. It holds uuids of the current token pair,
+// so all tokens of the user can be found through the sessions index without scanning the store.
+type SessionDetails struct {
+	ID          string
+	UserID      uint64
+	DeviceName  string
+	IP          string
+	UserAgent   string
+	CreatedAt   time.Time
+	LastUsedAt  time.Time
+	AccessUuid  string
+	RefreshUuid string
+}
+
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func userSessionsKey(userid uint64) string {
+	return fmt.Sprintf("user_sessions:%d", userid)
+}
+
+// CreateSession saves session without tokens, CreateTokens must be called for it right after
+func (service *Service) CreateSession(userid uint64, deviceName string, ip string, userAgent string) (*SessionDetails, error) {
+	methodName := "CreateSession"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	now := time.Now()
+	session := &SessionDetails{
+		ID:         uuid.New().String(),
+		UserID:     userid,
+		DeviceName: deviceName,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+
+	if err := service.saveSession(session); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenSaveMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return session, nil
+}
+
+func (service *Service) saveSession(session *SessionDetails) error {
+	if err := service.store.HashSet(sessionKey(session.ID), map[string]string{
+		"user_id":      strconv.FormatUint(session.UserID, 10),
+		"device_name":  session.DeviceName,
+		"ip":           session.IP,
+		"user_agent":   session.UserAgent,
+		"created_at":   strconv.FormatInt(session.CreatedAt.Unix(), 10),
+		"last_used_at": strconv.FormatInt(session.LastUsedAt.Unix(), 10),
+	}); err != nil {
+		return err
+	}
+
+	if err := service.store.Expire(sessionKey(session.ID), refreshTokenTTL); err != nil {
+		return err
+	}
+
+	if err := service.store.SetAdd(userSessionsKey(session.UserID), session.ID); err != nil {
+		return err
+	}
+
+	return service.store.Expire(userSessionsKey(session.UserID), refreshTokenTTL)
+}
+
+// FetchSession returns ErrSessionNotFound if the session has expired or has been deleted
+func (service *Service) FetchSession(sessionID string) (*SessionDetails, error) {
+	methodName := "FetchSession"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	fields, err := service.store.HashGetAll(sessionKey(sessionID))
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	if len(fields) == 0 {
+		errWrapped := errors.Wrap(ErrSessionNotFound, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	userid, _ := strconv.ParseUint(fields["user_id"], 10, 64)
+	createdAt, _ := strconv.ParseInt(fields["created_at"], 10, 64)
+	lastUsedAt, _ := strconv.ParseInt(fields["last_used_at"], 10, 64)
+
+	return &SessionDetails{
+		ID:          sessionID,
+		UserID:      userid,
+		DeviceName:  fields["device_name"],
+		IP:          fields["ip"],
+		UserAgent:   fields["user_agent"],
+		CreatedAt:   time.Unix(createdAt, 0),
+		LastUsedAt:  time.Unix(lastUsedAt, 0),
+		AccessUuid:  fields["access_uuid"],
+		RefreshUuid: fields["refresh_uuid"],
+	}, nil
+}
+
+// TouchSession updates the time the session was used last, deleted session isn't recreated
+func (service *Service) TouchSession(sessionID string, usedAt time.Time) error {
+	methodName := "TouchSession"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	// Checking and updating in one step, so the session revoked meanwhile isn't recreated without TTL
+	updated, err := service.store.HashUpdate(sessionKey(sessionID), map[string]string{
+		"last_used_at": strconv.FormatInt(usedAt.Unix(), 10),
+	})
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	if !updated {
+		errWrapped := errors.Wrap(ErrSessionNotFound, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
+
+// ListSessions returns active sessions of the user, the most recently used first.
+// Expired sessions are removed from the index on the way.
+func (service *Service) ListSessions(userid uint64) ([]*SessionDetails, error) {
+	methodName := "ListSessions"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	sessionIDs, err := service.store.SetMembers(userSessionsKey(userid))
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	sessions := []*SessionDetails{}
+
+	for _, sessionID := range sessionIDs {
+		session, err := service.FetchSession(sessionID)
+		if err != nil {
+			if errors.Cause(err) != ErrSessionNotFound {
+				errWrapped := errors.Wrap(err, errWrapMessage)
+				return nil, errWrapped
+			}
+
+			if err := service.store.SetRemove(userSessionsKey(userid), sessionID); err != nil {
+				errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+				errWrapped = errors.Wrap(errWrapped, err.Error())
+				errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+				return nil, errWrapped
+			}
+
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+// DeleteSession deletes the session of the user with its tokens.
+// Returns ErrSessionNotFound if there is no such session or it belongs to another user.
+func (service *Service) DeleteSession(userid uint64, sessionID string) error {
+	methodName := "DeleteSession"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	session, err := service.FetchSession(sessionID)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
+	}
+
+	if session.UserID != userid {
+		errWrapped := errors.Wrap(ErrSessionNotFound, errWrapMessage)
+		return errWrapped
+	}
+
+	keys := []string{sessionKey(sessionID)}
+	if session.AccessUuid != "" {
+		keys = append(keys, session.AccessUuid)
+	}
+	if session.RefreshUuid != "" {
+		keys = append(keys, session.RefreshUuid)
+	}
+
+	if err := service.store.Delete(keys...); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenDeleteMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	if err := service.store.SetRemove(userSessionsKey(userid), sessionID); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenDeleteMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package tokenutils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+// SigningKey is one of the keys tokens are signed with, tokens name it in "kid" header.
+// The key signs new tokens from ActiveFrom till the next key becomes active,
+// and is used for verification (and published in JWKS) till RetireAt.
+type SigningKey struct {
+	ID         string
+	ActiveFrom time.Time
+	RetireAt   time.Time // zero means the key is never retired
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// LoadSigningKey reads PEM encoded RSA (RS256) or Ed25519 (EdDSA) private key from the file
+func LoadSigningKey(id string, path string, activeFrom time.Time, retireAt time.Time) (*SigningKey, error) {
+	methodName := "LoadSigningKey"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	keyPEM, err := ioutil.ReadFile(path)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errKeyLoadMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	signingKey := &SigningKey{
+		ID:         id,
+		ActiveFrom: activeFrom,
+		RetireAt:   retireAt,
+	}
+
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyPEM); err == nil {
+		signingKey.method = jwt.SigningMethodRS256
+		signingKey.privateKey = rsaKey
+		signingKey.publicKey = &rsaKey.PublicKey
+		return signingKey, nil
+	}
+
+	edKey, err := jwt.ParseEdPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errKeyLoadMessage)
+		errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("Key %s is neither RSA nor Ed25519 private key", id))
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	signingKey.method = jwt.SigningMethodEdDSA
+	signingKey.privateKey = edKey
+	signingKey.publicKey = edKey.(ed25519.PrivateKey).Public()
+
+	return signingKey, nil
+}
+
+// NewEphemeralSigningKey generates Ed25519 key, that lives only in memory.
+// Tokens signed with it stop working after restart, so it is meant for development and tests.
+func NewEphemeralSigningKey(id string) (*SigningKey, error) {
+	methodName := "NewEphemeralSigningKey"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errKeyLoadMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return &SigningKey{
+		ID:         id,
+		method:     jwt.SigningMethodEdDSA,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}, nil
+}
+
+// sortSigningKeys checks, that there is at least one key and ids are unique, and sorts keys by ActiveFrom
+func sortSigningKeys(keys []*SigningKey) ([]*SigningKey, error) {
+	methodName := "SortSigningKeys"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	if len(keys) == 0 {
+		errWrapped := errors.Wrap(ErrInternal, errKeyNoneMessage)
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	ids := map[string]bool{}
+	for _, key := range keys {
+		if key.ID == "" || ids[key.ID] {
+			errWrapped := errors.Wrap(ErrInternal, errKeyIDMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("ID = %q", key.ID))
+			errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+			return nil, errWrapped
+		}
+		ids[key.ID] = true
+	}
+
+	keysSorted := make([]*SigningKey, len(keys))
+	copy(keysSorted, keys)
+	sort.SliceStable(keysSorted, func(i, j int) bool {
+		return keysSorted[i].ActiveFrom.Before(keysSorted[j].ActiveFrom)
+	})
+
+	return keysSorted, nil
+}
+
+func (signingKey *SigningKey) isRetired(now time.Time) bool {
+	return !signingKey.RetireAt.IsZero() && !now.Before(signingKey.RetireAt)
+}
+
+// currentSigningKey returns the most recently activated key, that isn't retired
+func (service *Service) currentSigningKey(now time.Time) *SigningKey {
+	var current *SigningKey
+
+	for _, key := range service.signingKeys {
+		if key.ActiveFrom.After(now) {
+			break
+		}
+		if !key.isRetired(now) {
+			current = key
+		}
+	}
+
+	return current
+}
+
+// findVerificationKey accepts keys, that aren't active yet, so they can be published in advance
+func (service *Service) findVerificationKey(id string, now time.Time) *SigningKey {
+	for _, key := range service.signingKeys {
+		if key.ID == id && !key.isRetired(now) {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) signToken(claims jwt.MapClaims) (string, error) {
+	methodName := "SignToken"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	key := service.currentSigningKey(time.Now())
+	if key == nil {
+		errWrapped := errors.Wrap(ErrInternal, errKeyNoneMessage)
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.privateKey)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenCreateMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	return tokenString, nil
+}
+
+// JSONWebKey is a public key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// PublicJSONWebKeys returns public keys, that tokens may be signed with, for JWKS endpoint
+func (service *Service) PublicJSONWebKeys(now time.Time) []JSONWebKey {
+	jsonWebKeys := []JSONWebKey{}
+
+	for _, key := range service.signingKeys {
+		if key.isRetired(now) {
+			continue
+		}
+
+		jsonWebKey := JSONWebKey{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+		}
+
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jsonWebKey.KeyType = "RSA"
+			jsonWebKey.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jsonWebKey.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jsonWebKey.KeyType = "OKP"
+			jsonWebKey.Curve = "Ed25519"
+			jsonWebKey.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		jsonWebKeys = append(jsonWebKeys, jsonWebKey)
+	}
+
+	return jsonWebKeys
+}
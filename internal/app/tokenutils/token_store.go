@@ -0,0 +1,34 @@
+package tokenutils
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrStoreKeyNotFound is returned by TokenStore for keys, that don't exist or have expired
+var ErrStoreKeyNotFound = errors.New("Key not found in token store")
+
+// TokenStore is a key-value storage with expiration, that keeps tokens and sessions.
+// Plain values, hashes and sets share one key space like in Redis.
+// Zero ttl means the key never expires.
+type TokenStore interface {
+	Set(key string, value string, ttl time.Duration) error
+	// SetIfNotExists sets the value only if the key doesn't exist, atomically, and tells if it did
+	SetIfNotExists(key string, value string, ttl time.Duration) (bool, error)
+	Get(key string) (string, error)
+	// GetDelete returns the value and deletes the key atomically
+	GetDelete(key string) (string, error)
+	Exists(key string) (bool, error)
+	Delete(keys ...string) error
+	// Expire does nothing for keys, that don't exist
+	Expire(key string, ttl time.Duration) error
+	HashSet(key string, fields map[string]string) error
+	// HashUpdate sets the fields only if the key exists, atomically, and tells if it did
+	HashUpdate(key string, fields map[string]string) (bool, error)
+	// HashGetAll returns empty map for keys, that don't exist
+	HashGetAll(key string) (map[string]string, error)
+	SetAdd(key string, members ...string) error
+	SetRemove(key string, members ...string) error
+	SetMembers(key string) ([]string, error)
+}
@@ -3,7 +3,6 @@ package tokenutils
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +12,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	accessTokenTTL  = time.Minute * 15
+	refreshTokenTTL = time.Hour * 24 * 7
+)
+
 type TokenPairDetails struct {
 	AccessToken  string
 	RefreshToken string
@@ -22,30 +26,39 @@ type TokenPairDetails struct {
 	RtExpires    int64
 }
 
-func CreateTokens(userid uint64) (*TokenPairDetails, error) {
+// CreateTokens creates new pair of tokens for the session, previous pair of the session stops working
+func (service *Service) CreateTokens(userid uint64, sessionID string) (*TokenPairDetails, error) {
 	methodName := "CreateTokens"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
 
+	session, err := service.FetchSession(sessionID)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	if session.UserID != userid {
+		errWrapped := errors.Wrap(ErrSessionNotFound, errWrapMessage)
+		return nil, errWrapped
+	}
+
 	tokensDetails := &TokenPairDetails{}
-	tokensDetails.AtExpires = time.Now().Add(time.Minute * 15).Unix()
+	tokensDetails.AtExpires = time.Now().Add(accessTokenTTL).Unix()
 	tokensDetails.AccessUuid = uuid.New().String()
 
-	tokensDetails.RtExpires = time.Now().Add(time.Hour * 24 * 7).Unix()
+	tokensDetails.RtExpires = time.Now().Add(refreshTokenTTL).Unix()
 	tokensDetails.RefreshUuid = uuid.New().String()
 
-	var err error
 	// Creating Access Token
 	accessTokenClaims := jwt.MapClaims{}
 	accessTokenClaims["authorized"] = true
 	accessTokenClaims["uuid"] = tokensDetails.AccessUuid
 	accessTokenClaims["user_id"] = userid
+	accessTokenClaims["session_id"] = sessionID
 	accessTokenClaims["exp"] = tokensDetails.AtExpires
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	tokensDetails.AccessToken, err = accessToken.SignedString([]byte(os.Getenv("TOKEN_SECRET")))
+	tokensDetails.AccessToken, err = service.signToken(accessTokenClaims)
 	if err != nil {
-		errWrapped := errors.Wrap(ErrInternal, errTokenCreateMessage)
-		errWrapped = errors.Wrap(errWrapped, err.Error())
-		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		errWrapped := errors.Wrap(err, errWrapMessage)
 		return nil, errWrapped
 	}
 
@@ -53,13 +66,11 @@ func CreateTokens(userid uint64) (*TokenPairDetails, error) {
 	refreshTokenClaims := jwt.MapClaims{}
 	refreshTokenClaims["uuid"] = tokensDetails.RefreshUuid
 	refreshTokenClaims["user_id"] = userid
+	refreshTokenClaims["session_id"] = sessionID
 	refreshTokenClaims["exp"] = tokensDetails.RtExpires
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
-	tokensDetails.RefreshToken, err = refreshToken.SignedString([]byte(os.Getenv("TOKEN_SECRET")))
+	tokensDetails.RefreshToken, err = service.signToken(refreshTokenClaims)
 	if err != nil {
-		errWrapped := errors.Wrap(ErrInternal, errTokenCreateMessage)
-		errWrapped = errors.Wrap(errWrapped, err.Error())
-		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		errWrapped := errors.Wrap(err, errWrapMessage)
 		return nil, errWrapped
 	}
 	// Converting Unix to UTC(to Time object)
@@ -68,7 +79,7 @@ func CreateTokens(userid uint64) (*TokenPairDetails, error) {
 	now := time.Now()
 
 	// Saving Access token
-	if err := redisStore.Set(tokensDetails.AccessUuid, strconv.Itoa(int(userid)), accessTokenExpires.Sub(now)).Err(); err != nil {
+	if err := service.store.Set(tokensDetails.AccessUuid, strconv.Itoa(int(userid)), accessTokenExpires.Sub(now)); err != nil {
 		errWrapped := errors.Wrap(ErrInternal, errTokenSaveMessage)
 		errWrapped = errors.Wrap(errWrapped, err.Error())
 		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
@@ -76,7 +87,15 @@ func CreateTokens(userid uint64) (*TokenPairDetails, error) {
 	}
 
 	// Saving Refresh token
-	if err := redisStore.Set(tokensDetails.RefreshUuid, strconv.Itoa(int(userid)), refreshTokenExpires.Sub(now)).Err(); err != nil {
+	if err := service.store.Set(tokensDetails.RefreshUuid, strconv.Itoa(int(userid)), refreshTokenExpires.Sub(now)); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errTokenSaveMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	// Linking tokens with the session and dropping the previous pair
+	if err := service.linkTokensToSession(session, tokensDetails, now, refreshTokenExpires.Sub(now)); err != nil {
 		errWrapped := errors.Wrap(ErrInternal, errTokenSaveMessage)
 		errWrapped = errors.Wrap(errWrapped, err.Error())
 		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
@@ -86,6 +105,37 @@ func CreateTokens(userid uint64) (*TokenPairDetails, error) {
 	return tokensDetails, nil
 }
 
+func (service *Service) linkTokensToSession(session *SessionDetails, tokensDetails *TokenPairDetails, now time.Time, ttl time.Duration) error {
+	previousKeys := []string{}
+	if session.AccessUuid != "" {
+		previousKeys = append(previousKeys, session.AccessUuid)
+	}
+	if session.RefreshUuid != "" {
+		previousKeys = append(previousKeys, session.RefreshUuid)
+		// Remembering rotated token to detect it being replayed
+		if err := service.store.Set(rotatedRefreshKey(session.RefreshUuid), session.ID, refreshTokenTTL); err != nil {
+			return err
+		}
+	}
+	if err := service.store.Delete(previousKeys...); err != nil {
+		return err
+	}
+
+	if err := service.store.HashSet(sessionKey(session.ID), map[string]string{
+		"access_uuid":  tokensDetails.AccessUuid,
+		"refresh_uuid": tokensDetails.RefreshUuid,
+		"last_used_at": strconv.FormatInt(now.Unix(), 10),
+	}); err != nil {
+		return err
+	}
+
+	if err := service.store.Expire(sessionKey(session.ID), ttl); err != nil {
+		return err
+	}
+
+	return service.store.Expire(userSessionsKey(session.UserID), ttl)
+}
+
 func ExtractToken(r *http.Request) (string, error) {
 	methodName := "ExtractToken"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
@@ -104,18 +154,38 @@ func ExtractToken(r *http.Request) (string, error) {
 	return strArr[1], nil
 }
 
-func verifyToken(tokenString string) (*jwt.Token, error) {
+func (service *Service) verifyToken(tokenString string) (*jwt.Token, error) {
 	methodName := "VerifyToken"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Make sure that the token method conform to "SigningMethodHMAC"
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		keyID, ok := token.Header["kid"].(string)
+		if !ok {
+			// Tokens issued before signing keys were introduced are signed with the shared secret,
+			// they are accepted until they expire while the legacy secret is set
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || service.legacySecret == "" {
+				errWrapped := errors.Wrap(ErrTokenDamaged, errTokenSigningMethodMessage)
+				errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+				return nil, errWrapped
+			}
+			return []byte(service.legacySecret), nil
+		}
+
+		key := service.findVerificationKey(keyID, time.Now())
+		if key == nil {
+			errWrapped := errors.Wrap(ErrTokenDamaged, errKeyUnknownMessage)
+			errWrapped = errors.Wrap(errWrapped, fmt.Sprintf("kid = %s", keyID))
+			errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+			return nil, errWrapped
+		}
+
+		// Algorithm is taken from the key, never from the token
+		if token.Method.Alg() != key.method.Alg() {
 			errWrapped := errors.Wrap(ErrTokenDamaged, errTokenSigningMethodMessage)
 			errWrapped = errors.Wrap(errWrapped, errWrapMessage)
 			return nil, errWrapped
 		}
-		return []byte(os.Getenv("TOKEN_SECRET")), nil
+		return key.publicKey, nil
 	})
 	if err != nil {
 		if err.Error() == errTokenExpiredMessage {
@@ -165,7 +235,7 @@ func verifyToken(tokenString string) (*jwt.Token, error) {
 //
 // 	count, err := redisStore.Exists(uuid).Result()
 // 	if err != nil {
-// 		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+// 		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
 // 		errWrapped = errors.Wrap(errWrapped, err.Error())
 // 		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
 // 		return errWrapped
@@ -179,16 +249,45 @@ func verifyToken(tokenString string) (*jwt.Token, error) {
 // 	return nil
 // }
 
+// SessionID is empty for tokens issued before sessions were introduced
 type TokenDetails struct {
-	Uuid   string
-	UserId uint64
+	Uuid      string
+	UserId    uint64
+	SessionID string
 }
 
-func ExtractTokenMetadata(tokenString string) (*TokenDetails, error) {
+func (service *Service) ExtractTokenMetadata(tokenString string) (*TokenDetails, error) {
 	methodName := "ExtractTokenMetadata"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
 
-	token, err := verifyToken(tokenString)
+	tokenDetails, err := service.parseToken(tokenString)
+	if err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	exists, err := service.store.Exists(tokenDetails.Uuid)
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	if !exists {
+		errWrapped := errors.Wrap(ErrTokenExpiredOrDeleted, errWrapMessage)
+		return nil, errWrapped
+	}
+
+	return tokenDetails, nil
+}
+
+// parseToken checks signature and expiration of the token, but not whether it has been deleted
+func (service *Service) parseToken(tokenString string) (*TokenDetails, error) {
+	methodName := "ParseToken"
+	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
+
+	token, err := service.verifyToken(tokenString)
 	if err != nil {
 		errWrapped := errors.Wrap(err, errWrapMessage)
 		return nil, errWrapped
@@ -216,19 +315,6 @@ func ExtractTokenMetadata(tokenString string) (*TokenDetails, error) {
 		return nil, errWrapped
 	}
 
-	count, err := redisStore.Exists(uuid).Result()
-	if err != nil {
-		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
-		errWrapped = errors.Wrap(errWrapped, err.Error())
-		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
-		return nil, errWrapped
-	}
-
-	if count == 0 {
-		errWrapped := errors.Wrap(ErrTokenExpiredOrDeleted, errWrapMessage)
-		return nil, errWrapped
-	}
-
 	userId, err := strconv.ParseUint(fmt.Sprintf("%.f", claims["user_id"]), 10, 64)
 	if err != nil {
 		errWrapped := errors.Wrap(ErrTokenDamaged, errUintParseMessage)
@@ -237,23 +323,26 @@ func ExtractTokenMetadata(tokenString string) (*TokenDetails, error) {
 		return nil, errWrapped
 	}
 
+	sessionID, _ := claims["session_id"].(string)
+
 	return &TokenDetails{
-		Uuid:   uuid,
-		UserId: userId,
+		Uuid:      uuid,
+		UserId:    userId,
+		SessionID: sessionID,
 	}, nil
 }
 
-func FetchAuth(authDetails *TokenDetails) (uint64, error) {
+func (service *Service) FetchAuth(authDetails *TokenDetails) (uint64, error) {
 	methodName := "FetchAuth"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
 
-	userIDRaw, err := redisStore.Get(authDetails.Uuid).Result()
+	userIDRaw, err := service.store.Get(authDetails.Uuid)
 	if err != nil {
-		if err.Error() == errRedisNilMessage {
+		if err == ErrStoreKeyNotFound {
 			errWrapped := errors.Wrap(ErrTokenExpiredOrDeleted, errWrapMessage)
 			return 0, errWrapped
 		}
-		errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
 		errWrapped = errors.Wrap(errWrapped, err.Error())
 		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
 		return 0, errWrapped
@@ -262,11 +351,11 @@ func FetchAuth(authDetails *TokenDetails) (uint64, error) {
 	return userID, nil
 }
 
-func DeleteAuth(uuid string) error {
+func (service *Service) DeleteAuth(uuid string) error {
 	methodName := "DeleteAuth"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
 
-	err := redisStore.Del(uuid).Err()
+	err := service.store.Delete(uuid)
 	if err != nil {
 		errWrapped := errors.Wrap(ErrInternal, errTokenDeleteMessage)
 		errWrapped = errors.Wrap(errWrapped, err.Error())
@@ -276,42 +365,30 @@ func DeleteAuth(uuid string) error {
 	return nil
 }
 
-func DeleteAllAuths(userid uint64) error {
+// DeleteAllAuths deletes every session of the user with its tokens
+func (service *Service) DeleteAllAuths(userid uint64) error {
 	methodName := "DeleteAllAuths"
 	errWrapMessage := fmt.Sprintf(errTokenUtilsMessageFormat, methodName)
 
-	var cursor uint64
-	for {
-		var keys []string
-		var err error
-		keys, cursor, err = redisStore.Scan(cursor, "*", 0).Result()
-		if err != nil {
-			errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
-			errWrapped = errors.Wrap(errWrapped, err.Error())
-			errWrapped = errors.Wrap(errWrapped, errWrapMessage)
-			return errWrapped
-		}
-
-		for _, key := range keys {
-			result, err := redisStore.Get(key).Result()
-			if err != nil {
-				errWrapped := errors.Wrap(ErrInternal, errRedisMessage)
-				errWrapped = errors.Wrap(errWrapped, err.Error())
-				errWrapped = errors.Wrap(errWrapped, errWrapMessage)
-				return errWrapped
-			}
+	sessionIDs, err := service.store.SetMembers(userSessionsKey(userid))
+	if err != nil {
+		errWrapped := errors.Wrap(ErrInternal, errStoreMessage)
+		errWrapped = errors.Wrap(errWrapped, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return errWrapped
+	}
 
-			if result == strconv.Itoa(int(userid)) {
-				if err := DeleteAuth(key); err != nil {
-					errWrapped := errors.Wrap(err, errWrapMessage)
-					return errWrapped
-				}
-			}
+	for _, sessionID := range sessionIDs {
+		if err := service.DeleteSession(userid, sessionID); err != nil && errors.Cause(err) != ErrSessionNotFound {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return errWrapped
 		}
+	}
 
-		if cursor == 0 { // no more keys
-			break
-		}
+	if err := service.DeleteAuth(userSessionsKey(userid)); err != nil {
+		errWrapped := errors.Wrap(err, errWrapMessage)
+		return errWrapped
 	}
+
 	return nil
 }
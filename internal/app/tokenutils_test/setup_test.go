@@ -5,23 +5,22 @@ import (
 	"os"
 	"testing"
 
-	"github.com/BurntSushi/toml"
-	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apiserver"
 	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
 )
 
-func TestMain(m *testing.M) {
-	config := apiserver.NewConfig()
+// testService keeps tokens in memory, so the tests don't need Redis
+var testService *tokenutils.Service
 
-	if _, err := toml.DecodeFile("../../../configs/local_test.toml", config); err != nil {
-		fmt.Printf("Couldn't get config:\n\t%s", err.Error())
+func TestMain(m *testing.M) {
+	signingKey, err := tokenutils.NewEphemeralSigningKey("test")
+	if err != nil {
+		fmt.Printf("Couldn't create signing key:\n\t%s", err.Error())
 		return
 	}
 
-	os.Setenv("TOKEN_SECRET", config.TokenSecret)
-
-	if err := tokenutils.SetupRedis(config.RedisAddr); err != nil {
-		fmt.Printf("Couldn't setup Redis:\n\t%s", err.Error())
+	testService, err = tokenutils.NewService(tokenutils.NewMemoryTokenStore(), []*tokenutils.SigningKey{signingKey}, "")
+	if err != nil {
+		fmt.Printf("Couldn't create token service:\n\t%s", err.Error())
 		return
 	}
 
@@ -0,0 +1,97 @@
+package tokenutils_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+func TestSigningKeysRotation(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Errorf("Couldn't generate RSA key:\n\t%s", err.Error())
+		return
+	}
+	rsaPath := filepath.Join(dir, "rsa.pem")
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	if err := ioutil.WriteFile(rsaPath, rsaPEM, 0600); err != nil {
+		t.Errorf("Couldn't write RSA key:\n\t%s", err.Error())
+		return
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Errorf("Couldn't generate Ed25519 key:\n\t%s", err.Error())
+		return
+	}
+	edKeyDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Errorf("Couldn't marshal Ed25519 key:\n\t%s", err.Error())
+		return
+	}
+	edPath := filepath.Join(dir, "ed25519.pem")
+	if err := ioutil.WriteFile(edPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edKeyDER}), 0600); err != nil {
+		t.Errorf("Couldn't write Ed25519 key:\n\t%s", err.Error())
+		return
+	}
+
+	keyRetired, err := tokenutils.LoadSigningKey("retired", rsaPath, now.Add(-48*time.Hour), now.Add(-time.Hour))
+	if err != nil {
+		t.Errorf("Couldn't load RSA key:\n\t%s", err.Error())
+		return
+	}
+	keyCurrent, err := tokenutils.LoadSigningKey("current", edPath, now.Add(-24*time.Hour), time.Time{})
+	if err != nil {
+		t.Errorf("Couldn't load Ed25519 key:\n\t%s", err.Error())
+		return
+	}
+	keyNext, err := tokenutils.LoadSigningKey("next", rsaPath, now.Add(24*time.Hour), time.Time{})
+	if err != nil {
+		t.Errorf("Couldn't load RSA key:\n\t%s", err.Error())
+		return
+	}
+
+	service, err := tokenutils.NewService(tokenutils.NewMemoryTokenStore(), []*tokenutils.SigningKey{keyNext, keyRetired, keyCurrent}, "")
+	if err != nil {
+		t.Errorf("Couldn't create token service:\n\t%s", err.Error())
+		return
+	}
+
+	jsonWebKeys := service.PublicJSONWebKeys(now)
+	if len(jsonWebKeys) != 2 ||
+		jsonWebKeys[0].KeyID != "current" || jsonWebKeys[0].Algorithm != "EdDSA" ||
+		jsonWebKeys[1].KeyID != "next" || jsonWebKeys[1].Algorithm != "RS256" {
+		t.Errorf("Wrong published keys:\n\tGot: %+v", jsonWebKeys)
+	}
+
+	tokenPairDetails, err := createTestTokens(service, 1)
+	if err != nil {
+		t.Errorf("Couldn't create tokens:\n\t%s", err.Error())
+		return
+	}
+
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenPairDetails.AccessToken, jwt.MapClaims{})
+	if err != nil {
+		t.Errorf("Couldn't parse access token:\n\t%s", err.Error())
+		return
+	}
+	if token.Header["kid"] != "current" {
+		t.Errorf("Token is signed with wrong key:\n\tWanted: current, Got: %v", token.Header["kid"])
+	}
+
+	if _, err := service.ExtractTokenMetadata(tokenPairDetails.AccessToken); err != nil {
+		t.Errorf("Couldn't verify token signed with current key:\n\t%s", err.Error())
+	}
+}
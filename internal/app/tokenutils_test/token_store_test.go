@@ -0,0 +1,146 @@
+package tokenutils_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/uuid"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/apiserver"
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/tokenutils"
+)
+
+func TestMemoryTokenStore(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Now()
+
+	store := tokenutils.NewMemoryTokenStoreWithClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+
+	testTokenStore(t, store, func(duration time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(duration)
+	})
+}
+
+func TestRedisTokenStore(t *testing.T) {
+	config := apiserver.NewConfig()
+
+	if _, err := toml.DecodeFile("../../../configs/local_test.toml", config); err != nil {
+		t.Skipf("Couldn't get config:\n\t%s", err.Error())
+	}
+
+	client, err := tokenutils.NewRedisClient(config.RedisAddr)
+	if err != nil {
+		t.Skipf("Couldn't setup Redis:\n\t%s", err.Error())
+	}
+	defer client.Close()
+
+	testTokenStore(t, tokenutils.NewRedisTokenStore(client), time.Sleep)
+}
+
+// testTokenStore checks behaviour both implementations must share, wait moves time forward
+func testTokenStore(t *testing.T, store tokenutils.TokenStore, wait func(time.Duration)) {
+	prefix := uuid.New().String() + ":"
+	ttl := time.Millisecond * 200
+
+	if _, err := store.Get(prefix + "missing"); err != tokenutils.ErrStoreKeyNotFound {
+		t.Errorf("Wrong error for missing key:\n\tWanted: %v, Got: %v", tokenutils.ErrStoreKeyNotFound, err)
+	}
+
+	if err := store.Set(prefix+"value", "first", ttl); err != nil {
+		t.Errorf("Couldn't set value:\n\t%s", err.Error())
+		return
+	}
+	if err := store.Set(prefix+"permanent", "second", 0); err != nil {
+		t.Errorf("Couldn't set value:\n\t%s", err.Error())
+		return
+	}
+	if value, err := store.Get(prefix + "value"); err != nil || value != "first" {
+		t.Errorf("Wrong value:\n\tWanted: first, Got: %q (%v)", value, err)
+	}
+	if set, err := store.SetIfNotExists(prefix+"value", "third", ttl); err != nil || set {
+		t.Errorf("Existing value was replaced:\n\tSet: %v (%v)", set, err)
+	}
+	if set, err := store.SetIfNotExists(prefix+"once", "fourth", ttl); err != nil || !set {
+		t.Errorf("Missing value wasn't set:\n\tSet: %v (%v)", set, err)
+	}
+
+	if err := store.HashSet(prefix+"hash", map[string]string{"a": "1", "b": "2"}); err != nil {
+		t.Errorf("Couldn't set hash:\n\t%s", err.Error())
+		return
+	}
+	if err := store.Expire(prefix+"hash", ttl); err != nil {
+		t.Errorf("Couldn't set expiration:\n\t%s", err.Error())
+		return
+	}
+	// Updating fields must keep expiration of the hash
+	if err := store.HashSet(prefix+"hash", map[string]string{"b": "3"}); err != nil {
+		t.Errorf("Couldn't set hash:\n\t%s", err.Error())
+		return
+	}
+	if fields, err := store.HashGetAll(prefix + "hash"); err != nil || len(fields) != 2 || fields["a"] != "1" || fields["b"] != "3" {
+		t.Errorf("Wrong hash:\n\tWanted: map[a:1 b:3], Got: %v (%v)", fields, err)
+	}
+	if updated, err := store.HashUpdate(prefix+"hash", map[string]string{"a": "4"}); err != nil || !updated {
+		t.Errorf("Existing hash wasn't updated:\n\tUpdated: %v (%v)", updated, err)
+	}
+	if fields, err := store.HashGetAll(prefix + "hash"); err != nil || fields["a"] != "4" {
+		t.Errorf("Wrong hash:\n\tWanted: map[a:4 b:3], Got: %v (%v)", fields, err)
+	}
+
+	if err := store.SetAdd(prefix+"set", "x", "y", "z"); err != nil {
+		t.Errorf("Couldn't add to set:\n\t%s", err.Error())
+		return
+	}
+	if err := store.SetRemove(prefix+"set", "y"); err != nil {
+		t.Errorf("Couldn't remove from set:\n\t%s", err.Error())
+		return
+	}
+	members, err := store.SetMembers(prefix + "set")
+	sort.Strings(members)
+	if err != nil || len(members) != 2 || members[0] != "x" || members[1] != "z" {
+		t.Errorf("Wrong set members:\n\tWanted: [x z], Got: %v (%v)", members, err)
+	}
+
+	if value, err := store.GetDelete(prefix + "permanent"); err != nil || value != "second" {
+		t.Errorf("Wrong value:\n\tWanted: second, Got: %q (%v)", value, err)
+	}
+	if _, err := store.GetDelete(prefix + "permanent"); err != tokenutils.ErrStoreKeyNotFound {
+		t.Errorf("Value was got twice:\n\tWanted: %v, Got: %v", tokenutils.ErrStoreKeyNotFound, err)
+	}
+
+	wait(ttl + time.Millisecond*100)
+
+	if exists, err := store.Exists(prefix + "value"); err != nil || exists {
+		t.Errorf("Value hasn't expired:\n\tExists: %v (%v)", exists, err)
+	}
+	if set, err := store.SetIfNotExists(prefix+"once", "fifth", ttl); err != nil || !set {
+		t.Errorf("Expired value wasn't set again:\n\tSet: %v (%v)", set, err)
+	}
+	if fields, err := store.HashGetAll(prefix + "hash"); err != nil || len(fields) != 0 {
+		t.Errorf("Hash hasn't expired:\n\tGot: %v (%v)", fields, err)
+	}
+	if updated, err := store.HashUpdate(prefix+"hash", map[string]string{"a": "5"}); err != nil || updated {
+		t.Errorf("Expired hash was updated:\n\tUpdated: %v (%v)", updated, err)
+	}
+	if exists, err := store.Exists(prefix + "hash"); err != nil || exists {
+		t.Errorf("Expired hash was recreated:\n\tExists: %v (%v)", exists, err)
+	}
+	if exists, err := store.Exists(prefix + "set"); err != nil || !exists {
+		t.Errorf("Set without expiration has expired:\n\tExists: %v (%v)", exists, err)
+	}
+
+	if err := store.Delete(prefix+"set", prefix+"missing"); err != nil {
+		t.Errorf("Couldn't delete keys:\n\t%s", err.Error())
+	}
+	if members, err := store.SetMembers(prefix + "set"); err != nil || len(members) != 0 {
+		t.Errorf("Set hasn't been deleted:\n\tGot: %v (%v)", members, err)
+	}
+}
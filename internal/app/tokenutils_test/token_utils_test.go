@@ -12,7 +12,7 @@ import (
 func TestCreateDeleteTokens(t *testing.T) {
 	var userid uint64 = 1
 
-	testTokenPairDetails, err := tokenutils.CreateTokens(userid)
+	testTokenPairDetails, err := createTestTokens(testService, userid)
 	if err != nil {
 		t.Errorf("Couldn't create tokens for userid (%d):\n\t%s", userid, err.Error())
 		return
@@ -22,10 +22,10 @@ func TestCreateDeleteTokens(t *testing.T) {
 		return
 	}
 
-	if err := tokenutils.DeleteAuth(testTokenPairDetails.AccessUuid); err != nil {
+	if err := testService.DeleteAuth(testTokenPairDetails.AccessUuid); err != nil {
 		t.Errorf("Couldn't delete access token for uuid=%s:\n\t%s", testTokenPairDetails.AccessUuid, err.Error())
 	}
-	if err := tokenutils.DeleteAuth(testTokenPairDetails.RefreshUuid); err != nil {
+	if err := testService.DeleteAuth(testTokenPairDetails.RefreshUuid); err != nil {
 		t.Errorf("Couldn't delete refresh token for uuid=%s:\n\t%s", testTokenPairDetails.RefreshUuid, err.Error())
 	}
 }
@@ -75,7 +75,7 @@ func TestExtractToken(t *testing.T) {
 }
 
 func TestExtractTokenMetadata(t *testing.T) {
-	if _, err := tokenutils.ExtractTokenMetadata("IncorrectToken"); err == nil {
+	if _, err := testService.ExtractTokenMetadata("IncorrectToken"); err == nil {
 		t.Errorf("Validated incorrect token (IncorrectToken)")
 	} else {
 		switch errors.Cause(err) {
@@ -90,7 +90,7 @@ func TestExtractTokenMetadata(t *testing.T) {
 
 	var userid uint64 = 1
 
-	testTokenPairDetails, err := tokenutils.CreateTokens(userid)
+	testTokenPairDetails, err := createTestTokens(testService, userid)
 	if err != nil {
 		t.Errorf("Couldn't create tokens for userid (%d):\n\t%s", userid, err.Error())
 		return
@@ -100,7 +100,7 @@ func TestExtractTokenMetadata(t *testing.T) {
 		return
 	}
 
-	if tokenDetails, err := tokenutils.ExtractTokenMetadata(testTokenPairDetails.AccessToken); err != nil {
+	if tokenDetails, err := testService.ExtractTokenMetadata(testTokenPairDetails.AccessToken); err != nil {
 		switch errors.Cause(err) {
 		case tokenutils.ErrTokenDamaged:
 			t.Errorf("Created access token (%s) for userid (%d) are not valid:\n\t%s", testTokenPairDetails.AccessToken, userid, err.Error())
@@ -118,14 +118,14 @@ func TestExtractTokenMetadata(t *testing.T) {
 		}
 	}
 
-	if err := tokenutils.DeleteAuth(testTokenPairDetails.AccessUuid); err != nil {
+	if err := testService.DeleteAuth(testTokenPairDetails.AccessUuid); err != nil {
 		t.Errorf("Couldn't delete access token for uuid=%s:\n\t%s", testTokenPairDetails.AccessUuid, err.Error())
 	}
-	if err := tokenutils.DeleteAuth(testTokenPairDetails.RefreshUuid); err != nil {
+	if err := testService.DeleteAuth(testTokenPairDetails.RefreshUuid); err != nil {
 		t.Errorf("Couldn't delete refresh token for uuid=%s:\n\t%s", testTokenPairDetails.RefreshUuid, err.Error())
 	}
 
-	if _, err := tokenutils.ExtractTokenMetadata(testTokenPairDetails.AccessToken); err == nil {
+	if _, err := testService.ExtractTokenMetadata(testTokenPairDetails.AccessToken); err == nil {
 		t.Errorf("Validated deleted token  with uuid (%s)", testTokenPairDetails.AccessUuid)
 	} else {
 		switch errors.Cause(err) {
@@ -145,7 +145,7 @@ func TestFetchAuth(t *testing.T) {
 		UserId: 0,
 	}
 
-	if userIdFetched, err := tokenutils.FetchAuth(tokenDetailsncorrect); err == nil {
+	if userIdFetched, err := testService.FetchAuth(tokenDetailsncorrect); err == nil {
 		t.Errorf("Fetched auth from incorrect uuid (IncorrectUUID), Got (%d)", userIdFetched)
 	} else if errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
 		t.Errorf("Something went wrong with incorrect uuid (IncorrectUUID):\n\t%s", err.Error())
@@ -153,7 +153,7 @@ func TestFetchAuth(t *testing.T) {
 
 	var userid uint64 = 1
 
-	testTokenPairDetails, err := tokenutils.CreateTokens(userid)
+	testTokenPairDetails, err := createTestTokens(testService, userid)
 	if err != nil {
 		t.Errorf("Couldn't create tokens for userid (%d):\n\t%s", userid, err.Error())
 		return
@@ -167,7 +167,7 @@ func TestFetchAuth(t *testing.T) {
 		Uuid:   testTokenPairDetails.AccessUuid,
 		UserId: userid,
 	}
-	userIdFetched, err := tokenutils.FetchAuth(tokenDetailsCorrect)
+	userIdFetched, err := testService.FetchAuth(tokenDetailsCorrect)
 	if err != nil {
 		t.Errorf("Couldn't fetch auth from correct uuid (%s):\n\t%s", tokenDetailsCorrect.Uuid, err.Error())
 	}
@@ -179,7 +179,7 @@ func TestFetchAuth(t *testing.T) {
 func TestDeleteAllAuths(t *testing.T) {
 	var userid uint64 = 1
 
-	testTokenPairDetails, err := tokenutils.CreateTokens(userid)
+	testTokenPairDetails, err := createTestTokens(testService, userid)
 	if err != nil {
 		t.Errorf("Couldn't create tokens for userid (%d):\n\t%s", userid, err.Error())
 		return
@@ -189,7 +189,7 @@ func TestDeleteAllAuths(t *testing.T) {
 		return
 	}
 
-	if err := tokenutils.DeleteAllAuths(userid); err != nil {
+	if err := testService.DeleteAllAuths(userid); err != nil {
 		t.Errorf("Couldn't delete all auths for userid (%d):\n\t%s", userid, err.Error())
 	}
 
@@ -197,7 +197,7 @@ func TestDeleteAllAuths(t *testing.T) {
 		Uuid:   testTokenPairDetails.AccessUuid,
 		UserId: userid,
 	}
-	if userIdFetched, err := tokenutils.FetchAuth(tokenDetailsAccess); err == nil {
+	if userIdFetched, err := testService.FetchAuth(tokenDetailsAccess); err == nil {
 		t.Errorf("Fetched deleted access auth from uuid (%s), Got (%d)", tokenDetailsAccess.Uuid, userIdFetched)
 	} else if errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
 		t.Errorf("Something went wrong with incorrect uuid (%s):\n\t%s", tokenDetailsAccess.Uuid, err.Error())
@@ -207,9 +207,154 @@ func TestDeleteAllAuths(t *testing.T) {
 		Uuid:   testTokenPairDetails.RefreshUuid,
 		UserId: userid,
 	}
-	if userIdFetched, err := tokenutils.FetchAuth(tokenDetailsRefresh); err == nil {
+	if userIdFetched, err := testService.FetchAuth(tokenDetailsRefresh); err == nil {
 		t.Errorf("Fetched deleted refresh auth from uuid (%s), Got (%d)", tokenDetailsRefresh.Uuid, userIdFetched)
 	} else if errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
 		t.Errorf("Something went wrong with incorrect uuid (%s):\n\t%s", tokenDetailsRefresh.Uuid, err.Error())
 	}
 }
+
+func TestOneTimeToken(t *testing.T) {
+	details := &tokenutils.OneTimeTokenDetails{
+		UserID: 1,
+		Email:  "user@example.com",
+	}
+
+	token, err := testService.CreateOneTimeToken(tokenutils.PurposePasswordReset, details, time.Minute)
+	if err != nil {
+		t.Errorf("Couldn't create one-time token:\n\t%s", err.Error())
+		return
+	}
+
+	if _, err := testService.ConsumeOneTimeToken(tokenutils.PurposeEmailVerification, token); errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
+		t.Errorf("Token was accepted for another purpose:\n\tWanted: %v, Got: %v", tokenutils.ErrTokenExpiredOrDeleted, err)
+	}
+
+	detailsFound, err := testService.ConsumeOneTimeToken(tokenutils.PurposePasswordReset, token)
+	if err != nil {
+		t.Errorf("Couldn't consume one-time token:\n\t%s", err.Error())
+		return
+	}
+	if *detailsFound != *details {
+		t.Errorf("Wrong one-time token details:\n\tWanted: %+v, Got: %+v", details, detailsFound)
+	}
+
+	if _, err := testService.ConsumeOneTimeToken(tokenutils.PurposePasswordReset, token); errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
+		t.Errorf("Token was consumed twice:\n\tWanted: %v, Got: %v", tokenutils.ErrTokenExpiredOrDeleted, err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	var userid uint64 = 2
+
+	firstSession, err := testService.CreateSession(userid, "Laptop", "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Errorf("Couldn't create session for userid (%d):\n\t%s", userid, err.Error())
+		return
+	}
+	secondSession, err := testService.CreateSession(userid, "Phone", "127.0.0.2", "test-agent")
+	if err != nil {
+		t.Errorf("Couldn't create session for userid (%d):\n\t%s", userid, err.Error())
+		return
+	}
+
+	if _, err := testService.CreateTokens(userid+1, firstSession.ID); errors.Cause(err) != tokenutils.ErrSessionNotFound {
+		t.Errorf("Created tokens for session of another user:\n\tWanted: %v, Got: %v", tokenutils.ErrSessionNotFound, err)
+	}
+
+	firstTokens, err := testService.CreateTokens(userid, firstSession.ID)
+	if err != nil {
+		t.Errorf("Couldn't create tokens for session (%s):\n\t%s", firstSession.ID, err.Error())
+		return
+	}
+
+	if err := testService.TouchSession(secondSession.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("Couldn't touch session (%s):\n\t%s", secondSession.ID, err.Error())
+	}
+
+	sessions, err := testService.ListSessions(userid)
+	if err != nil {
+		t.Errorf("Couldn't list sessions for userid (%d):\n\t%s", userid, err.Error())
+		return
+	}
+	if len(sessions) != 2 {
+		t.Errorf("Wrong sessions count:\n\tWanted: 2, Got: %d", len(sessions))
+		return
+	}
+	if sessions[0].ID != secondSession.ID {
+		t.Errorf("Sessions aren't sorted by last use:\n\tWanted first: %s, Got: %s", secondSession.ID, sessions[0].ID)
+	}
+
+	if err := testService.DeleteSession(userid, firstSession.ID); err != nil {
+		t.Errorf("Couldn't delete session (%s):\n\t%s", firstSession.ID, err.Error())
+	}
+	if err := testService.DeleteSession(userid, firstSession.ID); errors.Cause(err) != tokenutils.ErrSessionNotFound {
+		t.Errorf("Deleted session twice:\n\tWanted: %v, Got: %v", tokenutils.ErrSessionNotFound, err)
+	}
+
+	tokenDetailsAccess := &tokenutils.TokenDetails{
+		Uuid:   firstTokens.AccessUuid,
+		UserId: userid,
+	}
+	if _, err := testService.FetchAuth(tokenDetailsAccess); errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
+		t.Errorf("Tokens of deleted session are still valid:\n\tWanted: %v, Got: %v", tokenutils.ErrTokenExpiredOrDeleted, err)
+	}
+
+	if err := testService.DeleteAllAuths(userid); err != nil {
+		t.Errorf("Couldn't delete all auths for userid (%d):\n\t%s", userid, err.Error())
+	}
+	if sessions, err := testService.ListSessions(userid); err != nil || len(sessions) != 0 {
+		t.Errorf("Sessions left after deleting all auths:\n\tGot: %d, err: %v", len(sessions), err)
+	}
+}
+
+func createTestTokens(service *tokenutils.Service, userid uint64) (*tokenutils.TokenPairDetails, error) {
+	session, err := service.CreateSession(userid, "Test device", "127.0.0.1", "test-agent")
+	if err != nil {
+		return nil, err
+	}
+
+	return service.CreateTokens(userid, session.ID)
+}
+
+func TestRevokeReusedRefreshToken(t *testing.T) {
+	var userid uint64 = 3
+
+	firstTokens, err := createTestTokens(testService, userid)
+	if err != nil {
+		t.Errorf("Couldn't create tokens for userid (%d):\n\t%s", userid, err.Error())
+		return
+	}
+
+	if _, err := testService.RevokeReusedRefreshToken(firstTokens.RefreshToken); errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
+		t.Errorf("Not rotated refresh token is treated as reused:\n\tWanted: %v, Got: %v", tokenutils.ErrTokenExpiredOrDeleted, err)
+	}
+
+	firstTokenDetails, err := testService.ExtractTokenMetadata(firstTokens.RefreshToken)
+	if err != nil {
+		t.Errorf("Couldn't extract refresh token metadata:\n\t%s", err.Error())
+		return
+	}
+
+	secondTokens, err := testService.CreateTokens(userid, firstTokenDetails.SessionID)
+	if err != nil {
+		t.Errorf("Couldn't rotate tokens for session (%s):\n\t%s", firstTokenDetails.SessionID, err.Error())
+		return
+	}
+
+	reusedTokenDetails, err := testService.RevokeReusedRefreshToken(firstTokens.RefreshToken)
+	if err != nil {
+		t.Errorf("Reuse of rotated refresh token wasn't detected:\n\t%s", err.Error())
+		return
+	}
+	if reusedTokenDetails.UserId != userid || reusedTokenDetails.SessionID != firstTokenDetails.SessionID {
+		t.Errorf("Wrong reused token details:\n\tWanted: %d %s, Got: %+v", userid, firstTokenDetails.SessionID, reusedTokenDetails)
+	}
+
+	if _, err := testService.ExtractTokenMetadata(secondTokens.RefreshToken); errors.Cause(err) != tokenutils.ErrTokenExpiredOrDeleted {
+		t.Errorf("Token family wasn't revoked:\n\tWanted: %v, Got: %v", tokenutils.ErrTokenExpiredOrDeleted, err)
+	}
+	if _, err := testService.FetchSession(firstTokenDetails.SessionID); errors.Cause(err) != tokenutils.ErrSessionNotFound {
+		t.Errorf("Session of revoked family still exists:\n\tWanted: %v, Got: %v", tokenutils.ErrSessionNotFound, err)
+	}
+}
@@ -0,0 +1,12 @@
+package totp
+
+import "github.com/pkg/errors"
+
+var (
+	ErrSecretDamaged = errors.New("TOTP secret has been damaged")
+	ErrInternal      = errors.New("Internal error")
+)
+
+const (
+	errTOTPMessageFormat = "TOTP %s error"
+)
@@ -0,0 +1,54 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RecoveryCodesCount = 10
+	// Without similar looking characters, so codes are easy to type from paper
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+)
+
+// GenerateRecoveryCodes returns codes formatted as xxxxx-xxxxx, they are shown to the user once
+func GenerateRecoveryCodes() ([]string, error) {
+	methodName := "GenerateRecoveryCodes"
+	errWrapMessage := fmt.Sprintf(errTOTPMessageFormat, methodName)
+
+	codes := make([]string, 0, RecoveryCodesCount)
+	alphabetLength := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for len(codes) < RecoveryCodesCount {
+		code := make([]byte, recoveryCodeLength)
+		for i := range code {
+			index, err := rand.Int(rand.Reader, alphabetLength)
+			if err != nil {
+				errWrapped := errors.Wrap(ErrInternal, err.Error())
+				errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+				return nil, errWrapped
+			}
+			code[i] = recoveryCodeAlphabet[index.Int64()]
+		}
+
+		half := recoveryCodeLength / 2
+		codes = append(codes, string(code[:half])+"-"+string(code[half:]))
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode is what is saved instead of the code, dashes, spaces and case are ignored.
+// Codes are random enough, so plain SHA-256 is sufficient.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
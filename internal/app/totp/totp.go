@@ -0,0 +1,124 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Codes are generated as in RFC 6238 with parameters every authenticator app supports:
+// HMAC-SHA1, 6 digits, 30 seconds step
+const (
+	Digits       = 6
+	Period       = 30
+	secretLength = 20
+	// Codes of the neighbour steps are accepted too, so clock drift and slow typing don't break login
+	allowedSkew = 1
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns random secret encoded in base32, as authenticator apps expect it
+func GenerateSecret() (string, error) {
+	methodName := "GenerateSecret"
+	errWrapMessage := fmt.Sprintf(errTOTPMessageFormat, methodName)
+
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		errWrapped := errors.Wrap(ErrInternal, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// ProvisioningURI is shown to the user as QR code to add the secret to authenticator app
+func ProvisioningURI(secret string, issuer string, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Step is the number of the time step the moment belongs to
+func Step(moment time.Time) int64 {
+	return moment.Unix() / Period
+}
+
+// GenerateCode returns code for the time step
+func GenerateCode(secret string, step int64) (string, error) {
+	methodName := "GenerateCode"
+	errWrapMessage := fmt.Sprintf(errTOTPMessageFormat, methodName)
+
+	key, err := secretEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		errWrapped := errors.Wrap(ErrSecretDamaged, err.Error())
+		errWrapped = errors.Wrap(errWrapped, errWrapMessage)
+		return "", errWrapped
+	}
+
+	return hotp(key, uint64(step)), nil
+}
+
+// Validate checks the code for the moment and returns the step it matched, so the caller
+// can reject codes of this and earlier steps afterwards
+func Validate(secret string, code string, moment time.Time) (int64, bool, error) {
+	methodName := "Validate"
+	errWrapMessage := fmt.Sprintf(errTOTPMessageFormat, methodName)
+
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false, nil
+	}
+
+	current := Step(moment)
+	for step := current - allowedSkew; step <= current+allowedSkew; step++ {
+		expected, err := GenerateCode(secret, step)
+		if err != nil {
+			errWrapped := errors.Wrap(err, errWrapMessage)
+			return 0, false, errWrapped
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// hotp is HOTP from RFC 4226 with dynamic truncation
+func hotp(key []byte, counter uint64) string {
+	counterBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(counterBytes, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counterBytes)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
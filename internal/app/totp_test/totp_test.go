@@ -0,0 +1,119 @@
+package totp_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spolyakovs/price-hunter-ITMO/internal/app/totp"
+)
+
+// Secret "12345678901234567890" from RFC 6238 appendix B in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCodeRFC6238(t *testing.T) {
+	// SHA1 vectors of RFC 6238 are 8 digits long, 6 digit codes are their last digits
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for unixTime, codeLong := range vectors {
+		codeWant := codeLong[len(codeLong)-totp.Digits:]
+
+		code, err := totp.GenerateCode(rfcSecret, totp.Step(time.Unix(unixTime, 0)))
+		if err != nil {
+			t.Errorf("Couldn't generate code:\n\t%s", err.Error())
+			continue
+		}
+
+		if code != codeWant {
+			t.Errorf("Wrong code for time %d:\n\tWanted: %s, Got: %s", unixTime, codeWant, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Errorf("Couldn't generate secret:\n\t%s", err.Error())
+		return
+	}
+
+	now := time.Unix(1700000000, 0)
+	step := totp.Step(now)
+
+	for _, stepOffset := range []int64{-1, 0, 1} {
+		code, _ := totp.GenerateCode(secret, step+stepOffset)
+
+		stepMatched, ok, err := totp.Validate(secret, code, now)
+		if err != nil || !ok || stepMatched != step+stepOffset {
+			t.Errorf("Code of step %+d wasn't accepted:\n\tGot: %d, %v (%v)", stepOffset, stepMatched, ok, err)
+		}
+	}
+
+	for _, stepOffset := range []int64{-3, 2} {
+		code, _ := totp.GenerateCode(secret, step+stepOffset)
+
+		if _, ok, _ := totp.Validate(secret, code, now); ok {
+			t.Errorf("Code of step %+d was accepted", stepOffset)
+		}
+	}
+
+	if _, ok, _ := totp.Validate(secret, "12345", now); ok {
+		t.Errorf("Code of wrong length was accepted")
+	}
+
+	if _, _, err := totp.Validate("not base32!", "123456", now); err == nil {
+		t.Errorf("Damaged secret was accepted")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI(rfcSecret, "Price Hunter", "user 1")
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Errorf("Couldn't parse provisioning URI %s:\n\t%s", uri, err.Error())
+		return
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/Price Hunter:user 1" {
+		t.Errorf("Wrong provisioning URI label: %s", uri)
+	}
+
+	query := parsed.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Price Hunter" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("Wrong provisioning URI parameters: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		t.Errorf("Couldn't generate recovery codes:\n\t%s", err.Error())
+		return
+	}
+
+	if len(codes) != totp.RecoveryCodesCount {
+		t.Errorf("Wrong number of recovery codes:\n\tWanted: %d, Got: %d", totp.RecoveryCodesCount, len(codes))
+	}
+
+	hashes := map[string]bool{}
+	for _, code := range codes {
+		hash := totp.HashRecoveryCode(code)
+		if hashes[hash] {
+			t.Errorf("Recovery code is repeated: %s", code)
+		}
+		hashes[hash] = true
+
+		if totp.HashRecoveryCode(" "+strings.ToUpper(strings.Replace(code, "-", "", 1))) != hash {
+			t.Errorf("Hash of recovery code %s depends on formatting", code)
+		}
+	}
+}